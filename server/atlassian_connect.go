@@ -21,7 +21,7 @@ var atlassianConnectJSON = &Endpoint{
 func renderAtlassianConnectJSON(w http.ResponseWriter, r *http.Request, p *Plugin) {
 	conf := config.GetConfig()
 
-	if status, err := verifyHTTPSecret(conf.Secret, r.FormValue("secret")); err != nil {
+	if status, err := p.verifyWebhookSecret(r); err != nil {
 		p.client.Log.Error("Failed to verify secret for Atlassian Connect JSON", "error", err.Error())
 		http.Error(w, "Invalid secret", status)
 		return
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	auditOnlySystemAdmin   = "`/confluence audit` can only be run by a system administrator."
+	noAuditEntries         = "No audit log entries found."
+	maxAuditCommandEntries = 25
+	defaultAuditPeriod     = 7 * 24 * time.Hour
+	auditDateLayout        = "2006-01-02"
+)
+
+var exportAuditLog = &Endpoint{
+	Path:            "/audit",
+	Method:          http.MethodGet,
+	Execute:         handleExportAuditLog,
+	IsAuthenticated: true,
+}
+
+func handleExportAuditLog(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	if !util.IsSystemAdmin(userID) {
+		p.client.Log.Error("Non admin user does not have access to export the audit log", "UserID", userID)
+		http.Error(w, "only system admin can export the audit log", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	since := now.Add(-defaultAuditPeriod)
+	if value := r.FormValue("since"); value != "" {
+		var err error
+		if since, err = parseSince(value, now); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := service.GetAuditEntries(since, now, r.FormValue("channel_id"))
+	if err != nil {
+		p.client.Log.Error("Error reading the audit log", "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, _ := json.Marshal(entries)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func executeAudit(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, auditOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	now := time.Now()
+	channelName, since, err := parseAuditArgs(args, now)
+	if err != nil {
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	channelID := ""
+	if channelName != "" {
+		channel, appErr := p.API.GetChannelByName(context.TeamId, channelName, false)
+		if appErr != nil {
+			postCommandResponse(context, fmt.Sprintf("Channel **%s** not found.", channelName))
+			return &model.CommandResponse{}
+		}
+		channelID = channel.Id
+	}
+
+	entries, err := service.GetAuditEntries(since, now, channelID)
+	if err != nil {
+		p.client.Log.Error("Error reading the audit log", "error", err.Error())
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, p.formatAuditEntries(entries))
+	return &model.CommandResponse{}
+}
+
+// parseAuditArgs parses the arguments of `/confluence audit [channel] [--since <period>]`.
+func parseAuditArgs(args []string, now time.Time) (string, time.Time, error) {
+	channelName := ""
+	since := now.Add(-defaultAuditPeriod)
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--since":
+			if i+1 >= len(args) {
+				return "", time.Time{}, errors.New("Please specify a value for `--since`, e.g. `7d`, `12h` or `2024-01-31`.")
+			}
+			i++
+			var err error
+			if since, err = parseSince(args[i], now); err != nil {
+				return "", time.Time{}, err
+			}
+		case channelName == "":
+			channelName = strings.TrimPrefix(args[i], "~")
+		default:
+			return "", time.Time{}, errors.Errorf("Unexpected argument %q.", args[i])
+		}
+	}
+	return channelName, since, nil
+}
+
+// parseSince parses a period such as "7d" or "12h", or a "YYYY-MM-DD" date, into a point in time before now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(auditDateLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.Errorf("Invalid value %q for `--since`, use e.g. `7d`, `12h` or `2024-01-31`.", value)
+}
+
+func (p *Plugin) formatAuditEntries(entries []service.AuditEntry) string {
+	if len(entries) == 0 {
+		return noAuditEntries
+	}
+
+	usernames := map[string]string{}
+	channelNames := map[string]string{}
+
+	var sb strings.Builder
+	sb.WriteString("| Time (UTC) | Action | User | Channel | Subscription |\n| :--- | :--- | :--- | :--- | :--- |\n")
+	shown := 0
+	for i := len(entries) - 1; i >= 0 && shown < maxAuditCommandEntries; i-- {
+		entry := entries[i]
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n",
+			time.UnixMilli(entry.Timestamp).UTC().Format("2006-01-02 15:04"),
+			entry.Action,
+			p.auditActor(entry, usernames),
+			p.auditChannelName(entry.ChannelID, channelNames),
+			entry.Alias,
+		)
+		shown++
+	}
+
+	if len(entries) > shown {
+		fmt.Fprintf(&sb, "\nShowing the latest %d of %d entries. Use the `%s/api/v1%s` endpoint to export the full audit log.", shown, len(entries), util.GetPluginURL(), exportAuditLog.Path)
+	}
+	return sb.String()
+}
+
+// auditActor returns the user who made the change, followed by the user whose connection changed when they differ.
+func (p *Plugin) auditActor(entry service.AuditEntry, usernames map[string]string) string {
+	actor := p.auditUsername(entry.ActorID, usernames)
+	if entry.ActorID == "" {
+		actor = "system"
+	}
+	if entry.UserID == "" || entry.UserID == entry.ActorID {
+		return actor
+	}
+	return fmt.Sprintf("%s for %s", actor, p.auditUsername(entry.UserID, usernames))
+}
+
+func (p *Plugin) auditUsername(userID string, cache map[string]string) string {
+	if userID == "" {
+		return ""
+	}
+	if name, ok := cache[userID]; ok {
+		return name
+	}
+	name := userID
+	if user, appErr := p.API.GetUser(userID); appErr == nil {
+		name = "@" + user.Username
+	}
+	cache[userID] = name
+	return name
+}
+
+func (p *Plugin) auditChannelName(channelID string, cache map[string]string) string {
+	if channelID == "" {
+		return ""
+	}
+	if name, ok := cache[channelID]; ok {
+		return name
+	}
+	name := channelID
+	if channel, appErr := p.API.GetChannel(channelID); appErr == nil {
+		name = "~" + channel.Name
+	}
+	cache[channelID] = name
+	return name
+}
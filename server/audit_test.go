@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuditArgs(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	for name, val := range map[string]struct {
+		args        []string
+		channelName string
+		since       time.Time
+		isError     bool
+	}{
+		"no arguments": {
+			since: now.Add(-defaultAuditPeriod),
+		},
+		"channel": {
+			args:        []string{"~town-square"},
+			channelName: "town-square",
+			since:       now.Add(-defaultAuditPeriod),
+		},
+		"since days": {
+			args:  []string{"--since", "30d"},
+			since: now.AddDate(0, 0, -30),
+		},
+		"channel and since duration": {
+			args:        []string{"town-square", "--since", "12h"},
+			channelName: "town-square",
+			since:       now.Add(-12 * time.Hour),
+		},
+		"since date": {
+			args:  []string{"--since", "2024-05-01"},
+			since: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"missing since value": {
+			args:    []string{"--since"},
+			isError: true,
+		},
+		"invalid since value": {
+			args:    []string{"--since", "yesterday"},
+			isError: true,
+		},
+		"too many arguments": {
+			args:    []string{"town-square", "off-topic"},
+			isError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			channelName, since, err := parseAuditArgs(val.args, now)
+			if val.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, val.channelName, channelName)
+			assert.True(t, val.since.Equal(since), "expected %s, got %s", val.since, since)
+		})
+	}
+}
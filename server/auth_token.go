@@ -7,9 +7,12 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"io"
+	"strings"
 
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
@@ -17,13 +20,34 @@ import (
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
 )
 
+// keyIDSeparator separates the encryption key ID from the encrypted token. It is not part of the base64 URL alphabet,
+// so tokens encoded before key IDs were introduced can still be told apart.
+const keyIDSeparator = "."
+
 type AuthToken struct {
 	Token *oauth2.Token `json:"token,omitempty"`
 }
 
+// encryptionKeyID returns the ID recorded in the tokens encrypted with the key.
+func encryptionKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// tokenKeyID returns the ID of the key the token was encrypted with, or an empty string for tokens without key ID.
+func tokenKeyID(encoded string) string {
+	keyID, _, found := strings.Cut(encoded, keyIDSeparator)
+	if !found {
+		return ""
+	}
+	return keyID
+}
+
 func (p *Plugin) NewEncodedAuthToken(token *oauth2.Token) (encodedToken string, returnErr error) {
-	encryptionSecret := config.GetConfig().EncryptionKey
+	return p.encodeAuthToken(token, config.GetConfig().EncryptionKey)
+}
 
+func (p *Plugin) encodeAuthToken(token *oauth2.Token, encryptionSecret string) (string, error) {
 	t := AuthToken{
 		Token: token,
 	}
@@ -40,20 +64,20 @@ func (p *Plugin) NewEncodedAuthToken(token *oauth2.Token) (encodedToken string,
 		return "", err
 	}
 
-	return encode(encrypted), nil
+	return encryptionKeyID(encryptionSecret) + keyIDSeparator + encode(encrypted), nil
 }
 
 func (p *Plugin) ParseAuthToken(encoded string) (token *oauth2.Token, returnErr error) {
 	t := AuthToken{}
-	encryptionSecret := config.GetConfig().EncryptionKey
+	keyID := tokenKeyID(encoded)
 
-	decoded, err := decode(encoded)
+	decoded, err := decode(strings.TrimPrefix(encoded, keyID+keyIDSeparator))
 	if err != nil {
 		p.client.Log.Error("Error decoding the auth token", "error", err.Error())
 		return nil, err
 	}
 
-	jsonBytes, err := decrypt(decoded, []byte(encryptionSecret))
+	jsonBytes, err := p.decryptWithKeyring(decoded, keyID)
 	if err != nil {
 		p.client.Log.Error("Error decrypting the auth token", "error", err.Error())
 		return nil, err
@@ -67,6 +91,29 @@ func (p *Plugin) ParseAuthToken(encoded string) (token *oauth2.Token, returnErr
 	return t.Token, nil
 }
 
+// decryptWithKeyring decrypts the token with the current encryption key, falling back to the previous keys
+// when the token was encrypted before the key was rotated.
+func (p *Plugin) decryptWithKeyring(encrypted []byte, keyID string) ([]byte, error) {
+	currentKey := config.GetConfig().EncryptionKey
+	if keyID == "" || keyID == encryptionKeyID(currentKey) {
+		plain, err := decrypt(encrypted, []byte(currentKey))
+		if err == nil || keyID != "" {
+			return plain, err
+		}
+	}
+
+	for _, key := range config.GetConfig().GetPreviousEncryptionKeys() {
+		if key == currentKey || (keyID != "" && encryptionKeyID(key) != keyID) {
+			continue
+		}
+		if plain, err := decrypt(encrypted, []byte(key)); err == nil {
+			return plain, nil
+		}
+	}
+
+	return nil, errors.Errorf("no encryption key found to decrypt the token, key ID %q", keyID)
+}
+
 func encode(encrypted []byte) string {
 	encoded := make([]byte, base64.URLEncoding.EncodedLen(len(encrypted)))
 	base64.URLEncoding.Encode(encoded, encrypted)
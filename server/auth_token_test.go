@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func TestParseAuthTokenWithRotatedKeys(t *testing.T) {
+	oldKey := "0123456789abcdef0123456789abcdef"
+	newKey := "fedcba9876543210fedcba9876543210"
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}
+
+	setup := func(t *testing.T) *Plugin {
+		mockAPI := &plugintest.API{}
+		config.Mattermost = mockAPI
+		p := &Plugin{}
+		p.SetAPI(mockAPI)
+		p.client = pluginapi.NewClient(mockAPI, nil)
+
+		mockAPI.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		return p
+	}
+
+	encodeWith := func(t *testing.T, p *Plugin, key string) string {
+		encoded, err := p.encodeAuthToken(token, key)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	t.Run("token encrypted with the current key", func(t *testing.T) {
+		p := setup(t)
+		config.SetConfig(&config.Configuration{EncryptionKey: newKey})
+
+		encoded := encodeWith(t, p, newKey)
+		assert.Equal(t, encryptionKeyID(newKey), tokenKeyID(encoded))
+
+		parsed, err := p.ParseAuthToken(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, token.AccessToken, parsed.AccessToken)
+	})
+
+	t.Run("token encrypted with a previous key", func(t *testing.T) {
+		p := setup(t)
+		config.SetConfig(&config.Configuration{EncryptionKey: newKey, PreviousEncryptionKeys: oldKey})
+
+		parsed, err := p.ParseAuthToken(encodeWith(t, p, oldKey))
+		require.NoError(t, err)
+		assert.Equal(t, token.RefreshToken, parsed.RefreshToken)
+	})
+
+	t.Run("legacy token without key ID", func(t *testing.T) {
+		p := setup(t)
+		config.SetConfig(&config.Configuration{EncryptionKey: newKey, PreviousEncryptionKeys: oldKey})
+
+		encrypted, err := encrypt([]byte(`{"token":{"access_token":"access"}}`), []byte(oldKey))
+		require.NoError(t, err)
+		legacy := encode(encrypted)
+		assert.Empty(t, tokenKeyID(legacy))
+
+		parsed, err := p.ParseAuthToken(legacy)
+		require.NoError(t, err)
+		assert.Equal(t, "access", parsed.AccessToken)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		p := setup(t)
+		config.SetConfig(&config.Configuration{EncryptionKey: newKey})
+
+		_, err := p.ParseAuthToken(encodeWith(t, p, oldKey))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,270 @@
+// Package cache keeps the results of Confluence API lookups that rarely change. The entries are shared across the
+// cluster through the KV store, with an in-memory LRU front on each server. Invalidations are broadcast to the
+// other servers with a plugin cluster event.
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+// ClusterEventInvalidate is the ID of the cluster event sent when an entry is invalidated.
+const ClusterEventInvalidate = "cache_invalidate"
+
+// Results of a cache lookup
+const (
+	resultMemoryHit = "memory_hit"
+	resultKVHit     = "kv_hit"
+	resultMiss      = "miss"
+)
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]invalidator{}
+)
+
+type invalidator interface {
+	invalidateLocal(key string)
+	Stats() Stats
+}
+
+// Stats counts the lookups of a cache on this server since the plugin started.
+type Stats struct {
+	Name       string
+	MemoryHits uint64
+	KVHits     uint64
+	Misses     uint64
+}
+
+// HitRate returns the share of the lookups answered without calling Confluence.
+func (s Stats) HitRate() float64 {
+	total := s.MemoryHits + s.KVHits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.MemoryHits+s.KVHits) / float64(total)
+}
+
+// Cache is a TTL cache of values of type V.
+type Cache[V any] struct {
+	name string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+
+	memoryHits atomic.Uint64
+	kvHits     atomic.Uint64
+	misses     atomic.Uint64
+
+	now func() time.Time
+}
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// storedValue is the KV representation of an entry. The expiry is stored as well, so that the in-memory copies
+// of an entry read from the KV store expire with it.
+type storedValue[V any] struct {
+	Value     V     `json:"value"`
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// New creates a cache keeping its entries for ttl, and up to size of them in memory.
+func New[V any](name string, ttl time.Duration, size int) *Cache[V] {
+	c := &Cache[V]{
+		name:    name,
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+		now:     time.Now,
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[name] = c
+	return c
+}
+
+// Get returns the cached value for the key.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	if value, ok := c.getLocal(key); ok {
+		c.memoryHits.Add(1)
+		metrics.CacheRequests.WithLabelValues(c.name, resultMemoryHit).Inc()
+		return value, true
+	}
+
+	var zero V
+	data, appErr := config.Mattermost.KVGet(store.GetCacheKey(c.name, key))
+	if appErr != nil || data == nil {
+		c.misses.Add(1)
+		metrics.CacheRequests.WithLabelValues(c.name, resultMiss).Inc()
+		return zero, false
+	}
+
+	var stored storedValue[V]
+	if err := json.Unmarshal(data, &stored); err != nil || !c.now().Before(time.UnixMilli(stored.ExpiresAt)) {
+		c.misses.Add(1)
+		metrics.CacheRequests.WithLabelValues(c.name, resultMiss).Inc()
+		return zero, false
+	}
+
+	c.setLocal(key, stored.Value, time.UnixMilli(stored.ExpiresAt))
+	c.kvHits.Add(1)
+	metrics.CacheRequests.WithLabelValues(c.name, resultKVHit).Inc()
+	return stored.Value, true
+}
+
+// Set caches the value for the key. Failures to write the KV store are logged, the value is still cached in memory.
+func (c *Cache[V]) Set(key string, value V) {
+	expiresAt := c.now().Add(c.ttl)
+	c.setLocal(key, value, expiresAt)
+
+	data, err := json.Marshal(storedValue[V]{Value: value, ExpiresAt: expiresAt.UnixMilli()})
+	if err != nil {
+		config.Mattermost.LogWarn("Unable to encode the cache entry", "Cache", c.name, "Error", err.Error())
+		return
+	}
+	if appErr := config.Mattermost.KVSetWithExpiry(store.GetCacheKey(c.name, key), data, int64(c.ttl.Seconds())); appErr != nil {
+		config.Mattermost.LogWarn("Unable to store the cache entry", "Cache", c.name, "Error", appErr.Error())
+	}
+}
+
+// GetOrLoad returns the cached value for the key, or loads and caches it. Errors are not cached.
+func (c *Cache[V]) GetOrLoad(key string, load func() (V, error)) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	c.Set(key, value)
+	return value, nil
+}
+
+// Invalidate removes the entry from the KV store and from the memory of every server.
+func (c *Cache[V]) Invalidate(key string) {
+	c.invalidateLocal(key)
+	if appErr := config.Mattermost.KVDelete(store.GetCacheKey(c.name, key)); appErr != nil {
+		config.Mattermost.LogWarn("Unable to delete the cache entry", "Cache", c.name, "Error", appErr.Error())
+	}
+
+	data, _ := json.Marshal(clusterInvalidation{Name: c.name, Key: key})
+	if appErr := config.Mattermost.PublishPluginClusterEvent(
+		model.PluginClusterEvent{Id: ClusterEventInvalidate, Data: data},
+		model.PluginClusterEventSendOptions{SendType: model.PluginClusterEventSendTypeReliable},
+	); appErr != nil {
+		config.Mattermost.LogWarn("Unable to notify the cluster of the cache invalidation", "Cache", c.name, "Error", appErr.Error())
+	}
+}
+
+// Stats returns the lookup counts of the cache.
+func (c *Cache[V]) Stats() Stats {
+	return Stats{
+		Name:       c.name,
+		MemoryHits: c.memoryHits.Load(),
+		KVHits:     c.kvHits.Load(),
+		Misses:     c.misses.Load(),
+	}
+}
+
+func (c *Cache[V]) getLocal(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := element.Value.(*entry[V])
+	if !c.now().Before(e.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+func (c *Cache[V]) setLocal(key string, value V, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &entry[V]{key: key, value: value, expiresAt: expiresAt}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[V]).key)
+	}
+}
+
+func (c *Cache[V]) invalidateLocal(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+type clusterInvalidation struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// HandleClusterEvent drops the in-memory copy of an entry invalidated by another server.
+func HandleClusterEvent(event model.PluginClusterEvent) {
+	var invalidation clusterInvalidation
+	if err := json.Unmarshal(event.Data, &invalidation); err != nil {
+		config.Mattermost.LogWarn("Unable to decode the cache invalidation", "Error", err.Error())
+		return
+	}
+
+	registryLock.RLock()
+	c, ok := registry[invalidation.Name]
+	registryLock.RUnlock()
+	if ok {
+		c.invalidateLocal(invalidation.Key)
+	}
+}
+
+// AllStats returns the lookup counts of every cache, sorted by name.
+func AllStats() []Stats {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	stats := make([]Stats, 0, len(registry))
+	for _, c := range registry {
+		stats = append(stats, c.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
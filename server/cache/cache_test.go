@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+func newTestCache(t *testing.T, size int) (*Cache[string], *plugintest.API, *time.Time) {
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	mockAPI.On("KVSetWithExpiry", mock.Anything, mock.Anything, int64(3600)).Return(nil)
+	mockAPI.On("KVGet", mock.Anything).Return(nil, nil)
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c := New[string](t.Name(), time.Hour, size)
+	c.now = func() time.Time { return now }
+	return c, mockAPI, &now
+}
+
+func TestCacheGet(t *testing.T) {
+	for name, tc := range map[string]struct {
+		setup         func(c *Cache[string], now *time.Time)
+		expectedFound bool
+		expectedStats Stats
+	}{
+		"memory hit": {
+			setup: func(c *Cache[string], _ *time.Time) {
+				c.Set("1", "DEV")
+			},
+			expectedFound: true,
+			expectedStats: Stats{MemoryHits: 1},
+		},
+		"expired": {
+			setup: func(c *Cache[string], now *time.Time) {
+				c.Set("1", "DEV")
+				*now = now.Add(time.Hour)
+			},
+			expectedStats: Stats{Misses: 1},
+		},
+		"evicted": {
+			setup: func(c *Cache[string], _ *time.Time) {
+				c.Set("1", "DEV")
+				c.Set("2", "OPS")
+				c.Set("3", "QA")
+			},
+			expectedStats: Stats{Misses: 1},
+		},
+		"recently used entry kept": {
+			setup: func(c *Cache[string], _ *time.Time) {
+				c.Set("1", "DEV")
+				c.Set("2", "OPS")
+				c.Get("1")
+				c.Set("3", "QA")
+			},
+			expectedFound: true,
+			expectedStats: Stats{MemoryHits: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, _, now := newTestCache(t, 2)
+			tc.setup(c, now)
+
+			value, found := c.Get("1")
+			assert.Equal(t, tc.expectedFound, found)
+			if found {
+				assert.Equal(t, "DEV", value)
+			}
+
+			tc.expectedStats.Name = t.Name()
+			assert.Equal(t, tc.expectedStats, c.Stats())
+		})
+	}
+}
+
+func TestCacheKVHit(t *testing.T) {
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c := New[string](t.Name(), time.Hour, 10)
+	c.now = func() time.Time { return now }
+
+	// The entry was cached by another server.
+	data, err := json.Marshal(storedValue[string]{Value: "DEV", ExpiresAt: now.Add(time.Minute).UnixMilli()})
+	require.NoError(t, err)
+	mockAPI.On("KVGet", store.GetCacheKey(t.Name(), "1")).Return(data, nil).Once()
+
+	value, err := c.GetOrLoad("1", func() (string, error) {
+		t.Fatal("the value must not be loaded")
+		return "", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "DEV", value)
+
+	// The in-memory copy expires with the KV entry.
+	_, found := c.Get("1")
+	assert.True(t, found)
+	now = now.Add(time.Minute)
+	mockAPI.On("KVGet", store.GetCacheKey(t.Name(), "1")).Return(nil, nil)
+	_, found = c.Get("1")
+	assert.False(t, found)
+
+	assert.Equal(t, Stats{Name: t.Name(), MemoryHits: 1, KVHits: 1, Misses: 1}, c.Stats())
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	c, mockAPI, _ := newTestCache(t, 10)
+
+	loads := 0
+	load := func() (string, error) {
+		loads++
+		return "DEV", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := c.GetOrLoad("1", load)
+		require.NoError(t, err)
+		assert.Equal(t, "DEV", value)
+	}
+	assert.Equal(t, 1, loads)
+	mockAPI.AssertCalled(t, "KVSetWithExpiry", store.GetCacheKey(t.Name(), "1"), mock.Anything, int64(3600))
+	assert.InDelta(t, 2.0/3, c.Stats().HitRate(), 0.001)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	c, mockAPI, _ := newTestCache(t, 10)
+	mockAPI.On("KVDelete", store.GetCacheKey(t.Name(), "1")).Return(nil)
+	var published model.PluginClusterEvent
+	mockAPI.On("PublishPluginClusterEvent", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		published = args.Get(0).(model.PluginClusterEvent)
+	})
+
+	c.Set("1", "DEV")
+	c.Invalidate("1")
+
+	_, found := c.Get("1")
+	assert.False(t, found)
+	mockAPI.AssertCalled(t, "KVDelete", store.GetCacheKey(t.Name(), "1"))
+
+	assert.Equal(t, ClusterEventInvalidate, published.Id)
+
+	// The other servers drop their in-memory copy when they receive the event.
+	c.Set("1", "DEV")
+	HandleClusterEvent(published)
+	_, found = c.getLocal("1")
+	assert.False(t, found)
+}
+
+func TestStatsHitRate(t *testing.T) {
+	assert.Equal(t, 0.0, Stats{}.HitRate())
+	assert.Equal(t, 0.75, Stats{MemoryHits: 2, KVHits: 1, Misses: 1}.HitRate())
+}
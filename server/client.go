@@ -13,6 +13,9 @@ type Client interface {
 type RESTService interface {
 	GetSelf() (*types.ConfluenceUser, error)
 	GetSpaceData(string) (*SpaceResponse, error)
+	CheckSpaceAccess(string) error
 	GetPageData(int) (*PageResponse, error)
 	GetSpaceKeyFromSpaceID(int64) (string, error)
+	GetPageAncestors(int) ([]PageAncestor, error)
+	HasReadRestrictions(string) (bool, error)
 }
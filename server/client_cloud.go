@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+const (
+	PathCloudCurrentUser = "/wiki/rest/api/user/current"
+	PathCloudContentData = "/wiki/rest/api/content/"
+	PathCloudSpaceData   = "/wiki/rest/api/space"
+	PathCloudUserData    = "/wiki/rest/api/user"
+)
+
+// confluenceCloudClient calls the REST API of a Confluence Cloud site through the Atlassian API gateway, which is
+// the only way to use a 3LO token.
+type confluenceCloudClient struct {
+	URL        string
+	SiteURL    string
+	HTTPClient *http.Client
+}
+
+type ConfluenceCloudUser struct {
+	AccountID   string `json:"accountId"`
+	AccountType string `json:"accountType"`
+	DisplayName string `json:"displayName"`
+	PublicName  string `json:"publicName"`
+	Email       string `json:"email"`
+}
+
+type cloudSpacesResponse struct {
+	Results []SpaceResponse `json:"results"`
+}
+
+func newCloudClient(siteURL, cloudID string, httpClient *http.Client) Client {
+	return &confluenceCloudClient{
+		URL:        fmt.Sprintf("%s/ex/confluence/%s", atlassianAPIURL, cloudID),
+		SiteURL:    siteURL,
+		HTTPClient: httpClient,
+	}
+}
+
+func (ccc *confluenceCloudClient) GetSelf() (*types.ConfluenceUser, error) {
+	cloudUser := &ConfluenceCloudUser{}
+	if _, _, err := service.CallJSONWithURL(ccc.URL, PathCloudCurrentUser, http.MethodGet, nil, cloudUser, ccc.HTTPClient); err != nil {
+		return nil, errors.Wrap(err, "Confluence Cloud GetSelf. Error getting the current user")
+	}
+
+	return &types.ConfluenceUser{
+		AccountID:   cloudUser.AccountID,
+		Name:        cloudUser.PublicName,
+		DisplayName: cloudUser.DisplayName,
+	}, nil
+}
+
+func (ccc *confluenceCloudClient) GetPageData(pageID int) (*PageResponse, error) {
+	pageResponse := &PageResponse{}
+	if _, _, err := service.CallJSONWithURL(ccc.URL, fmt.Sprintf("%s%s?status=any&expand=body.view,container,space,history,version,ancestors", PathCloudContentData, strconv.Itoa(pageID)), http.MethodGet, nil, pageResponse, ccc.HTTPClient); err != nil {
+		return nil, err
+	}
+
+	pageResponse.Body.View.Value = util.GetBodyForExcerpt(pageResponse.Body.View.Value)
+
+	return pageResponse, nil
+}
+
+// GetPageAncestors returns the parent pages of the page, from the root of the space down to its direct parent.
+// It is used to check restrictions with the access of the user of the client, so it is not cached.
+func (ccc *confluenceCloudClient) GetPageAncestors(pageID int) ([]PageAncestor, error) {
+	pageResponse := &PageResponse{}
+	if _, _, err := service.CallJSONWithURL(ccc.URL, fmt.Sprintf("%s%s?status=any&expand=ancestors", PathCloudContentData, strconv.Itoa(pageID)), http.MethodGet, nil, pageResponse, ccc.HTTPClient); err != nil {
+		return nil, err
+	}
+	return pageResponse.Ancestors, nil
+}
+
+// HasReadRestrictions tells whether the viewing of the content is restricted. The restrictions of the ancestors
+// of a page are not included.
+func (ccc *confluenceCloudClient) HasReadRestrictions(contentID string) (bool, error) {
+	restrictions := &ContentRestrictions{}
+	if _, _, err := service.CallJSONWithURL(ccc.URL, fmt.Sprintf("%s%s/restriction/byOperation/read", PathCloudContentData, url.PathEscape(contentID)), http.MethodGet, nil, restrictions, ccc.HTTPClient); err != nil {
+		return false, errors.Wrap(err, "Confluence Cloud HasReadRestrictions. Error getting the content restrictions")
+	}
+
+	return restrictions.IsRestricted(), nil
+}
+
+func (ccc *confluenceCloudClient) GetSpaceData(spaceKey string) (*SpaceResponse, error) {
+	return cachedSpace(ccc.SiteURL, spaceKey, func() (*SpaceResponse, error) {
+		return ccc.getSpaceData(spaceKey)
+	})
+}
+
+// CheckSpaceAccess checks that the user of the client can see the space. Unlike GetSpaceData, it is never cached.
+func (ccc *confluenceCloudClient) CheckSpaceAccess(spaceKey string) error {
+	_, err := ccc.getSpaceData(spaceKey)
+	return err
+}
+
+func (ccc *confluenceCloudClient) getSpaceData(spaceKey string) (*SpaceResponse, error) {
+	spaceResponse := &SpaceResponse{}
+	if _, _, err := service.CallJSONWithURL(ccc.URL, fmt.Sprintf("%s/%s?expand=description.plain", PathCloudSpaceData, url.PathEscape(spaceKey)), http.MethodGet, nil, spaceResponse, ccc.HTTPClient); err != nil {
+		return nil, err
+	}
+
+	return spaceResponse, nil
+}
+
+func (ccc *confluenceCloudClient) GetSpaceKeyFromSpaceID(spaceID int64) (string, error) {
+	return spaceKeyCache.GetOrLoad(lookupCacheKey(ccc.SiteURL, strconv.FormatInt(spaceID, 10)), func() (string, error) {
+		response := &cloudSpacesResponse{}
+		if _, _, err := service.CallJSONWithURL(ccc.URL, fmt.Sprintf("%s?spaceId=%d", PathCloudSpaceData, spaceID), http.MethodGet, nil, response, ccc.HTTPClient); err != nil {
+			return "", errors.Wrap(err, "Confluence Cloud GetSpaceKeyFromSpaceID")
+		}
+
+		for _, space := range response.Results {
+			if space.ID == spaceID {
+				return space.Key, nil
+			}
+		}
+		return "", fmt.Errorf("confluence Cloud GetSpaceKeyFromSpaceID: no space key found for the space ID")
+	})
+}
+
+// GetCommentData returns the comment, with its body rendered for an excerpt.
+func (ccc *confluenceCloudClient) GetCommentData(commentID string) (*CommentResponse, error) {
+	commentResponse := &CommentResponse{}
+	if _, _, err := service.CallJSONWithURL(ccc.URL, fmt.Sprintf("%s%s?expand=body.view,container,space,history", PathCloudContentData, url.PathEscape(commentID)), http.MethodGet, nil, commentResponse, ccc.HTTPClient); err != nil {
+		return nil, err
+	}
+
+	commentResponse.Body.View.Value = util.GetBodyForExcerpt(commentResponse.Body.View.Value)
+
+	return commentResponse, nil
+}
+
+func (ccc *confluenceCloudClient) GetUserFromAccountID(accountID string) (*ConfluenceUser, error) {
+	return cachedUser(ccc.SiteURL, accountID, func() (*ConfluenceUser, error) {
+		cloudUser := &ConfluenceCloudUser{}
+		if _, _, err := service.CallJSONWithURL(ccc.URL, fmt.Sprintf("%s?accountId=%s", PathCloudUserData, url.QueryEscape(accountID)), http.MethodGet, nil, cloudUser, ccc.HTTPClient); err != nil {
+			return nil, fmt.Errorf("error fetching user data: %w", err)
+		}
+
+		return &ConfluenceUser{
+			DisplayName: cloudUser.DisplayName,
+			Email:       cloudUser.Email,
+			Type:        cloudUser.AccountType,
+			UserKey:     cloudUser.AccountID,
+		}, nil
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -20,6 +21,7 @@ const (
 	PathSpaceData   = "/rest/api/space/"
 	PathUserData    = "/rest/api/user/"
 	PathAdminData   = "/rest/api/audit"
+	PathWebhooks    = "/rest/api/webhooks"
 )
 
 const (
@@ -48,10 +50,15 @@ type AdminData struct {
 }
 
 type SpaceResponse struct {
-	ID    int64  `json:"id"`
-	Key   string `json:"key"`
-	Name  string `json:"name"`
-	Links Links  `json:"_links"`
+	ID          int64            `json:"id"`
+	Key         string           `json:"key"`
+	Name        string           `json:"name"`
+	Description SpaceDescription `json:"description"`
+	Links       Links            `json:"_links"`
+}
+
+type SpaceDescription struct {
+	Plain View `json:"plain"`
 }
 
 type CommentContainer struct {
@@ -81,6 +88,12 @@ type History struct {
 	CreatedBy CreatedBy `json:"createdBy"`
 }
 
+type ContentVersion struct {
+	Number    int    `json:"number"`
+	MinorEdit bool   `json:"minorEdit"`
+	Message   string `json:"message"`
+}
+
 type CommentResponse struct {
 	ID        string           `json:"id"`
 	Title     string           `json:"title"`
@@ -91,13 +104,37 @@ type CommentResponse struct {
 	History   History          `json:"history"`
 }
 
+type PageAncestor struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ContentRestrictions is the response of the restrictions API for a single operation.
+type ContentRestrictions struct {
+	Restrictions struct {
+		User  RestrictionSubjects `json:"user"`
+		Group RestrictionSubjects `json:"group"`
+	} `json:"restrictions"`
+}
+
+type RestrictionSubjects struct {
+	Size int `json:"size"`
+}
+
+// IsRestricted tells whether the operation is limited to some users or groups.
+func (cr *ContentRestrictions) IsRestricted() bool {
+	return cr.Restrictions.User.Size > 0 || cr.Restrictions.Group.Size > 0
+}
+
 type PageResponse struct {
-	ID      string        `json:"id"`
-	Title   string        `json:"title"`
-	Space   SpaceResponse `json:"space"`
-	Body    Body          `json:"body"`
-	Links   Links         `json:"_links"`
-	History History       `json:"history"`
+	ID        string         `json:"id"`
+	Title     string         `json:"title"`
+	Space     SpaceResponse  `json:"space"`
+	Body      Body           `json:"body"`
+	Links     Links          `json:"_links"`
+	History   History        `json:"history"`
+	Version   ContentVersion `json:"version"`
+	Ancestors []PageAncestor `json:"ancestors"`
 }
 
 type ConfluenceServerEvent struct {
@@ -105,6 +142,8 @@ type ConfluenceServerEvent struct {
 	Page    *PageResponse
 	Space   *SpaceResponse
 	BaseURL string
+	// Actor is the @mention of the Mattermost user who triggered the event, when the Confluence user is known.
+	Actor string
 }
 
 func newServerClient(url string, httpClient *http.Client) Client {
@@ -147,7 +186,10 @@ func (csc *confluenceServerClient) GetEventData(webhookPayload *serializer.Confl
 		}
 	}
 
-	if strings.Contains(webhookPayload.Event, Space) {
+	if webhookPayload.Event == serializer.SpaceRemovedEvent {
+		// A removed space cannot be fetched anymore.
+		confluenceServerEvent.Space = &SpaceResponse{Key: webhookPayload.Space.SpaceKey}
+	} else if strings.Contains(webhookPayload.Event, Space) {
 		confluenceServerEvent.Space, err = csc.GetSpaceData(webhookPayload.Space.SpaceKey)
 		if err != nil {
 			return nil, errors.Errorf("error getting space data for the event. SpaceKey %s. Error: %v", webhookPayload.Space.SpaceKey, err)
@@ -170,7 +212,7 @@ func (csc *confluenceServerClient) GetCommentData(webhookPayload *serializer.Con
 
 func (csc *confluenceServerClient) GetPageData(pageID int) (*PageResponse, error) {
 	pageResponse := &PageResponse{}
-	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s%s?status=any&expand=body.view,container,space,history", PathContentData, strconv.Itoa(pageID)), http.MethodGet, nil, pageResponse, csc.HTTPClient); err != nil {
+	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s%s?status=any&expand=body.view,container,space,history,version,ancestors", PathContentData, strconv.Itoa(pageID)), http.MethodGet, nil, pageResponse, csc.HTTPClient); err != nil {
 		return nil, err
 	}
 
@@ -179,9 +221,42 @@ func (csc *confluenceServerClient) GetPageData(pageID int) (*PageResponse, error
 	return pageResponse, nil
 }
 
+// GetPageAncestors returns the parent pages of the page, from the root of the space down to its direct parent.
+// It is used to check restrictions with the access of the user of the client, so it is not cached.
+func (csc *confluenceServerClient) GetPageAncestors(pageID int) ([]PageAncestor, error) {
+	pageResponse := &PageResponse{}
+	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s%s?status=any&expand=ancestors", PathContentData, strconv.Itoa(pageID)), http.MethodGet, nil, pageResponse, csc.HTTPClient); err != nil {
+		return nil, err
+	}
+	return pageResponse.Ancestors, nil
+}
+
+// HasReadRestrictions tells whether the viewing of the content is restricted. The restrictions of the ancestors
+// of a page are not included.
+func (csc *confluenceServerClient) HasReadRestrictions(contentID string) (bool, error) {
+	restrictions := &ContentRestrictions{}
+	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s%s/restriction/byOperation/read", PathContentData, url.PathEscape(contentID)), http.MethodGet, nil, restrictions, csc.HTTPClient); err != nil {
+		return false, errors.Wrap(err, "Confluence HasReadRestrictions. Error getting the content restrictions")
+	}
+
+	return restrictions.IsRestricted(), nil
+}
+
 func (csc *confluenceServerClient) GetSpaceData(spaceKey string) (*SpaceResponse, error) {
+	return cachedSpace(csc.URL, spaceKey, func() (*SpaceResponse, error) {
+		return csc.getSpaceData(spaceKey)
+	})
+}
+
+// CheckSpaceAccess checks that the user of the client can see the space. Unlike GetSpaceData, it is never cached.
+func (csc *confluenceServerClient) CheckSpaceAccess(spaceKey string) error {
+	_, err := csc.getSpaceData(spaceKey)
+	return err
+}
+
+func (csc *confluenceServerClient) getSpaceData(spaceKey string) (*SpaceResponse, error) {
 	spaceResponse := &SpaceResponse{}
-	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s%s?status=any", PathSpaceData, spaceKey), http.MethodGet, nil, spaceResponse, csc.HTTPClient); err != nil {
+	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s%s?status=any&expand=description.plain", PathSpaceData, url.PathEscape(spaceKey)), http.MethodGet, nil, spaceResponse, csc.HTTPClient); err != nil {
 		return nil, err
 	}
 
@@ -198,6 +273,12 @@ type apiResponse struct {
 }
 
 func (csc *confluenceServerClient) GetSpaceKeyFromSpaceID(spaceID int64) (string, error) {
+	return spaceKeyCache.GetOrLoad(lookupCacheKey(csc.URL, strconv.FormatInt(spaceID, 10)), func() (string, error) {
+		return csc.getSpaceKeyFromSpaceID(spaceID)
+	})
+}
+
+func (csc *confluenceServerClient) getSpaceKeyFromSpaceID(spaceID int64) (string, error) {
 	start := 0
 
 	for {
@@ -227,6 +308,7 @@ func (csc *confluenceServerClient) GetSpaceKeyFromSpaceID(spaceID int64) (string
 
 type ConfluenceUser struct {
 	DisplayName    string `json:"displayName"`
+	Email          string `json:"email"`
 	Type           string `json:"type"`
 	UserKey        string `json:"userKey"`
 	Username       string `json:"username"`
@@ -245,11 +327,71 @@ type ConfluenceUser struct {
 }
 
 func (csc *confluenceServerClient) GetUserFromUserKey(userKey string) (*ConfluenceUser, error) {
-	var user ConfluenceUser
+	return cachedUser(csc.URL, userKey, func() (*ConfluenceUser, error) {
+		var user ConfluenceUser
+
+		if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s?key=%s", PathUserData, userKey), http.MethodGet, nil, &user, csc.HTTPClient); err != nil {
+			return nil, fmt.Errorf("error fetching user data: %w", err)
+		}
+
+		return &user, nil
+	})
+}
+
+type ServerWebhook struct {
+	ID            int64             `json:"id,omitempty"`
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Active        bool              `json:"active"`
+	Events        []string          `json:"events"`
+	Configuration map[string]string `json:"configuration,omitempty"`
+}
+
+type serverWebhooksResponse struct {
+	Values     []ServerWebhook `json:"values"`
+	IsLastPage bool            `json:"isLastPage"`
+}
 
-	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s?key=%s", PathUserData, userKey), http.MethodGet, nil, &user, csc.HTTPClient); err != nil {
-		return nil, fmt.Errorf("error fetching user data: %w", err)
+type ServerWebhookTestResult struct {
+	StatusCode int `json:"statusCode"`
+}
+
+func (csc *confluenceServerClient) ListWebhooks() ([]ServerWebhook, error) {
+	var webhooks []ServerWebhook
+	for start := 0; ; start += pageSize {
+		response := &serverWebhooksResponse{}
+		if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s?start=%d&limit=%d", PathWebhooks, start, pageSize), http.MethodGet, nil, response, csc.HTTPClient); err != nil {
+			return nil, errors.Wrap(err, "Confluence ListWebhooks")
+		}
+
+		webhooks = append(webhooks, response.Values...)
+		if response.IsLastPage || len(response.Values) < pageSize {
+			return webhooks, nil
+		}
 	}
+}
 
-	return &user, nil
+func (csc *confluenceServerClient) CreateWebhook(webhook *ServerWebhook) (*ServerWebhook, error) {
+	created := &ServerWebhook{}
+	if _, _, err := service.CallJSONWithURL(csc.URL, PathWebhooks, http.MethodPost, webhook, created, csc.HTTPClient); err != nil {
+		return nil, errors.Wrap(err, "Confluence CreateWebhook")
+	}
+	return created, nil
+}
+
+func (csc *confluenceServerClient) UpdateWebhook(webhook *ServerWebhook) (*ServerWebhook, error) {
+	updated := &ServerWebhook{}
+	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s/%d", PathWebhooks, webhook.ID), http.MethodPut, webhook, updated, csc.HTTPClient); err != nil {
+		return nil, errors.Wrap(err, "Confluence UpdateWebhook")
+	}
+	return updated, nil
+}
+
+// TestWebhook asks Confluence to send a test delivery to the URL, and returns the status code it got back.
+func (csc *confluenceServerClient) TestWebhook(webhookURL string) (*ServerWebhookTestResult, error) {
+	result := &ServerWebhookTestResult{}
+	if _, _, err := service.CallJSONWithURL(csc.URL, fmt.Sprintf("%s/test?url=%s", PathWebhooks, url.QueryEscape(webhookURL)), http.MethodPost, nil, result, csc.HTTPClient); err != nil {
+		return nil, errors.Wrap(err, "Confluence TestWebhook")
+	}
+	return result, nil
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+// enrichCloudEvent looks up the author, the space name and an excerpt of the content of the event through the
+// Confluence Cloud REST API. The event is left as is for what cannot be looked up, the notification then falls back
+// to the data of the webhook. The client used is returned, nil when the user who triggered the event is not connected.
+func (p *Plugin) enrichCloudEvent(instanceID string, event *serializer.ConfluenceCloudEvent, eventType string) *confluenceCloudClient {
+	if instanceID == "" {
+		return nil
+	}
+
+	client, err := p.getCloudEventClient(instanceID, event.UserAccountID)
+	if err != nil {
+		// When the user who triggered the event is not connected, there is nothing to call the REST API with.
+		if errors.Cause(err) == store.ErrNotFound {
+			return nil
+		}
+		p.client.Log.Debug("Not enriching the Confluence Cloud event", "InstanceID", instanceID, "error", err.Error())
+		return nil
+	}
+
+	if event.UserAccountID != "" {
+		email := ""
+		user, err := client.GetUserFromAccountID(event.UserAccountID)
+		if err != nil {
+			p.client.Log.Warn("Error getting the Confluence Cloud user", "AccountID", event.UserAccountID, "error", err.Error())
+		} else {
+			email = user.Email
+			event.Actor = user.DisplayName
+		}
+		if mention := p.getMattermostMention(instanceID, event.UserAccountID, email); mention != "" {
+			event.Actor = mention
+		}
+	}
+
+	if spaceKey := event.GetSpaceKey(); spaceKey != "" {
+		if space, err := client.GetSpaceData(spaceKey); err != nil {
+			p.client.Log.Warn("Error getting the Confluence Cloud space", "SpaceKey", spaceKey, "error", err.Error())
+		} else {
+			event.SpaceName = space.Name
+		}
+	}
+
+	switch eventType {
+	case serializer.PageCreatedEvent, serializer.PageUpdatedEvent:
+		if event.Page == nil {
+			return client
+		}
+		pageID, err := strconv.Atoi(event.Page.ID)
+		if err != nil {
+			return client
+		}
+		page, err := client.GetPageData(pageID)
+		if err != nil {
+			p.client.Log.Warn("Error getting the Confluence Cloud page", "PageID", event.Page.ID, "error", err.Error())
+			return client
+		}
+		// Prefer the version comment left by the editor over the page body.
+		event.Excerpt = strings.TrimSpace(page.Version.Message)
+		if event.Excerpt == "" || eventType == serializer.PageCreatedEvent {
+			event.Excerpt = strings.TrimSpace(page.Body.View.Value)
+		}
+
+	case serializer.CommentCreatedEvent, serializer.CommentUpdatedEvent:
+		if event.Comment == nil {
+			return client
+		}
+		comment, err := client.GetCommentData(event.Comment.ID)
+		if err != nil {
+			p.client.Log.Warn("Error getting the Confluence Cloud comment", "CommentID", event.Comment.ID, "error", err.Error())
+			return client
+		}
+		event.Excerpt = strings.TrimSpace(comment.Body.View.Value)
+	}
+
+	return client
+}
+
+// getCloudEventClient returns a client authenticated as the user who triggered the event, when they connected their
+// account. The content is only fetched with the permissions of that user, never with those of another connected user.
+func (p *Plugin) getCloudEventClient(instanceID, accountID string) (*confluenceCloudClient, error) {
+	if accountID == "" {
+		return nil, errors.Wrap(store.ErrNotFound, "the event was not triggered by a user")
+	}
+	mmUserID, err := store.GetMattermostUserIDFromConfluenceID(instanceID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	connection, err := store.LoadConnection(instanceID, *mmUserID)
+	if err != nil {
+		return nil, err
+	}
+	if !connection.IsCloud() {
+		return nil, errors.New("no Confluence Cloud connection to call the REST API with")
+	}
+
+	client, err := p.GetCloudClient(instanceID, connection)
+	if err != nil {
+		return nil, err
+	}
+	cloudClient, ok := client.(*confluenceCloudClient)
+	if !ok {
+		return nil, errors.New("invalid Confluence Cloud client type")
+	}
+	return cloudClient, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+func TestEnrichCloudEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ex/confluence/cloud-1" + PathCloudUserData:
+			_, _ = w.Write([]byte(`{"accountId":"5b10ac8d82e05b22cc7d4ef5","displayName":"Jane Doe"}`))
+		case "/ex/confluence/cloud-1" + PathCloudSpaceData + "/DEV":
+			_, _ = w.Write([]byte(`{"id":1,"key":"DEV","name":"Development"}`))
+		case "/ex/confluence/cloud-1" + PathCloudContentData + "42":
+			_, _ = w.Write([]byte(`{"id":"42","title":"Release notes","body":{"view":{"value":"<p>The release notes</p>"}},"version":{"number":3,"message":"Fixed the typos"}}`))
+		case "/ex/confluence/cloud-1" + PathCloudContentData + "43":
+			_, _ = w.Write([]byte(`{"id":"43","body":{"view":{"value":"<p>Looks good</p>"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	previousAPIURL := atlassianAPIURL
+	atlassianAPIURL = server.URL
+	defer func() { atlassianAPIURL = previousAPIURL }()
+
+	page := &serializer.Page{ID: "42", Title: "Release notes", SpaceKey: "DEV"}
+	for name, tc := range map[string]struct {
+		instanceID      string
+		event           *serializer.ConfluenceCloudEvent
+		eventType       string
+		expectedActor   string
+		expectedSpace   string
+		expectedExcerpt string
+	}{
+		"page updated": {
+			instanceID:      "https://one.atlassian.net",
+			event:           &serializer.ConfluenceCloudEvent{UserAccountID: "5b10ac8d82e05b22cc7d4ef5", Page: page},
+			eventType:       serializer.PageUpdatedEvent,
+			expectedActor:   "@jane",
+			expectedSpace:   "Development",
+			expectedExcerpt: "Fixed the typos",
+		},
+		"comment created": {
+			instanceID:      "https://two.atlassian.net",
+			event:           &serializer.ConfluenceCloudEvent{UserAccountID: "5b10ac8d82e05b22cc7d4ef5", Comment: &serializer.Comment{ID: "43", SpaceKey: "DEV", Parent: page}},
+			eventType:       serializer.CommentCreatedEvent,
+			expectedActor:   "@jane",
+			expectedSpace:   "Development",
+			expectedExcerpt: "Looks good",
+		},
+		"no connection to the site": {
+			instanceID: "https://unknown.atlassian.net",
+			event:      &serializer.ConfluenceCloudEvent{UserAccountID: "5b10ac8d82e05b22cc7d4ef5", Page: page},
+			eventType:  serializer.PageUpdatedEvent,
+		},
+		"triggered by a user who is not connected": {
+			instanceID: "https://one.atlassian.net",
+			event:      &serializer.ConfluenceCloudEvent{UserAccountID: "557058:other", Page: page},
+			eventType:  serializer.PageUpdatedEvent,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			siteURL := "https://mattermost.example.com"
+			mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			config.SetConfig(&config.Configuration{EncryptionKey: "0123456789abcdef0123456789abcdef"})
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			encoded, err := p.NewEncodedAuthToken(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)})
+			require.NoError(t, err)
+			data, _ := json.Marshal(&types.Connection{
+				ConfluenceUser:   types.ConfluenceUser{AccountID: "5b10ac8d82e05b22cc7d4ef5"},
+				OAuth2Token:      encoded,
+				MattermostUserID: "user-id",
+				CloudID:          "cloud-1",
+			})
+			for _, site := range []string{"https://one.atlassian.net", "https://two.atlassian.net"} {
+				mockAPI.On("KVGet", site+"_5b10ac8d82e05b22cc7d4ef5").Return([]byte(`"user-id"`), nil)
+				mockAPI.On("KVGet", site+"_user-id").Return(data, nil)
+				// The connection of the last user who connected must not be used for the other users.
+				mockAPI.On("KVGet", site+"_admin").Return(data, nil).Maybe()
+			}
+			mockAPI.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Username: "jane"}, nil)
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+			mockAPI.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+			p.enrichCloudEvent(tc.instanceID, tc.event, tc.eventType)
+
+			assert.Equal(t, tc.expectedActor, tc.event.Actor)
+			assert.Equal(t, tc.expectedSpace, tc.event.SpaceName)
+			assert.Equal(t, tc.expectedExcerpt, tc.event.Excerpt)
+		})
+	}
+}
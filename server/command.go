@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -33,24 +34,39 @@ const (
 	noChannelSubscription     = "No subscriptions found for this channel."
 	commonHelpText            = "###### Mattermost Confluence Plugin - Slash Command Help\n\n" +
 		"* `/confluence connect` - Connect your Mattermost user to Confluence.\n" +
+		"* `/confluence connect cloud` - Connect your Mattermost user to Confluence Cloud.\n" +
 		"* `/confluence disconnect` - Disconnect your Mattermost user from Confluence.\n" +
 		"* `/confluence subscribe` - Subscribe the current channel to notifications from Confluence.\n" +
 		"* `/confluence unsubscribe \"<name>\"` - Unsubscribe the current channel from notifications associated with the given subscription name.\n" +
 		"* `/confluence list` - List all subscriptions for the current channel.\n" +
-		"* `/confluence edit \"<name>\"` - Edit the subscription settings associated with the given subscription name.\n"
+		"* `/confluence edit \"<name>\"` - Edit the subscription settings associated with the given subscription name.\n" +
+		"* `/confluence export [--all] [--format json|yaml]` - Export the subscriptions of the current channel, or of all channels, as a file.\n" +
+		"* `/confluence import [--dry-run] [--current-channel]` - Import the subscriptions from the last file you uploaded in the current channel.\n"
 
 	sysAdminHelpText = "\n###### For System Administrators:\n" +
 		"Setup Instructions:\n" +
 		"* `/confluence install cloud` - Connect Mattermost to a Confluence Cloud instance.\n" +
-		"* `/confluence install server` - Connect Mattermost to a Confluence Server or Data Center instance.\n"
+		"* `/confluence install server` - Connect Mattermost to a Confluence Server or Data Center instance.\n" +
+		"* `/confluence list --all [--space <key>] [--page <id>] [--event <event>] [--url <url>]` - List the subscriptions of all channels.\n" +
+		"* `/confluence webhook-secret [revoke-previous]` - Show which webhooks still use the previous webhook secret, or stop accepting it.\n" +
+		"* `/confluence doctor` - Check the configuration, connections, webhook and subscriptions, and suggest fixes for what does not work.\n" +
+		"* `/confluence deliveries [instance URL]` - Show the recent webhook deliveries and what happened to them.\n" +
+		"* `/confluence deliveries replay <ID> [instance URL]` - Send a recorded webhook delivery through the notifications again. Except for Confluence Data Center 9, the replay only has the metadata of the event, not the content of the pages and comments.\n" +
+		"* `/confluence cache` - Show the hit rate of the caches of Confluence API lookups.\n" +
+		"* `/confluence users [--instance <URL>]` - List the users connected to Confluence.\n" +
+		"* `/confluence users disconnect @user` - Disconnect a user from Confluence and revoke their token.\n" +
+		"* `/confluence webhook [status|repair]` - Check the webhook sending Confluence Data Center events to Mattermost, or create and repair it.\n" +
+		"* `/confluence webhook-signing-secret [status|generate|clear] [instance URL]` - Manage the secret used to verify the signature of Confluence Data Center webhooks.\n" +
+		"* `/confluence rotate-key [status]` - Rotate the key used to encrypt the stored tokens, or show the re-encryption progress.\n" +
+		"* `/confluence audit [channel] [--since <period>]` - Browse the audit log of subscription and connection changes, e.g. `--since 7d`.\n"
 
 	invalidCommand              = "Invalid command."
 	installOnlySystemAdmin      = "`/confluence install` can only be run by a system administrator."
-	commandsOnlySystemAdmin     = "`/confluence` commands can only be run by a system administrator."
 	errorUserLacksChannelAccess = "Cannot perform operation: user does not have access to the channel."
 	disconnectedUser            = "User not connected. Please use `/confluence connect`."
 	errorExecutingCommand       = "Error executing the command, please retry."
 	oauth2ConnectPath           = "%s/oauth2/connect"
+	oauth2ConnectCloudPath      = "%s/oauth2/cloud/connect"
 
 	generalDeleteError = "error occurred while deleting subscription with name **%s**"
 )
@@ -69,18 +85,32 @@ To finish the configuration, add a new app in your Confluence Cloud instance fol
 9. Press **Install app** to complete the installation.
 
 Once these steps are completed, your Confluence Cloud instance is fully configured and ready to use. You can now create subscriptions to receive notifications in Mattermost.
+
+To let users connect their Confluence Cloud accounts with ` + "`/confluence connect cloud`" + `, create an OAuth 2.0 (3LO) app in the [Atlassian developer console](https://developer.atlassian.com/console/myapps/) with the Confluence API permissions, set its callback URL to %s, and fill in the **Confluence Cloud** settings of the plugin.
 `
 )
 
 var ConfluenceCommandHandler = Handler{
 	handlers: map[string]HandlerFunc{
-		"list":           listChannelSubscription,
-		"unsubscribe":    deleteSubscription,
-		"install/cloud":  showInstallCloudHelp,
-		"install/server": showInstallServerHelp,
-		"connect":        executeConnect,
-		"disconnect":     executeDisconnect,
-		"help":           confluenceHelpCommand,
+		"list":                   listSubscriptions,
+		"unsubscribe":            deleteSubscription,
+		"install/cloud":          showInstallCloudHelp,
+		"install/server":         showInstallServerHelp,
+		"connect":                executeConnect,
+		"connect/cloud":          executeConnectCloud,
+		"disconnect":             executeDisconnect,
+		"help":                   confluenceHelpCommand,
+		"audit":                  executeAudit,
+		"rotate-key":             executeRotateKey,
+		"webhook":                executeWebhook,
+		"doctor":                 executeDoctor,
+		"deliveries":             executeDeliveries,
+		"cache":                  executeCache,
+		"users":                  executeUsers,
+		"webhook-secret":         executeWebhookSecret,
+		"webhook-signing-secret": executeWebhookSigningSecret,
+		"export":                 executeExport,
+		"import":                 executeImport,
 	},
 	defaultHandler: executeConfluenceDefault,
 }
@@ -117,7 +147,10 @@ func getAutoCompleteData() *model.AutocompleteData {
 	install.AddStaticListArgument("", false, installItems)
 	confluence.AddCommand(install)
 
-	list := model.NewAutocompleteData("list", "", "List all subscriptions for the current channel")
+	list := model.NewAutocompleteData("list", "[--all]", "List all subscriptions for the current channel")
+	listAll := model.NewAutocompleteData("--all", "[--space <key>] [--page <id>] [--event <event>] [--url <url>]", "List the subscriptions of all channels")
+	listAll.RoleID = model.SystemAdminRoleId
+	list.AddCommand(listAll)
 	confluence.AddCommand(list)
 
 	edit := model.NewAutocompleteData("edit", "[name]", "Edit the subscription settings associated with the given subscription name")
@@ -135,11 +168,114 @@ func getAutoCompleteData() *model.AutocompleteData {
 	confluence.AddCommand(help)
 
 	connect := model.NewAutocompleteData("connect", "", "Connect your Mattermost account to your Confluence account")
+	connect.AddStaticListArgument("", false, []model.AutocompleteListItem{{
+		HelpText: "Connect your Mattermost account to your Confluence Cloud account",
+		Item:     "cloud",
+	}})
 	confluence.AddCommand(connect)
 
 	disconnect := model.NewAutocompleteData("disconnect", "", "Disconnect your Mattermost account from your Confluence account")
 	confluence.AddCommand(disconnect)
 
+	export := model.NewAutocompleteData("export", "[--all] [--format json|yaml]", "Export the subscriptions of the current channel, or of all channels, as a file")
+	export.AddStaticListArgument("", false, []model.AutocompleteListItem{{
+		HelpText: "Export the subscriptions of all channels",
+		Item:     "--all",
+	}, {
+		HelpText: "Export as JSON or YAML",
+		Item:     "--format",
+	}})
+	confluence.AddCommand(export)
+
+	importCommand := model.NewAutocompleteData("import", "[--dry-run] [--current-channel]", "Import the subscriptions from the last file you uploaded in the current channel")
+	importCommand.AddStaticListArgument("", false, []model.AutocompleteListItem{{
+		HelpText: "Report what would be imported without saving anything",
+		Item:     "--dry-run",
+	}, {
+		HelpText: "Import every subscription in the current channel",
+		Item:     "--current-channel",
+	}})
+	confluence.AddCommand(importCommand)
+
+	doctor := model.NewAutocompleteData("doctor", "", "Check every part of the integration and suggest fixes")
+	doctor.RoleID = model.SystemAdminRoleId
+	confluence.AddCommand(doctor)
+
+	deliveries := model.NewAutocompleteData("deliveries", "[replay <ID>] [instance URL]", "Show the recent webhook deliveries")
+	deliveries.RoleID = model.SystemAdminRoleId
+	deliveries.AddStaticListArgument("", false, []model.AutocompleteListItem{{
+		HelpText: "Send a recorded delivery through the notifications again, with the metadata of the event only",
+		Item:     "replay",
+		Hint:     "<ID>",
+	}})
+	confluence.AddCommand(deliveries)
+
+	cacheStats := model.NewAutocompleteData("cache", "", "Show the hit rate of the caches of Confluence API lookups")
+	cacheStats.RoleID = model.SystemAdminRoleId
+	confluence.AddCommand(cacheStats)
+
+	users := model.NewAutocompleteData("users", "[disconnect @user] [--instance <URL>]", "List the users connected to Confluence")
+	users.RoleID = model.SystemAdminRoleId
+	users.AddStaticListArgument("", false, []model.AutocompleteListItem{{
+		HelpText: "Disconnect a user from Confluence and revoke their token",
+		Item:     "disconnect",
+		Hint:     "@user",
+	}})
+	confluence.AddCommand(users)
+
+	webhook := model.NewAutocompleteData("webhook", "[status|repair]", "Check the webhook sending Confluence Data Center events to Mattermost")
+	webhook.RoleID = model.SystemAdminRoleId
+	webhook.AddStaticListArgument("", false, []model.AutocompleteListItem{
+		{
+			HelpText: "Check whether the webhook is registered",
+			Item:     "status",
+		},
+		{
+			HelpText: "Create the webhook, or repair it",
+			Item:     "repair",
+		},
+	})
+	confluence.AddCommand(webhook)
+
+	webhookSecret := model.NewAutocompleteData("webhook-secret", "[revoke-previous]", "Show which webhooks still use the previous webhook secret")
+	webhookSecret.RoleID = model.SystemAdminRoleId
+	webhookSecret.AddStaticListArgument("", false, []model.AutocompleteListItem{{
+		HelpText: "Stop accepting the previous webhook secret",
+		Item:     "revoke-previous",
+	}})
+	confluence.AddCommand(webhookSecret)
+
+	webhookSigningSecret := model.NewAutocompleteData("webhook-signing-secret", "[status|generate|clear] [instance URL]", "Manage the secret used to verify the signature of Confluence Data Center webhooks")
+	webhookSigningSecret.RoleID = model.SystemAdminRoleId
+	webhookSigningSecret.AddStaticListArgument("", false, []model.AutocompleteListItem{
+		{
+			HelpText: "Show whether webhook signatures are verified",
+			Item:     "status",
+		},
+		{
+			HelpText: "Generate a new signing secret",
+			Item:     "generate",
+		},
+		{
+			HelpText: "Stop verifying webhook signatures",
+			Item:     "clear",
+		},
+	})
+	confluence.AddCommand(webhookSigningSecret)
+
+	rotateKey := model.NewAutocompleteData("rotate-key", "[status]", "Rotate the key used to encrypt the stored tokens")
+	rotateKey.RoleID = model.SystemAdminRoleId
+	rotateKey.AddStaticListArgument("", false, []model.AutocompleteListItem{{
+		HelpText: "Show the progress of the token re-encryption",
+		Item:     "status",
+	}})
+	confluence.AddCommand(rotateKey)
+
+	audit := model.NewAutocompleteData("audit", "[channel] [--since <period>]", "Browse the audit log of subscription and connection changes")
+	audit.RoleID = model.SystemAdminRoleId
+	audit.AddNamedTextArgument("since", "Period to show, e.g. 7d, 12h or 2024-01-31", "[period]", "", false)
+	confluence.AddCommand(audit)
+
 	return confluence
 }
 
@@ -185,6 +321,9 @@ func executeConnect(p *Plugin, context *model.CommandArgs, _ ...string) *model.C
 
 	pluginConfig := config.GetConfig()
 	if pluginConfig.ConfluenceURL == "" || !pluginConfig.IsOAuthConfigured() {
+		if pluginConfig.IsCloudOAuthConfigured() {
+			return executeConnectCloud(p, context)
+		}
 		if isAdmin {
 			return p.responsef(context, "OAuth config not set for Confluence plugin. Please run `/confluence install server`")
 		}
@@ -203,6 +342,23 @@ func executeConnect(p *Plugin, context *model.CommandArgs, _ ...string) *model.C
 	return p.responsef(context, "[Click here to link your Confluence account](%s)", link)
 }
 
+func executeConnectCloud(p *Plugin, context *model.CommandArgs, _ ...string) *model.CommandResponse {
+	if !config.GetConfig().IsCloudOAuthConfigured() {
+		if util.IsSystemAdmin(context.UserId) {
+			return p.responsef(context, "OAuth config not set for Confluence Cloud. Please set the Confluence Cloud URL and the OAuth client of your Atlassian app in the plugin settings")
+		}
+		return p.responsef(context, "OAuth config not set for Confluence Cloud. Please ask the admin to setup OAuth for the plugin")
+	}
+
+	if user, err := store.LoadUser(context.UserId); err == nil && user.InstanceURL != "" {
+		return p.responsef(context,
+			"Mattermost account is already linked to a Confluence account. Please use `/confluence disconnect` to disconnect")
+	}
+
+	link := fmt.Sprintf(oauth2ConnectCloudPath, util.GetPluginURL())
+	return p.responsef(context, "[Click here to link your Confluence Cloud account](%s)", link)
+}
+
 func executeDisconnect(p *Plugin, commArgs *model.CommandArgs, _ ...string) *model.CommandResponse {
 	user, err := store.LoadUser(commArgs.UserId)
 	if err != nil {
@@ -220,7 +376,11 @@ func executeDisconnect(p *Plugin, commArgs *model.CommandArgs, _ ...string) *mod
 		p.client.Log.Error("Error disconnecting the user", "UserID", commArgs.UserId, "error", err.Error())
 		return p.responsef(commArgs, "Failed to complete the **disconnection** request. Error: %v", err)
 	}
-	return p.responsef(commArgs, "You have successfully disconnected your Confluence account (**%s**).", disconnected.DisplayName)
+	if disconnected.RevokeErr != nil {
+		return p.responsef(commArgs, "You have disconnected your Confluence account (**%s**), but its access could not be revoked on Confluence. "+
+			"You can revoke it from the authorized applications of your Confluence profile. Error: %v", disconnected.Connection.DisplayName, disconnected.RevokeErr)
+	}
+	return p.responsef(commArgs, "You have successfully disconnected your Confluence account (**%s**).", disconnected.Connection.DisplayName)
 }
 
 func showInstallCloudHelp(_ *Plugin, context *model.CommandArgs, _ ...string) *model.CommandResponse {
@@ -230,7 +390,7 @@ func showInstallCloudHelp(_ *Plugin, context *model.CommandArgs, _ ...string) *m
 	}
 
 	cloudURL := util.GetPluginURL() + util.GetAtlassianConnectURLPath()
-	postCommandResponse(context, fmt.Sprintf(installCloudHelp, cloudURL))
+	postCommandResponse(context, fmt.Sprintf(installCloudHelp, cloudURL, util.GetPluginURL()+routeUserCompleteCloud))
 	return &model.CommandResponse{}
 }
 
@@ -257,8 +417,8 @@ func deleteSubscription(p *Plugin, context *model.CommandArgs, args ...string) *
 	userID := context.UserId
 	channelID := context.ChannelId
 
-	if !util.IsSystemAdmin(userID) {
-		postCommandResponse(context, commandsOnlySystemAdmin)
+	if !p.canManageSubscriptions(userID, channelID) {
+		postCommandResponse(context, commandsNoSubscriptionPermission)
 		return &model.CommandResponse{}
 	}
 
@@ -293,16 +453,30 @@ func deleteSubscription(p *Plugin, context *model.CommandArgs, args ...string) *
 	}
 
 	alias := strings.Join(args, " ")
+	oldSubscription, _, _ := service.GetChannelSubscription(channelID, alias)
 	if err := service.DeleteSubscription(channelID, alias); err != nil {
 		p.client.Log.Error("Error deleting the subscription", "subscription alias", alias, "error", err.Error())
 		postCommandResponse(context, fmt.Sprintf(generalDeleteError, alias))
 		return &model.CommandResponse{}
 	}
 
+	if oldSubscription != nil {
+		service.RecordAudit(service.NewSubscriptionAuditEntry(service.AuditActionSubscriptionDeleted, userID, channelID, oldSubscription, nil))
+	}
+
 	postCommandResponse(context, fmt.Sprintf(subscriptionDeleteSuccess, alias))
 	return &model.CommandResponse{}
 }
 
+// listSubscriptions lists the subscriptions of all channels when `--all` is given, in any position, and the
+// subscriptions of the current channel otherwise.
+func listSubscriptions(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if slices.Contains(args, "--all") {
+		return listAllSubscriptions(p, context, args...)
+	}
+	return listChannelSubscription(p, context, args...)
+}
+
 func listChannelSubscription(p *Plugin, context *model.CommandArgs, _ ...string) *model.CommandResponse {
 	pluginConfig := config.GetConfig()
 	if pluginConfig.ServerVersionGreaterthan9 {
@@ -322,8 +496,8 @@ func listChannelSubscription(p *Plugin, context *model.CommandArgs, _ ...string)
 			postCommandResponse(context, disconnectedUser)
 			return &model.CommandResponse{}
 		}
-	} else if !util.IsSystemAdmin(context.UserId) {
-		postCommandResponse(context, commandsOnlySystemAdmin)
+	} else if !p.canManageSubscriptions(context.UserId, context.ChannelId) {
+		postCommandResponse(context, commandsNoSubscriptionPermission)
 		return &model.CommandResponse{}
 	}
 
@@ -343,10 +517,10 @@ func listChannelSubscription(p *Plugin, context *model.CommandArgs, _ ...string)
 	return &model.CommandResponse{}
 }
 
-func confluenceHelpCommand(_ *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+func confluenceHelpCommand(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
 	pluginConfig := config.GetConfig()
-	if !pluginConfig.ServerVersionGreaterthan9 && !util.IsSystemAdmin(context.UserId) {
-		postCommandResponse(context, commandsOnlySystemAdmin)
+	if !pluginConfig.ServerVersionGreaterthan9 && !p.canManageSubscriptions(context.UserId, context.ChannelId) {
+		postCommandResponse(context, commandsNoSubscriptionPermission)
 		return &model.CommandResponse{}
 	}
 
@@ -44,3 +44,23 @@ func TestHandler_Handle_Default(t *testing.T) {
 
 	mockAPI.AssertExpectations(t)
 }
+
+func TestHandler_Handle_ListAll(t *testing.T) {
+	for name, args := range map[string][]string{
+		"--all first":  {"list", "--all", "--space", "KEY"},
+		"--all last":   {"list", "--space", "KEY", "--all"},
+		"--all middle": {"list", "--space", "KEY", "--all", "--event", "page_created"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := setupMockAPI()
+			var message string
+			mockAPI.On("SendEphemeralPost", "U1", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+				message = args.Get(1).(*model.Post).Message
+			}).Return(&model.Post{})
+
+			ConfluenceCommandHandler.Handle(&Plugin{}, &model.CommandArgs{UserId: "U1", ChannelId: "C1"}, args...)
+
+			assert.Equal(t, listAllOnlySystemAdmin, message)
+		})
+	}
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"encoding/json"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/pkg/errors"
@@ -11,6 +13,12 @@ import (
 
 const (
 	HeaderMattermostUserID = "Mattermost-User-Id"
+
+	// Roles allowed to manage subscriptions, from most to least restrictive
+	SubscriptionRoleSystemAdmin  = "system_admin"
+	SubscriptionRoleTeamAdmin    = "team_admin"
+	SubscriptionRoleChannelAdmin = "channel_admin"
+	SubscriptionRoleUsers        = "users"
 )
 
 var (
@@ -21,24 +29,46 @@ var (
 
 type Configuration struct {
 	Secret                      string `json:"secret"`
-	EncryptionKey               string `json:"encryptionkey"` // The encryption key used to encrypt tokens
-	AdminAPIToken               string `json:"adminapitoken"` // API token from Confluence Data Center
+	EncryptionKey               string `json:"encryptionkey"`          // The encryption key used to encrypt tokens
+	PreviousEncryptionKeys      string `json:"previousencryptionkeys"` // Comma-separated keys the stored tokens may still be encrypted with
+	AdminAPIToken               string `json:"adminapitoken"`          // API token from Confluence Data Center
 	ConfluenceOAuthClientID     string `json:"confluenceoauthclientid"`
 	ConfluenceOAuthClientSecret string `json:"confluenceoauthclientsecret"`
 	ConfluenceURL               string `json:"confluenceurl"`
 	ServerVersionGreaterthan9   bool   `json:"serverversiongreaterthan9"`
+	ServerVersion               string `json:"serverversion"` // Version of the Confluence Data Center instance, detected automatically
+
+	RolesAllowedToManageSubscriptions string `json:"rolesallowedtomanagesubscriptions"` // The minimum role required to create or edit subscriptions
+	TeamsAllowedToManageSubscriptions string `json:"teamsallowedtomanagesubscriptions"` // Comma-separated team names, empty allows every team
+
+	WebhookSecretGracePeriodHours int `json:"webhooksecretgraceperiodhours"` // How long the previous webhook secret is still accepted after it is regenerated
+
+	MatchUsersByEmail bool `json:"matchusersbyemail"` // Mention the Mattermost user with the same email as the Confluence user who triggered an event
+
+	ConfluenceCloudURL               string `json:"confluencecloudurl"` // The Confluence Cloud site users connect their accounts to
+	ConfluenceCloudOAuthClientID     string `json:"confluencecloudoauthclientid"`
+	ConfluenceCloudOAuthClientSecret string `json:"confluencecloudoauthclientsecret"`
 }
 
 func GetConfig() *Configuration {
 	return config.Load().(*Configuration)
 }
 
+// GetConfigIfSet returns the configuration, or nil when it has not been set yet.
+func GetConfigIfSet() *Configuration {
+	c, _ := config.Load().(*Configuration)
+	return c
+}
+
 func SetConfig(c *Configuration) {
 	config.Store(c)
 }
 
 func (c *Configuration) ProcessConfiguration() error {
 	c.Secret = strings.TrimSpace(c.Secret)
+	c.ConfluenceCloudURL = strings.TrimRight(strings.TrimSpace(c.ConfluenceCloudURL), "/")
+	c.ConfluenceCloudOAuthClientID = strings.TrimSpace(c.ConfluenceCloudOAuthClientID)
+	c.ConfluenceCloudOAuthClientSecret = strings.TrimSpace(c.ConfluenceCloudOAuthClientSecret)
 
 	return nil
 }
@@ -71,6 +101,11 @@ func (c *Configuration) IsOAuthConfigured() bool {
 	return (c.ConfluenceOAuthClientID != "" && c.ConfluenceOAuthClientSecret != "")
 }
 
+// IsCloudOAuthConfigured returns whether users can connect their Confluence Cloud accounts.
+func (c *Configuration) IsCloudOAuthConfigured() bool {
+	return c.ConfluenceCloudURL != "" && c.ConfluenceCloudOAuthClientID != "" && c.ConfluenceCloudOAuthClientSecret != ""
+}
+
 func (c *Configuration) ToMap() (map[string]interface{}, error) {
 	var out map[string]interface{}
 	data, err := json.Marshal(c)
@@ -85,6 +120,42 @@ func (c *Configuration) ToMap() (map[string]interface{}, error) {
 	return out, nil
 }
 
+// GetTeamsAllowedToManageSubscriptions returns the team names from TeamsAllowedToManageSubscriptions.
+func (c *Configuration) GetTeamsAllowedToManageSubscriptions() []string {
+	var teams []string
+	for _, team := range strings.Split(c.TeamsAllowedToManageSubscriptions, ",") {
+		if team = strings.TrimSpace(team); team != "" {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
+
+// GetPreviousEncryptionKeys returns the keys from PreviousEncryptionKeys.
+func (c *Configuration) GetPreviousEncryptionKeys() []string {
+	var keys []string
+	for _, key := range strings.Split(c.PreviousEncryptionKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// AddPreviousEncryptionKey adds the key to PreviousEncryptionKeys, unless it is already there.
+func (c *Configuration) AddPreviousEncryptionKey(key string) {
+	keys := c.GetPreviousEncryptionKeys()
+	if key == "" || slices.Contains(keys, key) {
+		return
+	}
+	c.PreviousEncryptionKeys = strings.Join(append(keys, key), ",")
+}
+
+// GetWebhookSecretGracePeriod returns how long the previous webhook secret is still accepted after it is regenerated.
+func (c *Configuration) GetWebhookSecretGracePeriod() time.Duration {
+	return time.Duration(c.WebhookSecretGracePeriodHours) * time.Hour
+}
+
 func (c *Configuration) GetConfluenceBaseURL() string {
 	return c.ConfluenceURL
 }
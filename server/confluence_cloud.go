@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"io"
 	"net/http"
+	"net/url"
 
 	"github.com/gorilla/mux"
 
-	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
 	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
 	"github.com/mattermost/mattermost-plugin-confluence/server/service"
 )
@@ -20,22 +23,54 @@ var confluenceCloudWebhook = &Endpoint{
 func handleConfluenceCloudWebhook(w http.ResponseWriter, r *http.Request, p *Plugin) {
 	p.client.Log.Info("Received Confluence cloud event.")
 
-	if status, err := verifyHTTPSecret(config.GetConfig().Secret, r.FormValue("secret")); err != nil {
+	if status, err := p.verifyWebhookSecret(r); err != nil {
 		p.client.Log.Error("Error verifying the secret for the Confluence cloud webhook", "error", err.Error())
 		http.Error(w, "Failed to verify the secret for the Confluence cloud webhook", status)
 		return
 	}
+	p.recordWebhookDelivery()
 
-	params := mux.Vars(r)
-	event, err := serializer.ConfluenceCloudEventFromJSON(r.Body)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		p.client.Log.Error("Error reading body of the Confluence cloud webhook", "error", err.Error())
+		http.Error(w, "Failed to read body for the Confluence cloud webhook", http.StatusBadRequest)
+		return
+	}
+
+	eventType := mux.Vars(r)["event"]
+	event, err := serializer.ConfluenceCloudEventFromJSON(bytes.NewReader(body))
 	if err != nil {
 		p.client.Log.Error("Error occurred while unmarshalling Confluence cloud webhook payload", "error", err)
+		metrics.DeliveryFailures.WithLabelValues(metrics.StagePayload).Inc()
 		http.Error(w, "Failed to process Confluence cloud webhook data", http.StatusInternalServerError)
 		return
 	}
 
-	go service.SendConfluenceNotifications(event, params["event"])
+	metrics.WebhooksReceived.WithLabelValues(service.DeliverySourceCloud, eventType).Inc()
+	instanceID := cloudInstanceID(event)
+	delivery := service.NewWebhookDelivery(service.DeliverySourceCloud, eventType, event.ContentID(), body)
+	if p.isDuplicateWebhook(event.DedupeKey(eventType)) {
+		delivery.Outcome = service.DeliveryOutcomeDuplicate
+		service.RecordWebhookDelivery(instanceID, delivery)
+		w.Header().Set("Content-Type", "application/json")
+		ReturnStatusOK(w)
+		return
+	}
+
+	go func() {
+		delivery.SetResult(p.sendCloudEventNotifications(instanceID, event, eventType), nil)
+		service.RecordWebhookDelivery(instanceID, delivery)
+	}()
 
 	w.Header().Set("Content-Type", "application/json")
 	ReturnStatusOK(w)
 }
+
+// cloudInstanceID returns the URL of the Confluence Cloud site that sent the event.
+func cloudInstanceID(event *serializer.ConfluenceCloudEvent) string {
+	u, err := url.Parse(event.GetURL())
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -12,6 +14,8 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/httpclient"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
 	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
 	"github.com/mattermost/mattermost-plugin-confluence/server/service"
 	"github.com/mattermost/mattermost-plugin-confluence/server/store"
@@ -28,22 +32,25 @@ var confluenceServerWebhook = &Endpoint{
 func handleConfluenceServerWebhook(w http.ResponseWriter, r *http.Request, p *Plugin) {
 	p.client.Log.Info("Received Confluence server event.")
 
-	if status, err := verifyHTTPSecret(config.GetConfig().Secret, r.FormValue("secret")); err != nil {
+	pluginConfig := config.GetConfig()
+
+	// The raw body is needed to verify its signature.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		p.client.Log.Error("Error reading body of the Confluence server webhook", "error", err.Error())
+		http.Error(w, "Failed to read body for the Confluence server webhook", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := p.verifyServerWebhookRequest(r, body, pluginConfig.GetConfluenceBaseURL()); err != nil {
 		p.client.Log.Error("Error verifying secret for the Confluence server webhook", "error", err.Error())
 		http.Error(w, "Failed to verify secret for the Confluence server webhook", status)
 		return
 	}
+	p.recordWebhookDelivery()
 
-	pluginConfig := config.GetConfig()
-
+	instanceID := pluginConfig.GetConfluenceBaseURL()
 	if pluginConfig.ServerVersionGreaterthan9 {
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			p.client.Log.Error("Error reading body of the Confluence server webhook", "error", err.Error())
-			http.Error(w, "Failed to read body for the Confluence server webhook", http.StatusBadRequest)
-			return
-		}
-
 		if respondToTestConnection(body) {
 			w.Header().Set("Content-Type", "application/json")
 			ReturnStatusOK(w)
@@ -54,116 +61,179 @@ func handleConfluenceServerWebhook(w http.ResponseWriter, r *http.Request, p *Pl
 		err = json.Unmarshal(body, &event)
 		if err != nil {
 			p.client.Log.Error("Error occurred while unmarshaling Confluence server webhook payload", "Error", err.Error())
+			metrics.DeliveryFailures.WithLabelValues(metrics.StagePayload).Inc()
+			recordFailedDelivery(instanceID, service.DeliverySourceServerWebhook, body, err)
 			http.Error(w, "Failed to unmarshal Confluence server webhook payload", http.StatusInternalServerError)
 			return
 		}
 
-		pluginConfig := config.GetConfig()
-		instanceID := pluginConfig.ConfluenceURL
+		metrics.WebhooksReceived.WithLabelValues(service.DeliverySourceServerWebhook, event.Event).Inc()
+		delivery := service.NewWebhookDelivery(service.DeliverySourceServerWebhook, event.Event, event.ContentID(), body)
+		if invalidateLookupCaches(instanceID, event) {
+			delivery.SetResult(service.DeliveryResult{}, nil)
+			service.RecordWebhookDelivery(instanceID, delivery)
+			w.Header().Set("Content-Type", "application/json")
+			ReturnStatusOK(w)
+			return
+		}
 
-		notification := p.getNotification()
+		dedupeKey := event.DedupeKey()
+		if p.isDuplicateWebhook(dedupeKey) {
+			delivery.Outcome = service.DeliveryOutcomeDuplicate
+			service.RecordWebhookDelivery(instanceID, delivery)
+			w.Header().Set("Content-Type", "application/json")
+			ReturnStatusOK(w)
+			return
+		}
 
-		client, _, err := p.GetClientFromUserKey(instanceID, event.UserKey)
-		// If there is an error while retrieving the client from the event user key, it could be due to one of the following reasons:
-		// - An expected error occurred.
-		// - The user who triggered the event in Confluence is not connected to Mattermost.
-		// If the Admin API token is available, we will attempt to fetch additional data using it to send a detailed notification.
-		// Otherwise, a generic notification will be sent.
+		result, err := p.sendServerWebhookNotification(event, pluginConfig)
+		delivery.SetResult(result, err)
+		service.RecordWebhookDelivery(instanceID, delivery)
 		if err != nil {
-			if pluginConfig.AdminAPIToken != "" {
-				p.client.Log.Info("Error getting client for the user who triggered webhook event. Sending notification using admin API token")
-				if strings.Contains(event.Event, Space) {
-					var spaceKey string
-					spaceKey, err = p.GetSpaceKeyFromSpaceIDWithAPIToken(event.Space.ID, pluginConfig)
-					if err != nil {
-						p.client.Log.Error("Error getting space key using space ID with API token", "error", err)
-						http.Error(w, "Failed to send Confluence notification using API Token", http.StatusInternalServerError)
-						return
-					}
-					event.Space.SpaceKey = spaceKey
-				}
-
-				var eventData *ConfluenceServerEvent
-				eventData, err = p.GetEventDataWithAPIToken(event, pluginConfig)
-				if err != nil {
-					p.client.Log.Error("Error getting event data with API token", "error", err)
-					http.Error(w, "Failed to send Confluence notification using API Token", http.StatusInternalServerError)
-					return
-				}
-
-				eventTriggerer, cErr := p.GetUserFromUserKeyWithAPIToken(event.UserKey, pluginConfig)
-				if cErr != nil {
-					p.client.Log.Error("Error getting details of the event triggerer user using API token", "error", cErr.Error())
-					http.Error(w, "Failed to get details of the event triggerer user using API token", http.StatusInternalServerError)
-					return
-				}
-
-				eventData.BaseURL = pluginConfig.ConfluenceURL
-				notification.SendConfluenceNotifications(eventData, event.Event, p.BotUserID, eventTriggerer.DisplayName)
-			} else {
-				p.client.Log.Info("Error getting client for the user who triggered webhook event. Sending generic notification")
-				notification.SendGenericWHNotification(event, p.BotUserID, pluginConfig.ConfluenceURL)
-			}
+			metrics.DeliveryFailures.WithLabelValues(metrics.StageEventData).Inc()
+			// Confluence retries the deliveries that failed, they must not be ignored as duplicates.
+			p.forgetWebhookDelivery(dedupeKey)
+			http.Error(w, "Failed to send notification for Confluence server webhook", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		event, err := serializer.ConfluenceServerEventFromJSON(bytes.NewReader(body))
+		if err != nil {
+			p.client.Log.Error("Error occurred while unmarshalling Confluence server webhook payload", "error", err)
+			metrics.DeliveryFailures.WithLabelValues(metrics.StagePayload).Inc()
+			recordFailedDelivery(instanceID, service.DeliverySourceServer, body, err)
+			http.Error(w, "Failed to unmarshal Confluence server webhook payload", http.StatusInternalServerError)
+			return
+		}
 
+		metrics.WebhooksReceived.WithLabelValues(service.DeliverySourceServer, event.Event).Inc()
+		delivery := service.NewWebhookDelivery(service.DeliverySourceServer, event.Event, event.ContentID(), body)
+		if p.isDuplicateWebhook(event.DedupeKey()) {
+			delivery.Outcome = service.DeliveryOutcomeDuplicate
+			service.RecordWebhookDelivery(instanceID, delivery)
 			w.Header().Set("Content-Type", "application/json")
 			ReturnStatusOK(w)
 			return
 		}
 
-		var spaceKey string
-		if strings.Contains(event.Event, Space) {
-			spaceKey, err = client.(*confluenceServerClient).GetSpaceKeyFromSpaceID(event.Space.ID)
+		go func() {
+			delivery.SetResult(p.sendServerEventNotifications(instanceID, event), nil)
+			service.RecordWebhookDelivery(instanceID, delivery)
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	ReturnStatusOK(w)
+}
+
+// sendServerWebhookNotification fetches the content of a Confluence Data Center 9 event, which the webhook payload
+// does not include, and sends the notification. An error is returned when the content cannot be fetched.
+func (p *Plugin) sendServerWebhookNotification(event *serializer.ConfluenceServerWebhookPayload, pluginConfig *config.Configuration) (service.DeliveryResult, error) {
+	instanceID := pluginConfig.ConfluenceURL
+
+	client, _, err := p.GetClientFromUserKey(instanceID, event.UserKey)
+	// If there is an error while retrieving the client from the event user key, it could be due to one of the following reasons:
+	// - An expected error occurred.
+	// - The user who triggered the event in Confluence is not connected to Mattermost.
+	// If the Admin API token is available, we will attempt to fetch additional data using it to send a detailed notification.
+	// Otherwise, a generic notification will be sent.
+	if err != nil {
+		if pluginConfig.AdminAPIToken == "" {
+			p.client.Log.Info("Error getting client for the user who triggered webhook event. Sending generic notification")
+			return p.sendEventNotifications(event.Normalize(pluginConfig.ConfluenceURL), nil), nil
+		}
+
+		p.client.Log.Info("Error getting client for the user who triggered webhook event. Sending notification using admin API token")
+		if strings.Contains(event.Event, Space) && event.Space.SpaceKey == "" {
+			var spaceKey string
+			spaceKey, err = p.GetSpaceKeyFromSpaceIDWithAPIToken(event.Space.ID, pluginConfig)
+			if err != nil && p.skipRemovedSpaceWithoutKey(event, err) {
+				return service.DeliveryResult{}, nil
+			}
 			if err != nil {
-				p.client.Log.Error("Failed to get Space Key from the Space ID", "Space ID", event.Space.ID, "error", err.Error())
-				http.Error(w, "Failed to send notification for Confluence server webhook", http.StatusInternalServerError)
-				return
+				p.client.Log.Error("Error getting space key using space ID with API token", "error", err)
+				return service.DeliveryResult{}, errors.Wrap(err, "failed to get the space key using the API token")
 			}
 			event.Space.SpaceKey = spaceKey
 		}
 
-		eventData, err := p.GetEventData(event, client)
+		var eventData *ConfluenceServerEvent
+		eventData, err = p.GetEventDataWithAPIToken(event, pluginConfig)
 		if err != nil {
-			p.client.Log.Error("Error getting event data for the Confluence server webhook", "error", err.Error())
-			http.Error(w, "Failed to send notification for Confluence server webhook", http.StatusInternalServerError)
-			return
+			p.client.Log.Error("Error getting event data with API token", "error", err)
+			return service.DeliveryResult{}, errors.Wrap(err, "failed to get the event data using the API token")
+		}
+
+		eventTriggerer, cErr := p.GetUserFromUserKeyWithAPIToken(event.UserKey, pluginConfig)
+		if cErr != nil {
+			p.client.Log.Error("Error getting details of the event triggerer user using API token", "error", cErr.Error())
+			return service.DeliveryResult{}, errors.Wrap(cErr, "failed to get details of the event triggerer user using the API token")
 		}
 
 		eventData.BaseURL = pluginConfig.ConfluenceURL
+		eventData.Actor = p.getMattermostMention(instanceID, event.UserKey, eventTriggerer.Email)
+		return p.sendEventNotifications(eventData.Normalize(event.Event, eventData.actorName(eventTriggerer)), p.getAPITokenRestrictionReader(pluginConfig)), nil
+	}
 
-		// Prefer Admin API Token if available since regular user tokens lack this permission.
-		var eventTriggerer *ConfluenceUser
-		var cErr error
-		if pluginConfig.AdminAPIToken != "" {
-			eventTriggerer, cErr = p.GetUserFromUserKeyWithAPIToken(event.UserKey, pluginConfig)
-			if cErr != nil {
-				p.client.Log.Error("Error getting details of the event triggerer user using API token", "error", cErr.Error())
-				http.Error(w, "Failed to get details of the event triggerer user", http.StatusInternalServerError)
-				return
-			}
-		} else {
-			// Fallback to user's OAuth token if Admin API Token is not configured
-			eventTriggerer, cErr = client.(*confluenceServerClient).GetUserFromUserKey(event.UserKey)
-			if cErr != nil {
-				p.client.Log.Error("Error getting details of the event triggerer user", "error", cErr.Error())
-				http.Error(w, "Failed to get details of the event triggerer user", http.StatusInternalServerError)
-				return
-			}
+	if strings.Contains(event.Event, Space) && event.Space.SpaceKey == "" {
+		var spaceKey string
+		spaceKey, err = client.(*confluenceServerClient).GetSpaceKeyFromSpaceID(event.Space.ID)
+		if err != nil && p.skipRemovedSpaceWithoutKey(event, err) {
+			return service.DeliveryResult{}, nil
+		}
+		if err != nil {
+			p.client.Log.Error("Failed to get Space Key from the Space ID", "Space ID", event.Space.ID, "error", err.Error())
+			return service.DeliveryResult{}, errors.Wrap(err, "failed to get the space key")
 		}
+		event.Space.SpaceKey = spaceKey
+	}
+
+	eventData, err := p.GetEventData(event, client)
+	if err != nil {
+		p.client.Log.Error("Error getting event data for the Confluence server webhook", "error", err.Error())
+		return service.DeliveryResult{}, errors.Wrap(err, "failed to get the event data")
+	}
+
+	eventData.BaseURL = pluginConfig.ConfluenceURL
 
-		notification.SendConfluenceNotifications(eventData, event.Event, p.BotUserID, eventTriggerer.DisplayName)
+	// Prefer Admin API Token if available since regular user tokens lack this permission.
+	var eventTriggerer *ConfluenceUser
+	var cErr error
+	if pluginConfig.AdminAPIToken != "" {
+		eventTriggerer, cErr = p.GetUserFromUserKeyWithAPIToken(event.UserKey, pluginConfig)
+		if cErr != nil {
+			p.client.Log.Error("Error getting details of the event triggerer user using API token", "error", cErr.Error())
+			return service.DeliveryResult{}, errors.Wrap(cErr, "failed to get details of the event triggerer user using the API token")
+		}
 	} else {
-		event, err := serializer.ConfluenceServerEventFromJSON(r.Body)
-		if err != nil {
-			p.client.Log.Error("Error occurred while unmarshalling Confluence server webhook payload", "error", err)
-			http.Error(w, "Failed to unmarshal Confluence server webhook payload", http.StatusInternalServerError)
-			return
+		// Fallback to user's OAuth token if Admin API Token is not configured
+		eventTriggerer, cErr = client.(*confluenceServerClient).GetUserFromUserKey(event.UserKey)
+		if cErr != nil {
+			p.client.Log.Error("Error getting details of the event triggerer user", "error", cErr.Error())
+			return service.DeliveryResult{}, errors.Wrap(cErr, "failed to get details of the event triggerer user")
 		}
+	}
 
-		go service.SendConfluenceNotifications(event, event.Event)
+	// The email address is only trusted when it was read with the admin API token.
+	email := ""
+	if pluginConfig.AdminAPIToken != "" {
+		email = eventTriggerer.Email
 	}
+	eventData.Actor = p.getMattermostMention(instanceID, event.UserKey, email)
 
-	w.Header().Set("Content-Type", "application/json")
-	ReturnStatusOK(w)
+	return p.sendEventNotifications(eventData.Normalize(event.Event, eventData.actorName(eventTriggerer)), client), nil
+}
+
+// skipRemovedSpaceWithoutKey returns whether the event is the removal of a space whose key could not be found. A
+// removed space cannot be fetched anymore, so its key is only known when it was cached. Without the key no
+// subscription matches the event, and failing the delivery would only make Confluence retry it over and over.
+func (p *Plugin) skipRemovedSpaceWithoutKey(event *serializer.ConfluenceServerWebhookPayload, err error) bool {
+	if event.Event != serializer.SpaceRemovedEvent {
+		return false
+	}
+
+	p.client.Log.Info("Skipping the removal of a space whose key is unknown", "Space ID", event.Space.ID, "error", err.Error())
+	return true
 }
 
 func (p *Plugin) GetEventData(webhookPayload *serializer.ConfluenceServerWebhookPayload, client Client) (*ConfluenceServerEvent, error) {
@@ -199,22 +269,56 @@ func (p *Plugin) GetClientFromUserKey(instanceID, eventUserKey string) (Client,
 }
 
 func (p *Plugin) GetUserFromUserKeyWithAPIToken(eventUserKey string, pluginConfig *config.Configuration) (*ConfluenceUser, error) {
-	var user ConfluenceUser
+	return cachedUser(pluginConfig.ConfluenceURL, eventUserKey, func() (*ConfluenceUser, error) {
+		var user ConfluenceUser
 
-	path := fmt.Sprintf("%s%s?key=%s", pluginConfig.ConfluenceURL, PathUserData, eventUserKey)
+		path := fmt.Sprintf("%s%s?key=%s", pluginConfig.ConfluenceURL, PathUserData, eventUserKey)
 
-	body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
-	if err != nil || statusCode != http.StatusOK {
-		return nil, fmt.Errorf("error fetching user data with API token: %w", err)
-	}
+		body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching user data with API token: %w", err)
+		}
+		if statusCode != http.StatusOK {
+			return nil, errors.Errorf("unexpected status %d", statusCode)
+		}
 
-	if err := json.Unmarshal(body, &user); err != nil {
-		return nil, fmt.Errorf("error unmarshaling user data with API token: %w", err)
-	}
+		if err := json.Unmarshal(body, &user); err != nil {
+			return nil, fmt.Errorf("error unmarshaling user data with API token: %w", err)
+		}
+
+		return &user, nil
+	})
+}
+
+func (p *Plugin) GetUserFromUsernameWithAPIToken(username string, pluginConfig *config.Configuration) (*ConfluenceUser, error) {
+	return cachedUser(pluginConfig.ConfluenceURL, "username/"+username, func() (*ConfluenceUser, error) {
+		var user ConfluenceUser
+
+		path := fmt.Sprintf("%s%s?username=%s", pluginConfig.ConfluenceURL, PathUserData, url.QueryEscape(username))
 
-	return &user, nil
+		body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching user data with API token: %w", err)
+		}
+		if statusCode != http.StatusOK {
+			return nil, errors.Errorf("unexpected status %d", statusCode)
+		}
+
+		if err := json.Unmarshal(body, &user); err != nil {
+			return nil, fmt.Errorf("error unmarshaling user data with API token: %w", err)
+		}
+
+		return &user, nil
+	})
 }
+
 func (p *Plugin) GetSpaceKeyFromSpaceIDWithAPIToken(spaceID int64, pluginConfig *config.Configuration) (string, error) {
+	return spaceKeyCache.GetOrLoad(lookupCacheKey(pluginConfig.ConfluenceURL, strconv.FormatInt(spaceID, 10)), func() (string, error) {
+		return p.getSpaceKeyFromSpaceIDWithAPIToken(spaceID, pluginConfig)
+	})
+}
+
+func (p *Plugin) getSpaceKeyFromSpaceIDWithAPIToken(spaceID int64, pluginConfig *config.Configuration) (string, error) {
 	start := 0
 
 	for {
@@ -223,9 +327,12 @@ func (p *Plugin) GetSpaceKeyFromSpaceIDWithAPIToken(spaceID int64, pluginConfig
 		response := &apiResponse{}
 
 		body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
-		if err != nil || statusCode != http.StatusOK {
+		if err != nil {
 			return "", errors.Wrapf(err, "error getting spaceKey from spaceID")
 		}
+		if statusCode != http.StatusOK {
+			return "", errors.Errorf("unexpected status %d", statusCode)
+		}
 
 		if err = json.Unmarshal(body, response); err != nil {
 			return "", errors.Wrapf(err, "failed to unmarshal spaceKey data")
@@ -268,7 +375,11 @@ func (p *Plugin) GetEventDataWithAPIToken(webhookPayload *serializer.ConfluenceS
 		}
 	}
 
-	if strings.Contains(webhookPayload.Event, Space) {
+	if webhookPayload.Event == serializer.SpaceRemovedEvent {
+		// A removed space cannot be fetched anymore.
+		supportedWHEventFound = true
+		confluenceServerEvent.Space = &SpaceResponse{Key: webhookPayload.Space.SpaceKey}
+	} else if strings.Contains(webhookPayload.Event, Space) {
 		supportedWHEventFound = true
 		confluenceServerEvent.Space, err = p.GetSpaceDataWithAPIToken(webhookPayload.Space.SpaceKey, pluginConfig)
 		if err != nil {
@@ -288,9 +399,12 @@ func (p *Plugin) GetCommentDataWithAPIToken(webhookPayload *serializer.Confluenc
 	path := fmt.Sprintf("%s%s", pluginConfig.ConfluenceURL, fmt.Sprintf("%s%s?expand=body.view,container,space,history", PathContentData, strconv.FormatInt(webhookPayload.Comment.ID, 10)))
 
 	body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
-	if err != nil || statusCode != http.StatusOK {
+	if err != nil {
 		return nil, err
 	}
+	if statusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %d", statusCode)
+	}
 
 	if err := json.Unmarshal(body, commentResponse); err != nil {
 		return nil, errors.Wrapf(err, "error getting comment data with API token")
@@ -303,12 +417,15 @@ func (p *Plugin) GetCommentDataWithAPIToken(webhookPayload *serializer.Confluenc
 
 func (p *Plugin) GetPageDataWithAPIToken(pageID int, pluginConfig *config.Configuration) (*PageResponse, error) {
 	pageResponse := &PageResponse{}
-	path := fmt.Sprintf("%s%s", pluginConfig.ConfluenceURL, fmt.Sprintf("%s%s?status=any&expand=body.view,container,space,history", PathContentData, strconv.Itoa(pageID)))
+	path := fmt.Sprintf("%s%s", pluginConfig.ConfluenceURL, fmt.Sprintf("%s%s?status=any&expand=body.view,container,space,history,version", PathContentData, strconv.Itoa(pageID)))
 
 	body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
-	if err != nil || statusCode != http.StatusOK {
+	if err != nil {
 		return nil, err
 	}
+	if statusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %d", statusCode)
+	}
 
 	if err := json.Unmarshal(body, pageResponse); err != nil {
 		return nil, errors.Wrapf(err, "error getting page data with API token")
@@ -320,25 +437,28 @@ func (p *Plugin) GetPageDataWithAPIToken(pageID int, pluginConfig *config.Config
 }
 
 func (p *Plugin) GetSpaceDataWithAPIToken(spaceKey string, pluginConfig *config.Configuration) (*SpaceResponse, error) {
-	spaceResponse := &SpaceResponse{}
-	path := fmt.Sprintf("%s%s", pluginConfig.ConfluenceURL, fmt.Sprintf("%s%s?status=any", PathSpaceData, spaceKey))
+	return cachedSpace(pluginConfig.ConfluenceURL, spaceKey, func() (*SpaceResponse, error) {
+		spaceResponse := &SpaceResponse{}
+		path := fmt.Sprintf("%s%s", pluginConfig.ConfluenceURL, fmt.Sprintf("%s%s?status=any&expand=description.plain", PathSpaceData, spaceKey))
 
-	body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
-	if err != nil || statusCode != http.StatusOK {
-		return nil, err
-	}
+		body, statusCode, err := p.MakeHTTPCallWithAPIToken(path)
+		if err != nil {
+			return nil, err
+		}
+		if statusCode != http.StatusOK {
+			return nil, errors.Errorf("unexpected status %d", statusCode)
+		}
 
-	if err := json.Unmarshal(body, spaceResponse); err != nil {
-		return nil, errors.Wrapf(err, "error getting space data with APIToken")
-	}
+		if err := json.Unmarshal(body, spaceResponse); err != nil {
+			return nil, errors.Wrapf(err, "error getting space data with APIToken")
+		}
 
-	return spaceResponse, nil
+		return spaceResponse, nil
+	})
 }
 
 func (p *Plugin) MakeHTTPCallWithAPIToken(path string) ([]byte, int, error) {
-	httpClient := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	httpClient := httpclient.Client(httpclient.DefaultTimeout)
 	req, err := http.NewRequest(http.MethodGet, path, nil)
 	if err != nil {
 		return nil, http.StatusInternalServerError, err
@@ -349,10 +469,13 @@ func (p *Plugin) MakeHTTPCallWithAPIToken(path string) ([]byte, int, error) {
 		return nil, http.StatusInternalServerError, err
 	}
 
+	start := time.Now()
 	resp, err := httpClient.Do(req)
 	if err != nil {
+		metrics.ObserveAPIRequest(path, 0, time.Since(start))
 		return nil, http.StatusInternalServerError, err
 	}
+	metrics.ObserveAPIRequest(path, resp.StatusCode, time.Since(start))
 
 	if resp == nil || resp.Body == nil {
 		return nil, http.StatusInternalServerError, err
@@ -1,236 +1,80 @@
 package main
 
 import (
-	"fmt"
 	"strings"
 
-	"github.com/mattermost/mattermost/server/public/model"
-
 	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
-	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 )
 
 func joinURL(baseURL, path string) string {
 	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
 }
 
-const (
-	ConfluencePageCreatedMessage            = "%s published a new page in %s."
-	ConfluencePageCreatedWithoutBodyMessage = "%s published a new page %s in %s."
-	ConfluencePageUpdatedMessage            = "%s updated %s in %s."
-	ConfluencePageTrashedMessage            = "%s trashed %s in %s."
-	ConfluencePageRestoredMessage           = "%s restored %s in %s."
-	ConfluenceCommentCreatedMessage         = "%s commented on %s in %s."
-	ConfluenceEmptyCommentCreatedMessage    = "%s [commented](%s) on %s in %s."
-	ConfluenceCommentUpdatedMessage         = "%s updated a comment on %s in %s."
-	ConfluenceEmptyCommentUpdatedMessage    = "%s updated a [comment](%s) on %s in %s."
-	ConfluenceSpaceUpdatedMessage           = "A space titled [%s](%s) was updated."
-)
-
-func (e ConfluenceServerEvent) GetSpaceKey() string {
-	if e.Space == nil {
-		return ""
-	}
-
-	return e.Space.Key
-}
-
-func (e ConfluenceServerEvent) GetURL() string {
-	return e.BaseURL
-}
-
-func (e ConfluenceServerEvent) GetCommentSpaceKey() string {
-	if e.Comment == nil {
-		return ""
-	}
-
-	return e.Comment.Space.Key
-}
-
-func (e ConfluenceServerEvent) GetCommentContainerID() string {
-	if e.Comment == nil {
-		return ""
-	}
-
-	return e.Comment.Container.ID
-}
-
-func (e ConfluenceServerEvent) GetPageSpaceKey() string {
-	if e.Page == nil {
-		return ""
-	}
-
-	return e.Page.Space.Key
-}
-
-func (e ConfluenceServerEvent) GetPageID() string {
-	if e.Page == nil {
-		return ""
-	}
-
-	return e.Page.ID
-}
-
-func (e *ConfluenceServerEvent) GetUserDisplayNameForCommentEvents() string {
-	if e.Comment == nil {
-		return ""
-	}
-
-	return util.GetUsernameOrAnonymousName(e.Comment.History.CreatedBy.Username)
-}
-
-func (e *ConfluenceServerEvent) GetUserDisplayNameForPageEvents() string {
-	if e.Page == nil {
-		return ""
-	}
-
-	return util.GetUsernameOrAnonymousName(e.Page.History.CreatedBy.Username)
-}
-
-func (e *ConfluenceServerEvent) GetSpaceDisplayNameForCommentEvents(baseURL string) string {
-	if e.Comment == nil {
-		return ""
-	}
-
-	name := e.Comment.Space.Key
-	if strings.TrimSpace(e.Comment.Space.Name) != "" {
-		name = strings.TrimSpace(e.Comment.Space.Name)
-	}
-	if e.Comment.Space.Links.Self != "" {
-		name = fmt.Sprintf("[%s](%s)", name, joinURL(baseURL, e.Comment.Space.Links.Self))
-	}
-	return name
-}
-
-func (e *ConfluenceServerEvent) GetSpaceDisplayNameForPageEvents(baseURL string) string {
-	if e.Page == nil {
-		return ""
-	}
-
-	name := e.Page.Space.Key
-	if strings.TrimSpace(e.Page.Space.Name) != "" {
-		name = strings.TrimSpace(e.Page.Space.Name)
-	}
-	if e.Page.Space.Links.Self != "" {
-		name = fmt.Sprintf("[%s](%s)", name, joinURL(baseURL, e.Page.Space.Links.Self))
-	}
-	return name
-}
-
-func (e *ConfluenceServerEvent) GetPageDisplayNameForPageEvents(baseURL string) string {
-	if e.Page == nil || e.Page.Title == "" {
-		return ""
-	}
-
-	name := e.Page.Title
-	if e.Page.Links.Self != "" {
-		name = fmt.Sprintf("[%s](%s)", name, joinURL(baseURL, e.Page.Links.Self))
+// actorName returns the name shown for the user who triggered the event.
+func (e *ConfluenceServerEvent) actorName(eventTriggerer *ConfluenceUser) string {
+	if e.Actor != "" {
+		return e.Actor
 	}
-	return name
+	return eventTriggerer.DisplayName
 }
 
-func (e *ConfluenceServerEvent) GetPageDisplayNameForCommentEvents(baseURL string) string {
-	if e.Comment == nil || e.Comment.Container.Title == "" {
+// link returns the absolute URL of a link of the Confluence REST API, empty when there is no link.
+func (e *ConfluenceServerEvent) link(path string) string {
+	if path == "" {
 		return ""
 	}
-
-	name := e.Comment.Container.Title
-	if e.Comment.Container.Links.Self != "" {
-		name = fmt.Sprintf("[%s](%s)", name, joinURL(baseURL, e.Comment.Container.Links.Self))
-	}
-	return name
+	return joinURL(e.BaseURL, path)
 }
 
-func (e ConfluenceServerEvent) GetNotificationPost(eventType, baseURL, botUserID, eventTriggerer string) *model.Post {
-	var attachment *model.SlackAttachment
-	post := &model.Post{
-		UserId: botUserID,
+// Normalize returns the common model of the event, nil when the content the event is about is missing.
+func (e *ConfluenceServerEvent) Normalize(eventType, actor string) *serializer.Event {
+	event := &serializer.Event{
+		Type:    eventType,
+		BaseURL: e.BaseURL,
+		Actor:   actor,
 	}
 
-	switch eventType {
-	case serializer.PageCreatedEvent, serializer.PageUpdatedEvent, serializer.PageTrashedEvent, serializer.PageRestoredEvent:
-		if e.Page == nil {
-			return nil
-		}
-	case serializer.CommentCreatedEvent, serializer.CommentUpdatedEvent:
+	var space *SpaceResponse
+	switch {
+	case strings.Contains(eventType, Comment):
 		if e.Comment == nil {
 			return nil
 		}
-	case serializer.SpaceUpdatedEvent:
-		if e.Space == nil {
+		space = &e.Comment.Space
+		event.PageID = e.Comment.Container.ID
+		event.PageTitle = e.Comment.Container.Title
+		event.PageURL = e.link(e.Comment.Container.Links.Self)
+		event.CommentURL = e.link(e.Comment.Links.Self)
+		event.Excerpt = e.Comment.Body.View.Value
+
+	case strings.Contains(eventType, Page):
+		if e.Page == nil {
 			return nil
 		}
-	default:
-		return nil
-	}
-
-	switch eventType {
-	case serializer.PageCreatedEvent:
-		message := fmt.Sprintf(ConfluencePageCreatedMessage, e.GetUserDisplayNameForPageEvents(), e.GetSpaceDisplayNameForPageEvents(baseURL))
-		if strings.TrimSpace(e.Page.Body.View.Value) != "" {
-			attachment = &model.SlackAttachment{
-				Fallback:  message,
-				Pretext:   message,
-				Title:     e.Page.Title,
-				TitleLink: joinURL(baseURL, e.Page.Links.Self),
-				Text:      fmt.Sprintf("%s\n\n[**View in Confluence**](%s)", strings.TrimSpace(e.Page.Body.View.Value), joinURL(baseURL, e.Page.Links.Self)),
-			}
-		} else {
-			post.Message = fmt.Sprintf(ConfluencePageCreatedWithoutBodyMessage, e.GetUserDisplayNameForPageEvents(), e.GetPageDisplayNameForPageEvents(baseURL), e.GetSpaceDisplayNameForPageEvents(baseURL))
-		}
-
-	case serializer.PageUpdatedEvent:
-		message := fmt.Sprintf(ConfluencePageUpdatedMessage, eventTriggerer, e.GetPageDisplayNameForPageEvents(baseURL), e.GetSpaceDisplayNameForPageEvents(baseURL))
-		if strings.TrimSpace(e.Page.Body.View.Value) != "" {
-			attachment = &model.SlackAttachment{
-				Fallback: message,
-				Pretext:  message,
-				Text:     fmt.Sprintf("**What's Changed?**\n> %s\n\n[**View in Confluence**](%s)", strings.TrimSpace(e.Page.Body.View.Value), joinURL(baseURL, e.Page.Links.Self)),
-			}
-		} else {
-			post.Message = message
+		space = &e.Page.Space
+		event.PageID = e.Page.ID
+		event.PageTitle = e.Page.Title
+		event.PageURL = e.link(e.Page.Links.Self)
+		event.IsMinorEdit = e.Page.Version.MinorEdit
+		if eventType == serializer.PageCreatedEvent || eventType == serializer.PageUpdatedEvent {
+			event.Excerpt = e.Page.Body.View.Value
 		}
 
-	case serializer.PageTrashedEvent:
-		post.Message = fmt.Sprintf(ConfluencePageTrashedMessage, e.GetUserDisplayNameForPageEvents(), e.GetPageDisplayNameForPageEvents(baseURL), e.GetSpaceDisplayNameForPageEvents(baseURL))
-
-	case serializer.PageRestoredEvent:
-		post.Message = fmt.Sprintf(ConfluencePageRestoredMessage, e.GetUserDisplayNameForPageEvents(), e.GetPageDisplayNameForPageEvents(baseURL), e.GetSpaceDisplayNameForPageEvents(baseURL))
-
-	case serializer.CommentCreatedEvent:
-		message := fmt.Sprintf(ConfluenceCommentCreatedMessage, e.GetUserDisplayNameForCommentEvents(), e.GetPageDisplayNameForCommentEvents(baseURL), e.GetSpaceDisplayNameForCommentEvents(baseURL))
-		text := ""
-		if strings.TrimSpace(e.Comment.Body.View.Value) != "" {
-			text = fmt.Sprintf("**%s wrote:**\n> %s\n\n", e.GetUserDisplayNameForCommentEvents(), strings.TrimSpace(e.Comment.Body.View.Value))
-			attachment = &model.SlackAttachment{
-				Fallback: message,
-				Pretext:  message,
-				Text:     fmt.Sprintf("%s\n\n[**View in Confluence**](%s)", text, joinURL(baseURL, e.Comment.Links.Self)),
-			}
-		} else {
-			post.Message = fmt.Sprintf(ConfluenceEmptyCommentCreatedMessage, e.GetUserDisplayNameForCommentEvents(), joinURL(baseURL, e.Comment.Links.Self), e.GetPageDisplayNameForCommentEvents(baseURL), e.GetSpaceDisplayNameForCommentEvents(baseURL))
+	case strings.Contains(eventType, Space):
+		if e.Space == nil {
+			return nil
 		}
-
-	case serializer.CommentUpdatedEvent:
-		message := fmt.Sprintf(ConfluenceCommentUpdatedMessage, e.GetUserDisplayNameForCommentEvents(), e.GetPageDisplayNameForCommentEvents(baseURL), e.GetSpaceDisplayNameForCommentEvents(baseURL))
-		if strings.TrimSpace(e.Comment.Body.View.Value) != "" {
-			attachment = &model.SlackAttachment{
-				Fallback: message,
-				Pretext:  message,
-				Text:     fmt.Sprintf("**Updated Comment:**\n> %s\n\n[**View in Confluence**](%s)", strings.TrimSpace(e.Comment.Body.View.Value), joinURL(baseURL, e.Comment.Links.Self)),
-			}
-		} else {
-			post.Message = fmt.Sprintf(ConfluenceEmptyCommentUpdatedMessage, e.GetUserDisplayNameForCommentEvents(), joinURL(baseURL, e.Comment.Links.Self), e.GetPageDisplayNameForCommentEvents(baseURL), e.GetSpaceDisplayNameForCommentEvents(baseURL))
+		space = e.Space
+		if eventType == serializer.SpaceCreatedEvent || eventType == serializer.SpaceUpdatedEvent {
+			event.Excerpt = e.Space.Description.Plain.Value
 		}
 
-	case serializer.SpaceUpdatedEvent:
-		post.Message = fmt.Sprintf(ConfluenceSpaceUpdatedMessage, e.Space.Key, joinURL(baseURL, e.Space.Links.Self))
 	default:
 		return nil
 	}
 
-	if attachment != nil {
-		model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
-	}
-	return post
+	event.SpaceKey = space.Key
+	event.SpaceName = space.Name
+	event.SpaceURL = e.link(space.Links.Self)
+	return event
 }
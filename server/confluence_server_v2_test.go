@@ -1,9 +1,18 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
 )
 
 func TestJoinURL(t *testing.T) {
@@ -64,3 +73,90 @@ func TestJoinURL(t *testing.T) {
 		})
 	}
 }
+
+func TestConfluenceServerEventNormalize(t *testing.T) {
+	space := SpaceResponse{Key: "DEV", Name: "Development", Links: Links{Self: "/display/DEV"}}
+	event := &ConfluenceServerEvent{
+		BaseURL: "https://confluence.example.com/",
+		Page: &PageResponse{
+			ID:      "42",
+			Title:   "Release notes",
+			Space:   space,
+			Body:    Body{View: View{Value: "The release notes"}},
+			Links:   Links{Self: "/pages/viewpage.action?pageId=42"},
+			Version: ContentVersion{MinorEdit: true, Message: "Fixed the typos"},
+		},
+		Comment: &CommentResponse{
+			Space:     space,
+			Container: CommentContainer{ID: "42", Title: "Release notes", Links: Links{Self: "/pages/viewpage.action?pageId=42"}},
+			Body:      Body{View: View{Value: "Looks good"}},
+			Links:     Links{Self: "/pages/viewpage.action?pageId=42#comment-43"},
+		},
+	}
+
+	page := event.Normalize(serializer.PageUpdatedEvent, "@jane")
+	require.NotNil(t, page)
+	assert.Equal(t, &serializer.Event{
+		Type:        serializer.PageUpdatedEvent,
+		BaseURL:     "https://confluence.example.com/",
+		SpaceKey:    "DEV",
+		SpaceName:   "Development",
+		SpaceURL:    "https://confluence.example.com/display/DEV",
+		PageID:      "42",
+		PageTitle:   "Release notes",
+		PageURL:     "https://confluence.example.com/pages/viewpage.action?pageId=42",
+		Actor:       "@jane",
+		Excerpt:     "The release notes",
+		IsMinorEdit: true,
+	}, page)
+
+	comment := event.Normalize(serializer.CommentCreatedEvent, "Jane Doe")
+	require.NotNil(t, comment)
+	assert.Equal(t, "42", comment.PageID)
+	assert.Equal(t, "https://confluence.example.com/pages/viewpage.action?pageId=42#comment-43", comment.CommentURL)
+	assert.Equal(t, "Looks good", comment.Excerpt)
+
+	assert.Nil(t, event.Normalize(serializer.SpaceUpdatedEvent, "Jane Doe"))
+
+	event.Space = &SpaceResponse{Key: "DEV", Name: "Development", Description: SpaceDescription{Plain: View{Value: "Everything about development"}}, Links: Links{Self: "/display/DEV"}}
+	assert.Equal(t, &serializer.Event{
+		Type:      serializer.SpaceUpdatedEvent,
+		BaseURL:   "https://confluence.example.com/",
+		SpaceKey:  "DEV",
+		SpaceName: "Development",
+		SpaceURL:  "https://confluence.example.com/display/DEV",
+		Actor:     "Jane Doe",
+		Excerpt:   "Everything about development",
+	}, event.Normalize(serializer.SpaceUpdatedEvent, "Jane Doe"))
+}
+
+func TestSendServerWebhookNotificationRemovedSpaceWithoutKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// The removed space is not listed anymore.
+		_, _ = w.Write([]byte(`{"results":[]}`))
+	}))
+	defer server.Close()
+
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	pluginConfig := &config.Configuration{ConfluenceURL: server.URL, AdminAPIToken: "admin-api-token"}
+	config.SetConfig(pluginConfig)
+	p := &Plugin{}
+	p.SetAPI(mockAPI)
+	p.client = pluginapi.NewClient(mockAPI, nil)
+
+	mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	mockAPI.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockAPI.On("LogInfo", mock.Anything).Maybe()
+	mockAPI.On("LogInfo", "Skipping the removal of a space whose key is unknown", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once()
+
+	event := &serializer.ConfluenceServerWebhookPayload{
+		Event:   serializer.SpaceRemovedEvent,
+		UserKey: "user-key",
+		Space:   serializer.SpacePayload{ID: 987654},
+	}
+	result, err := p.sendServerWebhookNotification(event, pluginConfig)
+	require.NoError(t, err)
+	assert.Zero(t, result.MatchedChannels)
+	mockAPI.AssertExpectations(t)
+}
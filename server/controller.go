@@ -35,8 +35,17 @@ var Endpoints = map[string]*Endpoint{
 	getEndpointKey(autocompleteGetChannelSubscriptions): autocompleteGetChannelSubscriptions,
 	getEndpointKey(userConnect):                         userConnect,
 	getEndpointKey(userConnectComplete):                 userConnectComplete,
+	getEndpointKey(userConnectCloud):                    userConnectCloud,
+	getEndpointKey(userConnectCompleteCloud):            userConnectCompleteCloud,
 	getEndpointKey(userConnectionInfo):                  userConnectionInfo,
 	getEndpointKey(getPluginConfig):                     getPluginConfig,
+	getEndpointKey(exportAuditLog):                      exportAuditLog,
+	getEndpointKey(getWebhookDeliveries):                getWebhookDeliveries,
+	getEndpointKey(replayWebhookDelivery):               replayWebhookDelivery,
+	getEndpointKey(getMetrics):                          getMetrics,
+	getEndpointKey(exportSubscriptions):                 exportSubscriptions,
+	getEndpointKey(importSubscriptions):                 importSubscriptions,
+	getEndpointKey(getAllSubscriptions):                 getAllSubscriptions,
 }
 
 // Uniquely identifies an endpoint using path and method
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	deliveriesOnlySystemAdmin = "`/confluence deliveries` can only be run by a system administrator."
+	deliveriesNoInstanceText  = "No Confluence Data Center instance is configured. Please specify the URL of the Confluence instance."
+	maxDeliveryCommandEntries = 25
+)
+
+var getWebhookDeliveries = &Endpoint{
+	Path:            "/deliveries",
+	Method:          http.MethodGet,
+	Execute:         handleGetWebhookDeliveries,
+	IsAuthenticated: true,
+}
+
+var replayWebhookDelivery = &Endpoint{
+	Path:            "/deliveries/{id:[A-Za-z0-9]+}/replay",
+	Method:          http.MethodPost,
+	Execute:         handleReplayWebhookDelivery,
+	IsAuthenticated: true,
+}
+
+func handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	if !util.IsSystemAdmin(userID) {
+		p.client.Log.Error("Non admin user does not have access to the webhook deliveries", "UserID", userID)
+		http.Error(w, "only system admin can read the webhook deliveries", http.StatusForbidden)
+		return
+	}
+
+	instanceID := deliveriesInstanceID(r.FormValue("instance"))
+	if instanceID == "" {
+		http.Error(w, "please specify the instance", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := service.GetWebhookDeliveries(instanceID)
+	if err != nil {
+		p.client.Log.Error("Error reading the webhook deliveries", "InstanceID", instanceID, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, _ := json.Marshal(deliveries)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func handleReplayWebhookDelivery(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	if !util.IsSystemAdmin(userID) {
+		p.client.Log.Error("Non admin user does not have access to replay webhook deliveries", "UserID", userID)
+		http.Error(w, "only system admin can replay webhook deliveries", http.StatusForbidden)
+		return
+	}
+
+	instanceID := deliveriesInstanceID(r.FormValue("instance"))
+	if instanceID == "" {
+		http.Error(w, "please specify the instance", http.StatusBadRequest)
+		return
+	}
+
+	replayed, err := p.replayWebhookDelivery(instanceID, mux.Vars(r)["id"])
+	if err != nil {
+		p.client.Log.Error("Error replaying the webhook delivery", "InstanceID", instanceID, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, _ := json.Marshal(replayed)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func executeDeliveries(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, deliveriesOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	if len(args) > 0 && args[0] == "replay" {
+		if len(args) < 2 {
+			postCommandResponse(context, "Please specify the ID of the delivery to replay.")
+			return &model.CommandResponse{}
+		}
+
+		instanceURL := ""
+		if len(args) > 2 {
+			instanceURL = args[2]
+		}
+		instanceID := deliveriesInstanceID(instanceURL)
+		if instanceID == "" {
+			postCommandResponse(context, deliveriesNoInstanceText)
+			return &model.CommandResponse{}
+		}
+
+		replayed, err := p.replayWebhookDelivery(instanceID, args[1])
+		if err != nil {
+			p.client.Log.Error("Error replaying the webhook delivery", "InstanceID", instanceID, "DeliveryID", args[1], "error", err.Error())
+			postCommandResponse(context, fmt.Sprintf("Unable to replay the delivery: %s.", err.Error()))
+			return &model.CommandResponse{}
+		}
+
+		postCommandResponse(context, formatReplayedDelivery(replayed))
+		return &model.CommandResponse{}
+	}
+
+	instanceURL := ""
+	if len(args) > 0 {
+		instanceURL = args[0]
+	}
+	instanceID := deliveriesInstanceID(instanceURL)
+	if instanceID == "" {
+		postCommandResponse(context, deliveriesNoInstanceText)
+		return &model.CommandResponse{}
+	}
+
+	deliveries, err := service.GetWebhookDeliveries(instanceID)
+	if err != nil {
+		p.client.Log.Error("Error reading the webhook deliveries", "InstanceID", instanceID, "error", err.Error())
+		postCommandResponse(context, errorExecutingCommand)
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, formatWebhookDeliveries(deliveries, instanceID))
+	return &model.CommandResponse{}
+}
+
+// deliveriesInstanceID returns the given instance URL, or the Confluence Data Center instance if none is given.
+func deliveriesInstanceID(instanceURL string) string {
+	if instanceURL == "" {
+		return config.GetConfig().GetConfluenceBaseURL()
+	}
+	return strings.TrimRight(instanceURL, "/")
+}
+
+// recordFailedDelivery records a webhook request whose payload could not be decoded.
+func recordFailedDelivery(instanceID, source string, body []byte, err error) {
+	delivery := service.NewWebhookDelivery(source, "", "", body)
+	delivery.SetResult(service.DeliveryResult{}, err)
+	service.RecordWebhookDelivery(instanceID, delivery)
+}
+
+// replayWebhookDelivery sends a recorded delivery through the notification pipeline again. Unlike a retry from
+// Confluence, it is not ignored as a duplicate. The replay is recorded as a new delivery.
+//
+// The recorded payload does not have the content of the pages and comments, so the replay is not a faithful
+// re-delivery: only the Data Center 9 webhooks fetch the content through the REST API, the other replays are
+// labeled as metadata only.
+func (p *Plugin) replayWebhookDelivery(instanceID, deliveryID string) (*service.WebhookDelivery, error) {
+	original, err := service.GetWebhookDelivery(instanceID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(original.Payload) == 0 {
+		return nil, errors.New("the payload of this delivery was not recorded")
+	}
+
+	replayed := service.NewWebhookDelivery(original.Source, original.EventType, original.ContentID, original.Payload)
+	replayed.ReplayOf = original.ID
+	replayed.MetadataOnly = original.Source != service.DeliverySourceServerWebhook
+
+	result, err := p.sendRecordedDelivery(original)
+	replayed.SetResult(result, err)
+	service.RecordWebhookDelivery(instanceID, replayed)
+	return &replayed, nil
+}
+
+func (p *Plugin) sendRecordedDelivery(delivery *service.WebhookDelivery) (service.DeliveryResult, error) {
+	switch delivery.Source {
+	case service.DeliverySourceCloud:
+		event, err := serializer.ConfluenceCloudEventFromJSON(bytes.NewReader(delivery.Payload))
+		if err != nil {
+			return service.DeliveryResult{}, err
+		}
+		return p.sendCloudEventNotifications(cloudInstanceID(event), event, delivery.EventType), nil
+	case service.DeliverySourceServer:
+		event, err := serializer.ConfluenceServerEventFromJSON(bytes.NewReader(delivery.Payload))
+		if err != nil {
+			return service.DeliveryResult{}, err
+		}
+		return p.sendServerEventNotifications(config.GetConfig().GetConfluenceBaseURL(), event), nil
+	case service.DeliverySourceServerWebhook:
+		var event *serializer.ConfluenceServerWebhookPayload
+		if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+			return service.DeliveryResult{}, err
+		}
+		return p.sendServerWebhookNotification(event, config.GetConfig())
+	default:
+		return service.DeliveryResult{}, errors.Errorf("unknown delivery source %q", delivery.Source)
+	}
+}
+
+func formatWebhookDeliveries(deliveries []service.WebhookDelivery, instanceID string) string {
+	if len(deliveries) == 0 {
+		return fmt.Sprintf("No webhook deliveries were recorded for %s.", instanceID)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Recent webhook deliveries for %s:\n\n", instanceID)
+	sb.WriteString("| Time (UTC) | ID | Event | Content | Channels | Outcome | Error |\n| :--- | :--- | :--- | :--- | :--- | :--- | :--- |\n")
+	shown := 0
+	for i := len(deliveries) - 1; i >= 0 && shown < maxDeliveryCommandEntries; i-- {
+		delivery := deliveries[i]
+		outcome := delivery.Outcome
+		if delivery.MetadataOnly {
+			outcome += " (metadata-only replay)"
+		} else if delivery.ReplayOf != "" {
+			outcome += " (replay)"
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %d | %s | %s |\n",
+			time.UnixMilli(delivery.Timestamp).UTC().Format("2006-01-02 15:04:05"),
+			delivery.ID,
+			delivery.EventType,
+			delivery.ContentID,
+			delivery.MatchedChannels,
+			outcome,
+			strings.ReplaceAll(delivery.Error, "|", "\\|"),
+		)
+		shown++
+	}
+
+	if len(deliveries) > shown {
+		fmt.Fprintf(&sb, "\nShowing the latest %d of %d deliveries. Use the `%s/api/v1%s` endpoint to read all of them.", shown, len(deliveries), util.GetPluginURL(), getWebhookDeliveries.Path)
+	}
+	sb.WriteString("\nRun `/confluence deliveries replay <ID>` to send a delivery through the notifications again. The content of the pages and comments is not recorded, the replays of Confluence Cloud and Data Center 8 deliveries only have the metadata of the event.")
+	return sb.String()
+}
+
+func formatReplayedDelivery(delivery *service.WebhookDelivery) string {
+	var out string
+	switch delivery.Outcome {
+	case service.DeliveryOutcomeFailed:
+		out = fmt.Sprintf("The delivery %s was replayed but failed: %s.", delivery.ReplayOf, delivery.Error)
+	case service.DeliveryOutcomeNoMatch:
+		out = fmt.Sprintf("The delivery %s was replayed, no subscription matches it.", delivery.ReplayOf)
+	default:
+		out = fmt.Sprintf("The delivery %s was replayed to %d channels.", delivery.ReplayOf, delivery.MatchedChannels)
+	}
+
+	if delivery.MetadataOnly {
+		out += " This replay only has the metadata of the event: the content of the pages and comments is not recorded, so the notifications do not include it."
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+const testCloudPayload = `{"timestamp":1714557600000,"page":{"id":"42","self":"https://example.atlassian.net/wiki/rest/api/content/42","spaceKey":"DEV","title":"Release notes","version":3}}`
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	const instanceID = "https://example.atlassian.net"
+	recorded := []service.WebhookDelivery{
+		{ID: "replayable", Source: service.DeliverySourceCloud, EventType: "page_updated", ContentID: "42", Outcome: service.DeliveryOutcomeFailed, Payload: json.RawMessage(testCloudPayload)},
+		{ID: "unrecorded", Source: service.DeliverySourceCloud, EventType: "page_updated", Outcome: service.DeliveryOutcomeDelivered},
+	}
+
+	for name, tc := range map[string]struct {
+		deliveryID      string
+		expectedOutcome string
+		expectError     bool
+	}{
+		"replayed through the notification pipeline": {
+			deliveryID:      "replayable",
+			expectedOutcome: service.DeliveryOutcomeNoMatch,
+		},
+		"payload not recorded": {
+			deliveryID:  "unrecorded",
+			expectError: true,
+		},
+		"unknown delivery": {
+			deliveryID:  "unknown",
+			expectError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+
+			ids := []string{}
+			for _, delivery := range recorded {
+				data, _ := json.Marshal(delivery)
+				mockAPI.On("KVGet", store.GetWebhookDeliveryKey(instanceID, delivery.ID)).Return(data, nil)
+				ids = append(ids, delivery.ID)
+			}
+			index, _ := json.Marshal(ids)
+			deliveriesKey := store.GetWebhookDeliveriesKey(instanceID)
+			mockAPI.On("KVGet", deliveriesKey).Return(index, nil)
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+
+			var stored service.WebhookDelivery
+			mockAPI.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				require.NoError(t, json.Unmarshal(args.Get(1).([]byte), &stored))
+			}).Return(nil)
+			var saved []string
+			mockAPI.On("KVCompareAndSet", deliveriesKey, index, mock.Anything).Run(func(args mock.Arguments) {
+				require.NoError(t, json.Unmarshal(args.Get(2).([]byte), &saved))
+			}).Return(true, nil)
+
+			replayed, err := p.replayWebhookDelivery(instanceID, tc.deliveryID)
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, saved)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOutcome, replayed.Outcome)
+			assert.Equal(t, tc.deliveryID, replayed.ReplayOf)
+			require.Len(t, saved, len(recorded)+1)
+			assert.Equal(t, stored.ID, saved[len(saved)-1])
+			assert.Equal(t, tc.deliveryID, stored.ReplayOf)
+			assert.True(t, stored.MetadataOnly)
+			assert.Contains(t, formatReplayedDelivery(replayed), "only has the metadata of the event")
+		})
+	}
+}
+
+func TestFormatWebhookDeliveries(t *testing.T) {
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	siteURL := "https://mattermost.example.com"
+	mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).UnixMilli()
+	out := formatWebhookDeliveries([]service.WebhookDelivery{
+		{ID: "first", Timestamp: at, EventType: "page_created", ContentID: "42", MatchedChannels: 2, Outcome: service.DeliveryOutcomeDelivered},
+		{ID: "second", Timestamp: at, EventType: "page_updated", ContentID: "42", Outcome: service.DeliveryOutcomeFailed, Error: "unable to get the page"},
+		{ID: "third", Timestamp: at, EventType: "page_updated", ContentID: "42", Outcome: service.DeliveryOutcomeNoMatch, ReplayOf: "second"},
+		{ID: "fourth", Timestamp: at, EventType: "page_updated", ContentID: "42", Outcome: service.DeliveryOutcomeDelivered, ReplayOf: "second", MetadataOnly: true},
+	}, "https://confluence.example.com")
+
+	assert.Contains(t, out, "| 2024-05-01 10:00:00 | first | page_created | 42 | 2 | delivered |  |")
+	assert.Contains(t, out, "| second | page_updated | 42 | 0 | failed | unable to get the page |")
+	assert.Contains(t, out, "| no_match (replay) |")
+	assert.Contains(t, out, "| delivered (metadata-only replay) |")
+	assert.Less(t, strings.Index(out, "third"), strings.Index(out, "first"))
+
+	assert.Equal(t, "No webhook deliveries were recorded for https://confluence.example.com.", formatWebhookDeliveries(nil, "https://confluence.example.com"))
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	doctorOnlySystemAdmin = "`/confluence doctor` can only be run by a system administrator."
+
+	// A webhook delivery older than this is reported, Confluence is likely not reaching Mattermost anymore.
+	recentWebhookDeliveryPeriod = 7 * 24 * time.Hour
+
+	// Results of a diagnostic check
+	checkPassed  = "pass"
+	checkFailed  = "fail"
+	checkSkipped = "skip"
+)
+
+// diagnosticCheck is the result of one of the checks run by `/confluence doctor`.
+type diagnosticCheck struct {
+	Name   string
+	Result string
+	Detail string
+	Fix    string
+}
+
+func passed(name, detail string) diagnosticCheck {
+	return diagnosticCheck{Name: name, Result: checkPassed, Detail: detail}
+}
+
+func failed(name, detail, fix string) diagnosticCheck {
+	return diagnosticCheck{Name: name, Result: checkFailed, Detail: detail, Fix: fix}
+}
+
+func skipped(name, detail string) diagnosticCheck {
+	return diagnosticCheck{Name: name, Result: checkSkipped, Detail: detail}
+}
+
+func executeDoctor(p *Plugin, context *model.CommandArgs, _ ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, doctorOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, formatDiagnostics(p.runDiagnostics()))
+	return &model.CommandResponse{}
+}
+
+// runDiagnostics checks every part of the integration, from the configuration to the webhook deliveries.
+func (p *Plugin) runDiagnostics() []diagnosticCheck {
+	pluginConfig := config.GetConfig()
+	checks := []diagnosticCheck{
+		checkConfiguration(pluginConfig),
+		p.checkBotUser(),
+		checkSubscriptionStore(),
+	}
+
+	instanceURL := pluginConfig.GetConfluenceBaseURL()
+	if instanceURL == "" {
+		return append(checks, skipped("Confluence Data Center", "No Confluence Data Center instance is configured, the remaining checks only apply to Data Center."))
+	}
+
+	reachable := checkConfluenceURL(instanceURL)
+	checks = append(checks, reachable)
+	if reachable.Result != checkPassed {
+		return checks
+	}
+
+	checks = append(checks, p.checkOAuthCredentials(pluginConfig, instanceURL))
+
+	adminConnection, client := p.checkAdminConnection(instanceURL)
+	checks = append(checks,
+		adminConnection,
+		p.checkAPIToken(pluginConfig, instanceURL),
+		p.checkWebhookRegistration(pluginConfig, client),
+		checkWebhookDeliveries(time.Now()),
+	)
+	return checks
+}
+
+func checkConfiguration(pluginConfig *config.Configuration) diagnosticCheck {
+	const name = "Plugin configuration"
+	if err := pluginConfig.IsValid(); err != nil {
+		return failed(name, err.Error(), "Fix the plugin settings in **System Console > Plugins > Confluence**.")
+	}
+	return passed(name, "The configuration is valid.")
+}
+
+func (p *Plugin) checkBotUser() diagnosticCheck {
+	const name = "Bot user"
+	fix := "Disable and enable the plugin to create the bot user again."
+	if p.BotUserID == "" {
+		return failed(name, "The bot user is not set up.", fix)
+	}
+
+	bot, appErr := p.API.GetUser(p.BotUserID)
+	if appErr != nil {
+		return failed(name, "The bot user does not exist: "+appErr.Error(), fix)
+	}
+	if bot.DeleteAt != 0 {
+		return failed(name, fmt.Sprintf("The bot user @%s is deactivated.", bot.Username), "Enable the bot in **System Console > Integrations > Bot Accounts**.")
+	}
+	return passed(name, fmt.Sprintf("The bot user @%s exists.", bot.Username))
+}
+
+func checkSubscriptionStore() diagnosticCheck {
+	const name = "Subscription store"
+	data, appErr := config.Mattermost.KVGet(store.GetSubscriptionKey())
+	if appErr != nil {
+		return failed(name, "The subscriptions cannot be read: "+appErr.Error(), "Check the Mattermost server logs for database errors.")
+	}
+
+	subscriptions, err := serializer.SubscriptionsFromJSON(data)
+	if err != nil {
+		return failed(name, "The subscriptions cannot be decoded: "+err.Error(), "Restore the subscriptions from an export with `/confluence import`.")
+	}
+
+	count := 0
+	for _, channelSubscriptions := range subscriptions.ByChannelID {
+		count += len(channelSubscriptions)
+	}
+	return passed(name, fmt.Sprintf("%d subscriptions decoded.", count))
+}
+
+func checkConfluenceURL(instanceURL string) diagnosticCheck {
+	const name = "Confluence URL"
+	if _, err := service.CheckConfluenceURL(util.GetSiteURL(), instanceURL, false); err != nil {
+		return failed(name, err.Error(), fmt.Sprintf("Make sure %s is reachable from the Mattermost server, or run `/confluence install server` to change the URL.", instanceURL))
+	}
+	return passed(name, instanceURL+" is reachable and running.")
+}
+
+// checkOAuthCredentials asks Confluence to refresh a made up token. Confluence rejects the grant when the client
+// credentials are valid, and the client otherwise.
+func (p *Plugin) checkOAuthCredentials(pluginConfig *config.Configuration, instanceURL string) diagnosticCheck {
+	const name = "OAuth credentials"
+	fix := "Run `/confluence install server` to enter the OAuth credentials of the Confluence application link again."
+	if !pluginConfig.IsOAuthConfigured() {
+		return failed(name, "The OAuth client ID and secret are not configured.", fix)
+	}
+
+	oconf, err := p.GetServerOAuth2Config(instanceURL, true)
+	if err != nil {
+		return failed(name, err.Error(), fix)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverVersionTimeout)
+	defer cancel()
+	_, err = oconf.TokenSource(ctx, &oauth2.Token{RefreshToken: "mattermost-doctor", Expiry: time.Now()}).Token()
+
+	var retrieveErr *oauth2.RetrieveError
+	switch {
+	case err == nil, errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant":
+		return passed(name, "Confluence accepts the OAuth client credentials.")
+	case retrieveErr != nil && (retrieveErr.ErrorCode == "invalid_client" || retrieveErr.ErrorCode == "unauthorized_client"):
+		return failed(name, "Confluence rejects the OAuth client ID or secret.", fix)
+	default:
+		return failed(name, "The OAuth token endpoint could not be checked: "+err.Error(), fix)
+	}
+}
+
+func (p *Plugin) checkAdminConnection(instanceURL string) (diagnosticCheck, *confluenceServerClient) {
+	const name = "Admin connection"
+	fix := "Run `/confluence connect` as a Confluence administrator."
+
+	client, err := p.getAdminServerClient(instanceURL)
+	if err != nil {
+		return failed(name, err.Error(), fix), nil
+	}
+
+	user, err := client.GetSelf()
+	if err != nil {
+		return failed(name, "The admin token is not accepted by Confluence: "+err.Error(), fix), nil
+	}
+	return passed(name, fmt.Sprintf("Connected as %s.", user.DisplayName)), client
+}
+
+func (p *Plugin) checkAPIToken(pluginConfig *config.Configuration, instanceURL string) diagnosticCheck {
+	const name = "Admin API token"
+	if pluginConfig.AdminAPIToken == "" {
+		return skipped(name, "No API token is configured, events triggered by users who are not connected are sent as generic notifications.")
+	}
+
+	_, statusCode, err := p.MakeHTTPCallWithAPIToken(instanceURL + PathCurrentUser)
+	if err != nil || statusCode != http.StatusOK {
+		detail := fmt.Sprintf("Confluence answered with status %d.", statusCode)
+		if err != nil {
+			detail = err.Error()
+		}
+		return failed(name, detail, "Create a new personal access token with a Confluence administrator account, and set it in the plugin settings.")
+	}
+	return passed(name, "Confluence accepts the API token.")
+}
+
+func (p *Plugin) checkWebhookRegistration(pluginConfig *config.Configuration, client *confluenceServerClient) diagnosticCheck {
+	const name = "Webhook registration"
+	if !pluginConfig.ServerVersionGreaterthan9 {
+		return skipped(name, "Confluence versions before 9 send their events through the Mattermost app, its configuration cannot be checked.")
+	}
+	if client == nil {
+		return skipped(name, "The webhooks cannot be listed without the admin connection.")
+	}
+
+	webhooks, err := client.ListWebhooks()
+	if err != nil {
+		return failed(name, "The webhooks cannot be listed: "+err.Error(), "Make sure the admin connection has administrator permissions.")
+	}
+
+	registration := findServerWebhook(webhooks, expectedServerWebhookURL())
+	switch registration.status {
+	case webhookStatusRegistered:
+		return passed(name, "The webhook is registered.")
+	case webhookStatusStale:
+		return failed(name, "The webhook needs to be repaired: "+strings.Join(registration.problems, ", ")+".", "Run `/confluence webhook repair`.")
+	default:
+		return failed(name, "No webhook sends the Confluence events to Mattermost.", "Run `/confluence webhook repair`.")
+	}
+}
+
+func checkWebhookDeliveries(now time.Time) diagnosticCheck {
+	const name = "Webhook deliveries"
+	fix := "Check the webhook in Confluence, and that Confluence can reach the Mattermost site URL."
+
+	lastDelivery, err := store.LoadLastWebhookDelivery()
+	if err != nil {
+		return failed(name, "The last delivery cannot be read: "+err.Error(), "Check the Mattermost server logs for database errors.")
+	}
+	if lastDelivery.IsZero() {
+		return failed(name, "No webhook request was received yet.", fix)
+	}
+	if now.Sub(lastDelivery) > recentWebhookDeliveryPeriod {
+		return failed(name, "The last webhook request was received on "+lastDelivery.UTC().Format(time.RFC1123)+".", fix)
+	}
+	return passed(name, "The last webhook request was received on "+lastDelivery.UTC().Format(time.RFC1123)+".")
+}
+
+func formatDiagnostics(checks []diagnosticCheck) string {
+	icons := map[string]string{
+		checkPassed:  ":white_check_mark:",
+		checkFailed:  ":x:",
+		checkSkipped: ":heavy_minus_sign:",
+	}
+
+	var sb strings.Builder
+	failures := 0
+	sb.WriteString("| | Check | Details | Fix |\n| :--- | :--- | :--- | :--- |\n")
+	for _, check := range checks {
+		if check.Result == checkFailed {
+			failures++
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", icons[check.Result], check.Name, check.Detail, check.Fix)
+	}
+
+	if failures == 0 {
+		sb.WriteString("\nAll checks passed.")
+	} else {
+		fmt.Fprintf(&sb, "\n%d of %d checks failed.", failures, len(checks))
+	}
+	return sb.String()
+}
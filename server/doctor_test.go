@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func TestCheckSubscriptionStore(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data           []byte
+		expectedResult string
+	}{
+		"empty store": {
+			expectedResult: checkPassed,
+		},
+		"valid subscriptions": {
+			data:           []byte(`{"ByChannelID":{"channel":{"alias":{"alias":"alias","baseURL":"https://confluence.example.com","spaceKey":"DEV","events":["page_created"],"channelID":"channel","subscriptionType":"space_subscription"}}}}`),
+			expectedResult: checkPassed,
+		},
+		"corrupted subscriptions": {
+			data:           []byte(`{"ByChannelID":`),
+			expectedResult: checkFailed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(tc.data, nil)
+
+			assert.Equal(t, tc.expectedResult, checkSubscriptionStore().Result)
+		})
+	}
+}
+
+func TestCheckWebhookDeliveries(t *testing.T) {
+	now := time.Now()
+	for name, tc := range map[string]struct {
+		lastDelivery   time.Time
+		expectedResult string
+	}{
+		"no delivery": {
+			expectedResult: checkFailed,
+		},
+		"recent delivery": {
+			lastDelivery:   now.Add(-time.Hour),
+			expectedResult: checkPassed,
+		},
+		"old delivery": {
+			lastDelivery:   now.Add(-30 * 24 * time.Hour),
+			expectedResult: checkFailed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+
+			var data []byte
+			if !tc.lastDelivery.IsZero() {
+				data, _ = json.Marshal(tc.lastDelivery.UnixMilli())
+			}
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(data, nil)
+
+			assert.Equal(t, tc.expectedResult, checkWebhookDeliveries(now).Result)
+		})
+	}
+}
+
+func TestCheckBotUser(t *testing.T) {
+	for name, tc := range map[string]struct {
+		botUserID      string
+		user           *model.User
+		appErr         *model.AppError
+		expectedResult string
+	}{
+		"bot exists": {
+			botUserID:      "bot",
+			user:           &model.User{Id: "bot", Username: "confluence"},
+			expectedResult: checkPassed,
+		},
+		"bot deactivated": {
+			botUserID:      "bot",
+			user:           &model.User{Id: "bot", Username: "confluence", DeleteAt: 1},
+			expectedResult: checkFailed,
+		},
+		"bot missing": {
+			botUserID:      "bot",
+			appErr:         &model.AppError{Message: "not found"},
+			expectedResult: checkFailed,
+		},
+		"bot not set up": {
+			expectedResult: checkFailed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			p := &Plugin{BotUserID: tc.botUserID}
+			p.SetAPI(mockAPI)
+			mockAPI.On("GetUser", "bot").Return(tc.user, tc.appErr)
+
+			assert.Equal(t, tc.expectedResult, p.checkBotUser().Result)
+		})
+	}
+}
+
+func TestCheckOAuthCredentials(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status         int
+		errorCode      string
+		configured     bool
+		expectedResult string
+	}{
+		"valid credentials": {
+			status:         http.StatusBadRequest,
+			errorCode:      "invalid_grant",
+			configured:     true,
+			expectedResult: checkPassed,
+		},
+		"invalid credentials": {
+			status:         http.StatusUnauthorized,
+			errorCode:      "invalid_client",
+			configured:     true,
+			expectedResult: checkFailed,
+		},
+		"not configured": {
+			expectedResult: checkFailed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/rest/oauth2/latest/token", r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(`{"error":"` + tc.errorCode + `"}`))
+			}))
+			defer server.Close()
+
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			siteURL := "https://mattermost.example.com"
+			mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+
+			pluginConfig := &config.Configuration{ConfluenceURL: server.URL}
+			if tc.configured {
+				pluginConfig.ConfluenceOAuthClientID = "client"
+				pluginConfig.ConfluenceOAuthClientSecret = "secret"
+			}
+			config.SetConfig(pluginConfig)
+
+			p := &Plugin{}
+			assert.Equal(t, tc.expectedResult, p.checkOAuthCredentials(pluginConfig, server.URL).Result)
+		})
+	}
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	out := formatDiagnostics([]diagnosticCheck{
+		passed("Bot user", "The bot user @confluence exists."),
+		failed("Webhook deliveries", "No webhook request was received yet.", "Check the webhook in Confluence."),
+		skipped("Admin API token", "No API token is configured."),
+	})
+
+	assert.Contains(t, out, "| :white_check_mark: | Bot user | The bot user @confluence exists. |  |")
+	assert.Contains(t, out, "| :x: | Webhook deliveries | No webhook request was received yet. | Check the webhook in Confluence. |")
+	assert.Contains(t, out, "1 of 3 checks failed.")
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
 	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
 	"github.com/mattermost/mattermost-plugin-confluence/server/service"
-	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 )
 
 var editChannelSubscription = &Endpoint{
@@ -31,9 +30,9 @@ func handleEditChannelSubscription(w http.ResponseWriter, r *http.Request, p *Pl
 	var subscription serializer.Subscription
 	var err error
 
-	if !util.IsSystemAdmin(userID) {
-		p.client.Log.Error("Non admin user does not have access to edit subscription for this channel", "UserID", userID, "ChannelID", channelID)
-		http.Error(w, "only system admin can edit a subscription", http.StatusForbidden)
+	if !p.canManageSubscriptions(userID, channelID) {
+		p.client.Log.Error("User does not have permission to edit subscription for this channel", "UserID", userID, "ChannelID", channelID)
+		http.Error(w, "User does not have permission to edit a subscription in this channel", http.StatusForbidden)
 		return
 	}
 
@@ -60,14 +59,18 @@ func handleEditChannelSubscription(w http.ResponseWriter, r *http.Request, p *Pl
 		return
 	}
 
+	if subscriptionChannelID := subscription.GetBaseSubscription().ChannelID; subscriptionChannelID != channelID {
+		p.client.Log.Error("The channel of the subscription does not match the channel of the request", "UserID", userID, "ChannelID", channelID, "SubscriptionChannelID", subscriptionChannelID)
+		http.Error(w, "The channel of the subscription does not match the channel of the request", http.StatusBadRequest)
+		return
+	}
+
 	pluginConfig := config.GetConfig()
-	if pluginConfig.ServerVersionGreaterthan9 {
-		var statusCode int
-		if statusCode, err = p.validateUserConfluenceAccess(userID, pluginConfig.ConfluenceURL, subscriptionType, subscription); err != nil {
-			p.client.Log.Error("Error validating the user's Confluence access", "Error", err.Error())
-			http.Error(w, err.Error(), statusCode) // safe to return the error string directly, as this function ensures all returned errors are user-friendly
-			return
-		}
+	var statusCode int
+	if statusCode, err = p.validateSubscriptionAccess(userID, pluginConfig, subscriptionType, subscription); err != nil {
+		p.client.Log.Error("Error validating the user's Confluence access", "Error", err.Error())
+		http.Error(w, err.Error(), statusCode) // safe to return the error string directly, as this function ensures all returned errors are user-friendly
+		return
 	}
 
 	if err := serializer.ValidateEventsForServerVersion(subscription, pluginConfig.ServerVersionGreaterthan9); err != nil {
@@ -76,12 +79,20 @@ func handleEditChannelSubscription(w http.ResponseWriter, r *http.Request, p *Pl
 		return
 	}
 
+	oldAlias := subscription.GetBaseSubscription().OldAlias
+	if oldAlias == "" {
+		oldAlias = subscription.GetAlias()
+	}
+	oldSubscription, _, _ := service.GetChannelSubscription(channelID, oldAlias)
+
 	if nErr := service.EditSubscription(subscription); nErr != nil {
 		config.Mattermost.LogError("Error occurred while editing subscription", "Subscription Name", subscription.Name(), "error", nErr.Error())
 		http.Error(w, "An error occurred attempting to edit a subscription", http.StatusInternalServerError)
 		return
 	}
 
+	service.RecordAudit(service.NewSubscriptionAuditEntry(service.AuditActionSubscriptionEdited, userID, channelID, oldSubscription, subscription))
+
 	post := &model.Post{
 		UserId:    config.BotUserID,
 		ChannelId: channelID,
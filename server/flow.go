@@ -28,6 +28,9 @@ type FlowManager struct {
 	webhookURL       string
 	setupFlow        *flow.Flow
 	completionFlow   *flow.Flow
+	// webhookFlow completes the setup of Confluence Data Center 9 and above, where the webhook can be registered
+	// automatically.
+	webhookFlow      *flow.Flow
 	announcementFlow *flow.Flow
 }
 
@@ -78,6 +81,20 @@ func (p *Plugin) NewFlowManager() (*FlowManager, error) {
 	)
 	fm.completionFlow = completionFlow
 
+	webhookFlow, err := fm.newFlow("webhook")
+	if err != nil {
+		p.client.Log.Error("Error creating new flow for webhook", "error", err.Error())
+		return nil, err
+	}
+	webhookFlow.WithSteps(
+		fm.stepWebhookRegistration(),
+		fm.stepWebhookRegistered(),
+		fm.stepWebhookInstructions(),
+		fm.stepDone(),
+		fm.stepCancel("completion"),
+	)
+	fm.webhookFlow = webhookFlow
+
 	announcementFlow, err := fm.newFlow("announcement")
 	if err != nil {
 		p.client.Log.Error("Error creating new flow for announcement", "error", err.Error())
@@ -117,6 +134,8 @@ const (
 	stepOAuthInput               flow.Name = "oauth-input"
 	stepCSversionLessthan9       flow.Name = "server-version-less-than-9"
 	stepCSversionGreaterthan9    flow.Name = "server-version-greater-than-9"
+	stepWebhookRegistration      flow.Name = "webhook-registration"
+	stepWebhookRegistered        flow.Name = "webhook-registered"
 	stepWebhookInstructions      flow.Name = "webhook-instruction"
 	stepAnnouncementQuestion     flow.Name = "announcement-question"
 	stepAnnouncementConfirmation flow.Name = "announcement-confirmation"
@@ -126,6 +145,9 @@ const (
 
 	keyConfluenceURL     = "ConfluenceURL"
 	keyIsOAuthConfigured = "IsOAuthConfigured"
+	keyServerVersion     = "ServerVersion"
+	keyWebhookAction     = "WebhookAction"
+	keyWebhookError      = "WebhookError"
 )
 
 func cancelButton() flow.Button {
@@ -181,7 +203,12 @@ func (fm *FlowManager) StartSetupWizard(userID string, delegatedFrom string) err
 func (fm *FlowManager) StartCompletionWizard(userID string) error {
 	state := fm.getBaseState()
 
-	if err := fm.completionFlow.ForUser(userID).Start(state); err != nil {
+	completionFlow := fm.completionFlow
+	if fm.getConfiguration().ServerVersionGreaterthan9 {
+		completionFlow = fm.webhookFlow
+	}
+
+	if err := completionFlow.ForUser(userID).Start(state); err != nil {
 		fm.plugin.client.Log.Error("Error creating setup flow for user", "UserID", userID, "error", err.Error())
 		return err
 	}
@@ -233,7 +260,7 @@ func (fm *FlowManager) stepCSversionGreaterthan9() flow.Step {
 	return flow.NewStep(stepCSversionGreaterthan9).
 		WithText(
 			fmt.Sprintf(
-				"%s has been successfully added. To finish the configuration, add an Application Link in your Confluence instance following these steps:\n",
+				"%s has been successfully added{{ if .ServerVersion }}, it runs Confluence {{ .ServerVersion }}{{ end }}. To finish the configuration, add an Application Link in your Confluence instance following these steps:\n",
 				fm.getConfluenceBaseURL(),
 			) +
 				"1. Go to [**Settings > Applications > Application Links**]({{ .ConfluenceURL }}/plugins/servlet/applinks/listApplicationLinks)\n" +
@@ -250,15 +277,52 @@ func (fm *FlowManager) stepCSversionGreaterthan9() flow.Step {
 		WithButton(continueButton(stepOAuthInput))
 }
 
+func (fm *FlowManager) stepWebhookRegistration() flow.Step {
+	return flow.NewStep(stepWebhookRegistration).
+		WithText("You have successfully connected your Mattermost account to Confluence server. To finish the configuration, a webhook sending the Confluence events to Mattermost is needed. Mattermost can create it for you using your Confluence account.").
+		WithButton(flow.Button{
+			Name:    "Create the webhook",
+			Color:   flow.ColorPrimary,
+			OnClick: fm.registerWebhook,
+		}).
+		WithButton(flow.Button{
+			Name:    "Set it up manually",
+			Color:   flow.ColorDefault,
+			OnClick: flow.Goto(stepWebhookInstructions),
+		})
+}
+
+func (fm *FlowManager) registerWebhook(f *flow.Flow) (flow.Name, flow.State, error) {
+	if !util.IsSystemAdmin(f.UserID) {
+		return stepWebhookInstructions, flow.State{keyWebhookError: "only a system administrator can create the webhook"}, nil
+	}
+
+	action, err := fm.plugin.registerServerWebhookForInstance(fm.getConfluenceBaseURL())
+	if err != nil {
+		fm.client.Log.Warn("Error registering the Confluence webhook", "error", err.Error())
+		return stepWebhookInstructions, flow.State{keyWebhookError: err.Error()}, nil
+	}
+
+	return stepWebhookRegistered, flow.State{keyWebhookAction: action}, nil
+}
+
+func (fm *FlowManager) stepWebhookRegistered() flow.Step {
+	return flow.NewStep(stepWebhookRegistered).
+		WithText("The webhook has been {{ .WebhookAction }} in Confluence, and a test delivery succeeded. Run `/confluence webhook` at any time to check it, and `/confluence webhook repair` to fix it.").
+		Next(stepDone)
+}
+
 func (fm *FlowManager) stepWebhookInstructions() flow.Step {
 	return flow.NewStep(stepWebhookInstructions).
 		WithText(
-			"You have successfully connected your Mattermost account to Confluence server. To finish the configuration, add a Webhook in your Confluence server following these steps:\n" +
+			"{{ if .WebhookError }}The webhook could not be created automatically: {{ .WebhookError }}.\n\n{{ end }}" +
+				"To finish the configuration, add a Webhook in your Confluence server following these steps:\n" +
 				"1. Go to [**Settings > Plugins > Servlet > Webhooks**]({{ .ConfluenceURL }}/plugins/servlet/webhooks/)\n" +
 				"2. Select **Create Webhook**.\n" +
 				"4. On the **Create Webhook** screen, set the following values:\n" +
 				"   - **Name**: `Mattermost Webhook`\n" +
 				fmt.Sprintf("   - **URL**: `%s`\n", fm.webhookURL) +
+				"   - **Secret**: run `/confluence webhook-signing-secret generate` and paste the generated secret, so that Mattermost verifies the signature of each payload\n" +
 				"   - Select all the Events in the list\n" +
 				"   Select **Save**.\n",
 		).
@@ -331,6 +395,16 @@ func (fm *FlowManager) submitConfluenceURL(_ *flow.Flow, submitted map[string]in
 	config.ConfluenceURL = confluenceURL
 	config.Sanitize()
 
+	// The admin is only asked for the server version when it cannot be detected.
+	nextStep := stepServerVersionQuestion
+	if err := detectServerVersion(config); err != nil {
+		fm.client.Log.Info("Unable to detect the Confluence server version", "ConfluenceURL", config.ConfluenceURL, "error", err.Error())
+	} else if config.ServerVersionGreaterthan9 {
+		nextStep = stepCSversionGreaterthan9
+	} else {
+		nextStep = stepCSversionLessthan9
+	}
+
 	configMap, err := config.ToMap()
 	if err != nil {
 		fm.plugin.client.Log.Error("Error converting config to map", "Flow step", stepConfluenceURL, "error", err.Error())
@@ -342,8 +416,9 @@ func (fm *FlowManager) submitConfluenceURL(_ *flow.Flow, submitted map[string]in
 		return "", nil, nil, errors.Wrap(err, "failed to save plugin config")
 	}
 
-	return stepServerVersionQuestion, flow.State{
+	return nextStep, flow.State{
 		keyConfluenceURL: config.GetConfluenceBaseURL(),
+		keyServerVersion: config.ServerVersion,
 	}, nil, nil
 }
 
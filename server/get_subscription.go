@@ -10,7 +10,6 @@ import (
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
 	"github.com/mattermost/mattermost-plugin-confluence/server/service"
 	"github.com/mattermost/mattermost-plugin-confluence/server/store"
-	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 )
 
 var getChannelSubscription = &Endpoint{
@@ -26,9 +25,9 @@ func handleGetChannelSubscription(w http.ResponseWriter, r *http.Request, p *Plu
 	userID := r.Header.Get(config.HeaderMattermostUserID)
 	alias := r.FormValue("alias")
 
-	if !util.IsSystemAdmin(userID) {
-		p.client.Log.Error("Non admin user does not have access to fetch subscription for this channel", "UserID", userID, "ChannelID", channelID)
-		http.Error(w, "only system admin can fetch a subscription", http.StatusForbidden)
+	if !p.canManageSubscriptions(userID, channelID) {
+		p.client.Log.Error("User does not have permission to fetch subscription for this channel", "UserID", userID, "ChannelID", channelID)
+		http.Error(w, "User does not have permission to fetch a subscription in this channel", http.StatusForbidden)
 		return
 	}
 
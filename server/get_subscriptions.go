@@ -8,7 +8,6 @@ import (
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
 	"github.com/mattermost/mattermost-plugin-confluence/server/service"
 	"github.com/mattermost/mattermost-plugin-confluence/server/store"
-	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 
 	"github.com/mattermost/mattermost/server/public/model"
 )
@@ -22,10 +21,11 @@ var autocompleteGetChannelSubscriptions = &Endpoint{
 
 func handleGetChannelSubscriptions(w http.ResponseWriter, r *http.Request, p *Plugin) {
 	mattermostUserID := r.Header.Get(config.HeaderMattermostUserID)
+	channelID := r.FormValue("channel_id")
 
-	if !util.IsSystemAdmin(mattermostUserID) {
-		p.client.Log.Error("Non admin user does not have access to fetch subscription list", "UserID", mattermostUserID)
-		http.Error(w, "only system admin can fetch subscription list", http.StatusForbidden)
+	if !p.canManageSubscriptions(mattermostUserID, channelID) {
+		p.client.Log.Error("User does not have permission to fetch subscription list", "UserID", mattermostUserID, "ChannelID", channelID)
+		http.Error(w, "User does not have permission to fetch the subscription list for this channel", http.StatusForbidden)
 		return
 	}
 
@@ -55,7 +55,6 @@ func handleGetChannelSubscriptions(w http.ResponseWriter, r *http.Request, p *Pl
 		}
 	}
 
-	channelID := r.FormValue("channel_id")
 	if _, err := p.API.GetChannel(channelID); err != nil {
 		p.client.Log.Error("Invalid channel ID. ChannelID: %s. Error: %s", channelID, err.Error())
 		http.Error(w, "Invalid channel ID.", http.StatusBadRequest)
@@ -0,0 +1,274 @@
+// Package httpclient provides the HTTP transport shared by the requests made to Confluence. It limits the number of
+// concurrent requests, retries the requests Confluence could not handle, and stops sending requests to an instance
+// that keeps failing for a while, so that a burst of webhooks does not overload a struggling Confluence.
+package httpclient
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
+)
+
+const (
+	// DefaultTimeout bounds a request to Confluence, including its retries.
+	DefaultTimeout = 30 * time.Second
+
+	defaultMaxConcurrent    = 10
+	defaultMaxRetries       = 3
+	defaultBaseBackoff      = 500 * time.Millisecond
+	defaultMaxBackoff       = 10 * time.Second
+	defaultFailureThreshold = 5
+	defaultOpenDuration     = 30 * time.Second
+
+	// Reasons a request is retried
+	retryRateLimited = "rate_limited"
+	retryServerError = "server_error"
+	retryNetwork     = "network_error"
+)
+
+// ErrCircuitOpen is returned without sending the request when the instance failed too many times in a row.
+var ErrCircuitOpen = errors.New("too many failed requests to Confluence, requests are paused for a while")
+
+var shared = NewTransport(http.DefaultTransport)
+
+// Client returns a client sending its requests through the shared transport.
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: shared, Timeout: timeout}
+}
+
+// Transport is an http.RoundTripper limiting, retrying and guarding the requests sent through Base.
+type Transport struct {
+	Base             http.RoundTripper
+	MaxRetries       int
+	BaseBackoff      time.Duration
+	MaxBackoff       time.Duration
+	FailureThreshold int
+	OpenDuration     time.Duration
+
+	slots chan struct{}
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// circuit tracks the consecutive failures of the requests to a host. Once open, it lets a single request
+// through after OpenDuration to probe whether the host recovered.
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewTransport creates a transport with the default limits.
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		Base:             base,
+		MaxRetries:       defaultMaxRetries,
+		BaseBackoff:      defaultBaseBackoff,
+		MaxBackoff:       defaultMaxBackoff,
+		FailureThreshold: defaultFailureThreshold,
+		OpenDuration:     defaultOpenDuration,
+		slots:            make(chan struct{}, defaultMaxConcurrent),
+		circuits:         map[string]*circuit{},
+		now:              time.Now,
+		sleep:            sleep,
+	}
+}
+
+// RoundTrip sends the request, retrying it when Confluence asks to slow down or is temporarily unavailable.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+
+	for attempt := 0; ; attempt++ {
+		// The slot is taken before asking the circuit, so that a request allowed to probe the host is always
+		// sent and recorded.
+		select {
+		case t.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !t.allow(host) {
+			<-t.slots
+			return nil, errors.Wrap(ErrCircuitOpen, host)
+		}
+		resp, err := t.Base.RoundTrip(req)
+		<-t.slots
+
+		t.record(host, err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests)
+
+		wait, reason := t.retryDelay(req, resp, err, attempt)
+		if reason == "" {
+			return resp, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && t.now().Add(wait).After(deadline) {
+			return resp, err
+		}
+
+		retry := req
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			retry = req.Clone(ctx)
+			retry.Body = body
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		metrics.APIRetries.WithLabelValues(reason).Inc()
+		if err = t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		req = retry
+	}
+}
+
+// retryDelay returns how long to wait before retrying the request, and why it is retried.
+// An empty reason means that the request must not be retried.
+func (t *Transport) retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, string) {
+	if attempt >= t.MaxRetries {
+		return 0, ""
+	}
+
+	if err != nil {
+		if req.Context().Err() != nil || !isIdempotent(req.Method) {
+			return 0, ""
+		}
+		return t.backoff(attempt), retryNetwork
+	}
+
+	reason := ""
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// The request was not processed, it can be retried whatever its method.
+		reason = retryRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !isIdempotent(req.Method) {
+			return 0, ""
+		}
+		reason = retryServerError
+	default:
+		return 0, ""
+	}
+
+	// When Confluence asks to wait longer than MaxBackoff, the response is returned to the caller instead of retrying
+	// sooner than asked. The failure still counts toward opening the circuit.
+	wait, ok := t.retryAfter(resp, attempt)
+	if !ok {
+		return 0, ""
+	}
+	return wait, reason
+}
+
+// retryAfter honors the Retry-After header, given in seconds or as a date. It returns false when the header asks to
+// wait longer than MaxBackoff.
+func (t *Transport) retryAfter(resp *http.Response, attempt int) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return t.backoff(attempt), true
+	}
+
+	wait := time.Duration(-1)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = at.Sub(t.now())
+	}
+
+	switch {
+	case wait < 0:
+		return t.backoff(attempt), true
+	case wait > t.MaxBackoff:
+		return 0, false
+	default:
+		return wait, true
+	}
+}
+
+// backoff doubles the wait after each attempt, with jitter so that concurrent requests do not retry together.
+func (t *Transport) backoff(attempt int) time.Duration {
+	wait := t.BaseBackoff << attempt
+	if wait <= 0 || wait > t.MaxBackoff {
+		wait = t.MaxBackoff
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func (t *Transport) allow(host string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.circuits[host]
+	if c == nil || c.openUntil.IsZero() {
+		return true
+	}
+	if t.now().Before(c.openUntil) || c.probing {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+func (t *Transport) record(host string, success bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.circuits[host]
+	if c == nil {
+		c = &circuit{}
+		t.circuits[host] = c
+	}
+
+	if success {
+		*c = circuit{}
+		return
+	}
+
+	c.failures++
+	c.probing = false
+	if c.failures >= t.FailureThreshold {
+		if c.openUntil.IsZero() {
+			metrics.CircuitOpened.WithLabelValues(host).Inc()
+		}
+		c.openUntil = t.now().Add(t.OpenDuration)
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
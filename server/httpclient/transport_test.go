@@ -0,0 +1,249 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResponse struct {
+	status     int
+	retryAfter string
+	err        error
+}
+
+type fakeRoundTripper struct {
+	mu        sync.Mutex
+	responses []fakeResponse
+	bodies    []string
+}
+
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body := ""
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+	}
+	f.bodies = append(f.bodies, body)
+
+	response := f.responses[0]
+	if len(f.responses) > 1 {
+		f.responses = f.responses[1:]
+	}
+	if response.err != nil {
+		return nil, response.err
+	}
+
+	header := http.Header{}
+	if response.retryAfter != "" {
+		header.Set("Retry-After", response.retryAfter)
+	}
+	return &http.Response{StatusCode: response.status, Header: header, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func newTestTransport(base http.RoundTripper) (*Transport, *[]time.Duration, *time.Time) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var waits []time.Duration
+
+	transport := NewTransport(base)
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		now = now.Add(d)
+		return nil
+	}
+	return transport, &waits, &now
+}
+
+func TestTransportRetries(t *testing.T) {
+	for name, tc := range map[string]struct {
+		method          string
+		responses       []fakeResponse
+		expectedStatus  int
+		expectedWaits   []time.Duration
+		expectedRetries int
+	}{
+		"success": {
+			method:         http.MethodGet,
+			responses:      []fakeResponse{{status: http.StatusOK}},
+			expectedStatus: http.StatusOK,
+		},
+		"rate limited with Retry-After": {
+			method:         http.MethodGet,
+			responses:      []fakeResponse{{status: http.StatusTooManyRequests, retryAfter: "2"}, {status: http.StatusOK}},
+			expectedStatus: http.StatusOK,
+			expectedWaits:  []time.Duration{2 * time.Second},
+		},
+		"server unavailable until the retries run out": {
+			method:          http.MethodGet,
+			responses:       []fakeResponse{{status: http.StatusServiceUnavailable}},
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedRetries: defaultMaxRetries,
+		},
+		"POST not retried on server errors": {
+			method:         http.MethodPost,
+			responses:      []fakeResponse{{status: http.StatusBadGateway}, {status: http.StatusOK}},
+			expectedStatus: http.StatusBadGateway,
+		},
+		"network error retried": {
+			method:          http.MethodGet,
+			responses:       []fakeResponse{{err: errors.New("connection reset")}, {status: http.StatusOK}},
+			expectedStatus:  http.StatusOK,
+			expectedRetries: 1,
+		},
+		"client error not retried": {
+			method:         http.MethodGet,
+			responses:      []fakeResponse{{status: http.StatusNotFound}, {status: http.StatusOK}},
+			expectedStatus: http.StatusNotFound,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := &fakeRoundTripper{responses: tc.responses}
+			transport, waits, _ := newTestTransport(base)
+
+			req, err := http.NewRequest(tc.method, "https://confluence.example.com/rest/api/content", strings.NewReader(`{"title":"page"}`))
+			require.NoError(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+
+			if tc.expectedWaits != nil {
+				assert.Equal(t, tc.expectedWaits, *waits)
+			}
+			if tc.expectedRetries != 0 {
+				assert.Len(t, *waits, tc.expectedRetries)
+			}
+			for _, body := range base.bodies {
+				assert.Equal(t, `{"title":"page"}`, body)
+			}
+		})
+	}
+}
+
+func TestTransportCircuitBreaker(t *testing.T) {
+	base := &fakeRoundTripper{responses: []fakeResponse{{status: http.StatusInternalServerError}}}
+	transport, _, now := newTestTransport(base)
+	transport.MaxRetries = 0
+
+	get := func(host string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, "https://"+host+"/rest/api/content", nil)
+		require.NoError(t, err)
+		return transport.RoundTrip(req)
+	}
+
+	for i := 0; i < defaultFailureThreshold; i++ {
+		resp, err := get("confluence.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	_, err := get("confluence.example.com")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Len(t, base.bodies, defaultFailureThreshold)
+
+	// Other instances are not affected.
+	_, err = get("other.example.com")
+	assert.NoError(t, err)
+
+	// After a while a single request probes the instance, and a success closes the circuit.
+	*now = now.Add(defaultOpenDuration)
+	base.responses = []fakeResponse{{status: http.StatusOK}}
+	resp, err := get("confluence.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = get("confluence.example.com")
+	assert.NoError(t, err)
+}
+
+func TestTransportCircuitBreakerCancelledProbe(t *testing.T) {
+	base := &fakeRoundTripper{responses: []fakeResponse{{status: http.StatusInternalServerError}}}
+	transport, _, now := newTestTransport(base)
+	transport.MaxRetries = 0
+
+	get := func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://confluence.example.com/rest/api/content", nil)
+		require.NoError(t, err)
+		return transport.RoundTrip(req)
+	}
+
+	for i := 0; i < defaultFailureThreshold; i++ {
+		_, err := get(context.Background())
+		require.NoError(t, err)
+	}
+	*now = now.Add(defaultOpenDuration)
+
+	// The probe is cancelled while every slot is taken by requests to other instances.
+	for i := 0; i < cap(transport.slots); i++ {
+		transport.slots <- struct{}{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := get(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	for i := 0; i < cap(transport.slots); i++ {
+		<-transport.slots
+	}
+
+	// The next request still probes the instance, and closes the circuit.
+	base.responses = []fakeResponse{{status: http.StatusOK}}
+	resp, err := get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTransportRetryAfterDeadline(t *testing.T) {
+	base := &fakeRoundTripper{responses: []fakeResponse{{status: http.StatusTooManyRequests, retryAfter: "5"}, {status: http.StatusOK}}}
+	transport, waits, _ := newTestTransport(base)
+	transport.now = time.Now
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://confluence.example.com/rest/api/content", nil)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Empty(t, *waits)
+}
+
+func TestTransportRetryAfterLongerThanMaxBackoff(t *testing.T) {
+	base := &fakeRoundTripper{responses: []fakeResponse{{status: http.StatusTooManyRequests, retryAfter: "120"}, {status: http.StatusOK}}}
+	transport, waits, _ := newTestTransport(base)
+
+	post := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPost, "https://confluence.example.com/rest/api/content", strings.NewReader(`{"title":"page"}`))
+		require.NoError(t, err)
+		return transport.RoundTrip(req)
+	}
+
+	// The response is returned to the caller instead of retrying sooner than asked.
+	resp, err := post()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "120", resp.Header.Get("Retry-After"))
+	assert.Empty(t, *waits)
+	assert.Len(t, base.bodies, 1)
+
+	// The rejected requests count toward opening the circuit.
+	base.responses = []fakeResponse{{status: http.StatusTooManyRequests, retryAfter: "120"}}
+	for i := 1; i < defaultFailureThreshold; i++ {
+		_, err = post()
+		require.NoError(t, err)
+	}
+	_, err = post()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
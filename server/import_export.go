@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	exportAllOnlySystemAdmin = "Only a system administrator can export the subscriptions of all channels."
+	noImportFile             = "Please upload the exported file in this channel before running `/confluence import`."
+	maxImportFileSize        = 5 * 1024 * 1024
+	importFilePostsLookup    = 100
+)
+
+var exportSubscriptions = &Endpoint{
+	Path:            "/subscriptions/export",
+	Method:          http.MethodGet,
+	Execute:         handleExportSubscriptions,
+	IsAuthenticated: true,
+}
+
+var importSubscriptions = &Endpoint{
+	Path:            "/subscriptions/import",
+	Method:          http.MethodPost,
+	Execute:         handleImportSubscriptions,
+	IsAuthenticated: true,
+}
+
+func handleExportSubscriptions(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	channelID := r.FormValue("channel_id")
+	format := r.FormValue("format")
+	if format == "" {
+		format = service.ExportFormatJSON
+	}
+
+	if statusCode, err := p.checkExportPermission(userID, channelID); err != nil {
+		p.client.Log.Error("User does not have permission to export subscriptions", "UserID", userID, "ChannelID", channelID)
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	data, err := p.encodeSubscriptionsExport(channelID, format)
+	if err != nil {
+		p.client.Log.Error("Error exporting subscriptions", "ChannelID", channelID, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFileName(channelID, format, time.Now())))
+	_, _ = w.Write(data)
+}
+
+func handleImportSubscriptions(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	dryRun := r.FormValue("dry_run") == "true"
+	targetChannelID := r.FormValue("channel_id")
+
+	var body io.Reader = r.Body
+	if file, _, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxImportFileSize+1))
+	if err != nil {
+		http.Error(w, "Could not read the request body.", http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxImportFileSize {
+		http.Error(w, "The file is too large to be imported.", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := p.importSubscriptionsForUser(userID, data, targetChannelID, dryRun)
+	if err != nil {
+		p.client.Log.Error("Error importing subscriptions", "UserID", userID, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func executeExport(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	channelID := context.ChannelId
+	format := service.ExportFormatJSON
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--all":
+			channelID = ""
+		case "--format":
+			if i+1 < len(args) {
+				i++
+				format = strings.ToLower(args[i])
+			}
+		default:
+			postCommandResponse(context, fmt.Sprintf("Unexpected argument %q.", args[i]))
+			return &model.CommandResponse{}
+		}
+	}
+
+	if _, err := p.checkExportPermission(context.UserId, channelID); err != nil {
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	data, err := p.encodeSubscriptionsExport(channelID, format)
+	if err != nil {
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	if err := p.sendFileToUser(context.UserId, exportFileName(channelID, format, time.Now()), data, "Here is the export of your Confluence subscriptions."); err != nil {
+		p.client.Log.Error("Error sending the subscriptions export", "UserID", context.UserId, "error", err.Error())
+		postCommandResponse(context, errorExecutingCommand)
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, "The export has been sent to you in a direct message.")
+	return &model.CommandResponse{}
+}
+
+func executeImport(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	dryRun := false
+	targetChannelID := ""
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run":
+			dryRun = true
+		case "--current-channel":
+			targetChannelID = context.ChannelId
+		default:
+			postCommandResponse(context, fmt.Sprintf("Unexpected argument %q.", arg))
+			return &model.CommandResponse{}
+		}
+	}
+
+	data, err := p.getLatestUploadedFile(context.UserId, context.ChannelId)
+	if err != nil {
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	result, err := p.importSubscriptionsForUser(context.UserId, data, targetChannelID, dryRun)
+	if err != nil {
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, formatImportResult(result))
+	return &model.CommandResponse{}
+}
+
+func (p *Plugin) checkExportPermission(userID, channelID string) (int, error) {
+	if channelID == "" {
+		if !util.IsSystemAdmin(userID) {
+			return http.StatusForbidden, errors.New(exportAllOnlySystemAdmin)
+		}
+		return http.StatusOK, nil
+	}
+	if !p.canManageSubscriptions(userID, channelID) {
+		return http.StatusForbidden, errors.New(commandsNoSubscriptionPermission)
+	}
+	return http.StatusOK, nil
+}
+
+func (p *Plugin) encodeSubscriptionsExport(channelID, format string) ([]byte, error) {
+	export, err := service.ExportSubscriptions(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return service.EncodeSubscriptionsExport(export, format)
+}
+
+// importSubscriptionsForUser imports the subscriptions the user is allowed to manage. If targetChannelID is set,
+// every subscription is imported in that channel instead of the channel it was exported from.
+func (p *Plugin) importSubscriptionsForUser(userID string, data []byte, targetChannelID string, dryRun bool) (service.ImportResult, error) {
+	subscriptions, invalid, err := service.DecodeSubscriptionsImport(data)
+	if err != nil {
+		return service.ImportResult{}, err
+	}
+
+	pluginConfig := config.GetConfig()
+	isAdmin := util.IsSystemAdmin(userID)
+	var allowed []serializer.Subscription
+	for _, subscription := range subscriptions {
+		if targetChannelID != "" {
+			subscription = withChannelID(subscription, targetChannelID)
+		}
+
+		base := subscription.GetBaseSubscription()
+		if err := p.checkImportedSubscription(userID, isAdmin, pluginConfig, subscription); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s (channel %s): %s", base.Alias, base.ChannelID, err.Error()))
+			continue
+		}
+		allowed = append(allowed, subscription)
+	}
+
+	result, err := service.ImportSubscriptions(allowed, dryRun)
+	if err != nil {
+		return service.ImportResult{}, err
+	}
+	result.Invalid = append(invalid, result.Invalid...)
+
+	if !dryRun {
+		for _, subscription := range result.Imported {
+			service.RecordAudit(service.NewSubscriptionAuditEntry(service.AuditActionSubscriptionCreated, userID, subscription.GetBaseSubscription().ChannelID, nil, subscription))
+		}
+	}
+
+	return result, nil
+}
+
+func (p *Plugin) checkImportedSubscription(userID string, isAdmin bool, pluginConfig *config.Configuration, subscription serializer.Subscription) error {
+	channelID := subscription.GetBaseSubscription().ChannelID
+	if _, appErr := p.API.GetChannel(channelID); appErr != nil {
+		return errors.New("channel not found")
+	}
+	if !p.canManageSubscriptions(userID, channelID) {
+		return errors.New("no permission to manage subscriptions in this channel")
+	}
+	if err := serializer.ValidateEventsForServerVersion(subscription, pluginConfig.ServerVersionGreaterthan9); err != nil {
+		return err
+	}
+	if !isAdmin {
+		if _, err := p.validateSubscriptionAccess(userID, pluginConfig, subscription.Name(), subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func withChannelID(subscription serializer.Subscription, channelID string) serializer.Subscription {
+	switch sub := subscription.(type) {
+	case serializer.SpaceSubscription:
+		sub.ChannelID = channelID
+		return sub
+	case serializer.PageSubscription:
+		sub.ChannelID = channelID
+		return sub
+	default:
+		return subscription
+	}
+}
+
+// getLatestUploadedFile returns the content of the most recent file the user uploaded in the channel.
+func (p *Plugin) getLatestUploadedFile(userID, channelID string) ([]byte, error) {
+	postList, appErr := p.API.GetPostsForChannel(channelID, 0, importFilePostsLookup)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to read the channel posts")
+	}
+
+	for _, postID := range postList.Order {
+		post := postList.Posts[postID]
+		if post.UserId != userID || len(post.FileIds) == 0 {
+			continue
+		}
+
+		fileID := post.FileIds[0]
+		fileInfo, appErr := p.API.GetFileInfo(fileID)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to read the uploaded file")
+		}
+		if fileInfo.Size > maxImportFileSize {
+			return nil, errors.New("The uploaded file is too large to be imported.")
+		}
+
+		data, appErr := p.API.GetFile(fileID)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to read the uploaded file")
+		}
+		return data, nil
+	}
+
+	return nil, errors.New(noImportFile)
+}
+
+func (p *Plugin) sendFileToUser(userID, fileName string, data []byte, message string) error {
+	channel, appErr := p.API.GetDirectChannel(userID, config.BotUserID)
+	if appErr != nil {
+		return appErr
+	}
+
+	fileInfo, appErr := p.API.UploadFile(data, channel.Id, fileName)
+	if appErr != nil {
+		return appErr
+	}
+
+	_, appErr = p.API.CreatePost(&model.Post{
+		UserId:    config.BotUserID,
+		ChannelId: channel.Id,
+		Message:   message,
+		FileIds:   []string{fileInfo.Id},
+	})
+	if appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+func formatImportResult(result service.ImportResult) string {
+	var sb strings.Builder
+	if result.DryRun {
+		sb.WriteString("**Dry run:** no subscription has been saved.\n\n")
+		fmt.Fprintf(&sb, "%d subscription(s) would be imported.\n", len(result.Imported))
+	} else {
+		fmt.Fprintf(&sb, "%d subscription(s) imported.\n", len(result.Imported))
+	}
+	for _, subscription := range result.Imported {
+		fmt.Fprintf(&sb, "* %s\n", subscription.GetAlias())
+	}
+
+	if len(result.Conflicts) > 0 {
+		fmt.Fprintf(&sb, "\n%d conflict(s):\n", len(result.Conflicts))
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(&sb, "* %s\n", conflict)
+		}
+	}
+
+	if len(result.Invalid) > 0 {
+		fmt.Fprintf(&sb, "\n%d invalid subscription(s):\n", len(result.Invalid))
+		for _, invalid := range result.Invalid {
+			fmt.Fprintf(&sb, "* %s\n", invalid)
+		}
+	}
+
+	return sb.String()
+}
+
+func exportFileName(channelID, format string, now time.Time) string {
+	scope := "all"
+	if channelID != "" {
+		scope = channelID
+	}
+	return fmt.Sprintf("confluence-subscriptions-%s-%s.%s", scope, now.Format("20060102"), format)
+}
+
+func exportContentType(format string) string {
+	if format == service.ExportFormatYAML {
+		return "application/yaml"
+	}
+	return "application/json"
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/httpclient"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+const (
+	PathAccessibleResources = "/oauth/token/accessible-resources"
+)
+
+var (
+	// atlassianAuthURL and atlassianAPIURL are variables so that the tests can point them to a local server.
+	atlassianAuthURL = "https://auth.atlassian.com"
+	atlassianAPIURL  = "https://api.atlassian.com"
+
+	cloudOAuthScopes = []string{
+		"read:confluence-content.all",
+		"read:confluence-content.permission",
+		"read:confluence-space.summary",
+		"read:confluence-user",
+		"offline_access",
+	}
+
+	errCloudRevocationUnsupported = errors.New("Atlassian does not allow apps to revoke the tokens of Confluence Cloud users")
+)
+
+// accessibleResource is a site the user granted the OAuth 2.0 (3LO) app access to.
+type accessibleResource struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (p *Plugin) GetCloudOAuth2Config() (*oauth2.Config, error) {
+	config := config.GetConfig()
+	if config == nil {
+		return nil, errors.New("error getting plugin configurations")
+	}
+
+	return &oauth2.Config{
+		ClientID:     config.ConfluenceCloudOAuthClientID,
+		ClientSecret: config.ConfluenceCloudOAuthClientSecret,
+		RedirectURL:  fmt.Sprintf("%s%s", util.GetPluginURL(), routeUserCompleteCloud),
+		Scopes:       cloudOAuthScopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  fmt.Sprintf("%s/authorize", atlassianAuthURL),
+			TokenURL: fmt.Sprintf("%s/oauth/token", atlassianAuthURL),
+		},
+	}, nil
+}
+
+func (p *Plugin) GetCloudClient(instanceID string, connection *types.Connection) (Client, error) {
+	oconf, err := p.GetCloudOAuth2Config()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.refreshAndStoreToken(connection, instanceID, oconf)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := oconf.Client(confluenceContext(), token)
+	httpClient.Timeout = httpclient.DefaultTimeout
+
+	return newCloudClient(instanceID, connection.CloudID, httpClient), nil
+}
+
+// GetClient returns the client of the Confluence instance the connection was made to.
+func (p *Plugin) GetClient(instanceID string, connection *types.Connection) (Client, error) {
+	if connection.IsCloud() {
+		return p.GetCloudClient(instanceID, connection)
+	}
+	return p.GetServerClient(instanceID, connection)
+}
+
+// getOAuth2Config returns the OAuth2 config the token of the connection was issued with.
+func (p *Plugin) getOAuth2Config(instanceID string, connection *types.Connection) (*oauth2.Config, error) {
+	if connection.IsCloud() {
+		return p.GetCloudOAuth2Config()
+	}
+	return p.GetServerOAuth2Config(instanceID, connection.IsAdmin)
+}
+
+// revokeToken revokes the token of the connection on Confluence.
+func (p *Plugin) revokeToken(instanceID string, connection *types.Connection) error {
+	if connection.IsCloud() {
+		return errCloudRevocationUnsupported
+	}
+	return p.revokeServerToken(instanceID, connection)
+}
+
+// getAccessibleResources returns the sites the token gives access to.
+func getAccessibleResources(httpClient *http.Client) ([]accessibleResource, error) {
+	var resources []accessibleResource
+	if _, _, err := service.CallJSONWithURL(atlassianAPIURL, PathAccessibleResources, http.MethodGet, nil, &resources, httpClient); err != nil {
+		return nil, errors.Wrap(err, "Confluence Cloud getAccessibleResources")
+	}
+	return resources, nil
+}
+
+// findAccessibleResource returns the accessible resource of the site, or an error naming the sites the user
+// granted access to instead.
+func findAccessibleResource(resources []accessibleResource, siteURL string) (*accessibleResource, error) {
+	var sites []string
+	for i := range resources {
+		if strings.EqualFold(strings.TrimRight(resources[i].URL, "/"), siteURL) {
+			return &resources[i], nil
+		}
+		sites = append(sites, resources[i].URL)
+	}
+
+	if len(sites) == 0 {
+		return nil, errors.Errorf("the Confluence account was not granted access to any site, access to %s is required", siteURL)
+	}
+	return nil, errors.Errorf("the Confluence account was granted access to %s, but not to %s", strings.Join(sites, ", "), siteURL)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+func TestFindAccessibleResource(t *testing.T) {
+	resources := []accessibleResource{
+		{ID: "cloud-1", URL: "https://one.atlassian.net", Name: "one"},
+		{ID: "cloud-2", URL: "https://Two.atlassian.net/", Name: "two"},
+	}
+
+	for name, tc := range map[string]struct {
+		resources       []accessibleResource
+		siteURL         string
+		expectedCloudID string
+		expectedError   string
+	}{
+		"site found": {
+			resources:       resources,
+			siteURL:         "https://one.atlassian.net",
+			expectedCloudID: "cloud-1",
+		},
+		"site URL compared without case and trailing slash": {
+			resources:       resources,
+			siteURL:         "https://two.atlassian.net",
+			expectedCloudID: "cloud-2",
+		},
+		"site not granted": {
+			resources:     resources,
+			siteURL:       "https://three.atlassian.net",
+			expectedError: "the Confluence account was granted access to https://one.atlassian.net, https://Two.atlassian.net/, but not to https://three.atlassian.net",
+		},
+		"no site granted": {
+			siteURL:       "https://one.atlassian.net",
+			expectedError: "the Confluence account was not granted access to any site, access to https://one.atlassian.net is required",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource, err := findAccessibleResource(tc.resources, tc.siteURL)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedCloudID, resource.ID)
+		})
+	}
+}
+
+func TestCloudAPIGateway(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case PathAccessibleResources:
+			_, _ = w.Write([]byte(`[{"id":"cloud-1","url":"https://one.atlassian.net","name":"one","scopes":["read:confluence-user"]}]`))
+		case "/ex/confluence/cloud-1" + PathCloudCurrentUser:
+			_, _ = w.Write([]byte(`{"accountId":"5b10ac8d82e05b22cc7d4ef5","displayName":"Jane Doe","publicName":"jane"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	previousAPIURL := atlassianAPIURL
+	atlassianAPIURL = server.URL
+	defer func() { atlassianAPIURL = previousAPIURL }()
+	config.Mattermost = &plugintest.API{}
+
+	resources, err := getAccessibleResources(server.Client())
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "cloud-1", resources[0].ID)
+
+	user, err := newCloudClient("https://one.atlassian.net", resources[0].ID, server.Client()).GetSelf()
+	require.NoError(t, err)
+	assert.Equal(t, &types.ConfluenceUser{AccountID: "5b10ac8d82e05b22cc7d4ef5", Name: "jane", DisplayName: "Jane Doe"}, user)
+}
+
+func TestRevokeCloudToken(t *testing.T) {
+	p := &Plugin{}
+	err := p.revokeToken("https://one.atlassian.net", &types.Connection{CloudID: "cloud-1", OAuth2Token: "token"})
+	assert.Equal(t, errCloudRevocationUnsupported, err)
+}
@@ -3,12 +3,18 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/httpclient"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
 	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
 )
@@ -52,12 +58,65 @@ func (p *Plugin) GetServerClient(instanceID string, connection *types.Connection
 	if err != nil {
 		return nil, err
 	}
-	httpClient := oconf.Client(context.Background(), token)
-	httpClient.Timeout = 10 * time.Second
+	httpClient := oconf.Client(confluenceContext(), token)
+	httpClient.Timeout = httpclient.DefaultTimeout
 
 	return newServerClient(instanceID, httpClient), nil
 }
 
+// revokeServerToken revokes the token of the connection on Confluence. Revoking the refresh token also revokes the
+// access tokens issued with it.
+func (p *Plugin) revokeServerToken(instanceID string, connection *types.Connection) error {
+	token, err := p.ParseAuthToken(connection.OAuth2Token)
+	if err != nil {
+		return err
+	}
+
+	oconf, err := p.GetServerOAuth2Config(instanceID, connection.IsAdmin)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"client_id":     {oconf.ClientID},
+		"client_secret": {oconf.ClientSecret},
+	}
+	if token.RefreshToken != "" {
+		form.Set("token", token.RefreshToken)
+		form.Set("token_type_hint", "refresh_token")
+	} else {
+		form.Set("token", token.AccessToken)
+		form.Set("token_type_hint", "access_token")
+	}
+
+	revokeURL := fmt.Sprintf("%s/rest/oauth2/latest/revoke", instanceID)
+	req, err := http.NewRequest(http.MethodPost, revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	start := time.Now()
+	resp, err := httpclient.Client(httpclient.DefaultTimeout).Do(req)
+	if err != nil {
+		metrics.ObserveAPIRequest(revokeURL, 0, time.Since(start))
+		return errors.Wrap(err, "failed to revoke the token")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	metrics.ObserveAPIRequest(revokeURL, resp.StatusCode, time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Confluence answered the token revocation with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// confluenceContext makes the OAuth2 clients, and the token refreshes, send their requests through the shared transport.
+func confluenceContext() context.Context {
+	return context.WithValue(context.Background(), oauth2.HTTPClient, httpclient.Client(httpclient.DefaultTimeout))
+}
+
 func (p *Plugin) GetRedirectURL() string {
 	return fmt.Sprintf("%s%s", util.GetPluginURL(), routeUserComplete)
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+)
+
+const (
+	releaseHeldNotificationsJobKey      = "release_held_notifications"
+	releaseHeldNotificationsJobInterval = 1 * time.Minute
+
+	reencryptTokensJobKey      = "reencrypt_tokens"
+	reencryptTokensJobInterval = 1 * time.Hour
+)
+
+type backgroundJob struct {
+	key      string
+	interval time.Duration
+	run      func()
+}
+
+func (p *Plugin) backgroundJobs() []backgroundJob {
+	return []backgroundJob{
+		{
+			key:      releaseHeldNotificationsJobKey,
+			interval: releaseHeldNotificationsJobInterval,
+			run: func() {
+				service.ReleaseHeldNotifications(time.Now())
+			},
+		},
+		{
+			key:      reencryptTokensJobKey,
+			interval: reencryptTokensJobInterval,
+			run:      p.reencryptTokensIfNeeded,
+		},
+		{
+			key:      refreshTokensJobKey,
+			interval: refreshTokensJobInterval,
+			run:      p.refreshExpiringTokens,
+		},
+	}
+}
+
+// scheduleJobs starts the background jobs. Jobs are coordinated across the cluster so that only one node runs each of them.
+func (p *Plugin) scheduleJobs() error {
+	for _, backgroundJob := range p.backgroundJobs() {
+		job, err := cluster.Schedule(p.API, backgroundJob.key, cluster.MakeWaitForInterval(backgroundJob.interval), backgroundJob.run)
+		if err != nil {
+			return errors.Wrapf(err, "failed to schedule the %s job", backgroundJob.key)
+		}
+		p.jobs = append(p.jobs, job)
+	}
+
+	return nil
+}
+
+func (p *Plugin) closeJobs() {
+	for _, job := range p.jobs {
+		if err := job.Close(); err != nil {
+			p.client.Log.Warn("Failed to close background job", "error", err.Error())
+		}
+	}
+	p.jobs = nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+const (
+	rotateKeyOnlySystemAdmin    = "`/confluence rotate-key` can only be run by a system administrator."
+	keyRotationLockKey          = "key_rotation_lock"
+	keyRotationLockWait         = 2 * time.Second
+	keyRotationStatusSavePeriod = 50
+)
+
+type connectionKey struct {
+	instanceID       string
+	mattermostUserID string
+}
+
+func executeRotateKey(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, rotateKeyOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	if len(args) > 0 {
+		if args[0] != "status" {
+			postCommandResponse(context, fmt.Sprintf("Unexpected argument %q.", args[0]))
+			return &model.CommandResponse{}
+		}
+		postCommandResponse(context, formatKeyRotationStatus())
+		return &model.CommandResponse{}
+	}
+
+	newKey, err := generateRandomKey(32)
+	if err != nil {
+		p.client.Log.Error("Error generating the encryption key", "error", err.Error())
+		postCommandResponse(context, errorExecutingCommand)
+		return &model.CommandResponse{}
+	}
+
+	// The current key is kept with the previous ones, so that the tokens encrypted with it can still be decrypted
+	// until they are re-encrypted with the new key.
+	configuration := *config.GetConfig()
+	configuration.AddPreviousEncryptionKey(configuration.EncryptionKey)
+	configuration.EncryptionKey = newKey
+	if err = p.savePluginConfig(&configuration); err != nil {
+		p.client.Log.Error("Error saving the encryption key", "error", err.Error())
+		postCommandResponse(context, errorExecutingCommand)
+		return &model.CommandResponse{}
+	}
+
+	go p.reencryptTokens(newKey)
+
+	postCommandResponse(context, fmt.Sprintf("The encryption key has been rotated, its ID is `%s`. Stored tokens are being re-encrypted in the background, use `/confluence rotate-key status` to follow the progress.", encryptionKeyID(newKey)))
+	return &model.CommandResponse{}
+}
+
+// reencryptTokensIfNeeded re-encrypts the stored tokens unless they all have been re-encrypted with the current key.
+func (p *Plugin) reencryptTokensIfNeeded() {
+	key := config.GetConfig().EncryptionKey
+	status, err := store.LoadKeyRotationStatus()
+	if err != nil && errors.Cause(err) != store.ErrNotFound {
+		p.client.Log.Error("Error loading the key rotation status", "error", err.Error())
+		return
+	}
+	if status != nil && status.KeyID == encryptionKeyID(key) && status.FinishedAt != 0 && status.Failed == 0 {
+		return
+	}
+
+	p.reencryptTokens(key)
+}
+
+// reencryptTokens re-encrypts every stored token with the key, saving the progress as it goes.
+// Only one node of the cluster re-encrypts the tokens at a time.
+func (p *Plugin) reencryptTokens(key string) {
+	mutex, err := cluster.NewMutex(p.API, keyRotationLockKey)
+	if err != nil {
+		p.client.Log.Error("Error creating the key rotation lock", "error", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyRotationLockWait)
+	defer cancel()
+	if err = mutex.LockWithContext(ctx); err != nil {
+		p.client.Log.Debug("Tokens are already being re-encrypted")
+		return
+	}
+	defer mutex.Unlock()
+
+	connections, err := p.listConnectionKeys()
+	if err != nil {
+		p.client.Log.Error("Error listing the stored connections", "error", err.Error())
+		return
+	}
+
+	keyID := encryptionKeyID(key)
+	status := &types.KeyRotationStatus{
+		KeyID:     keyID,
+		Total:     len(connections),
+		StartedAt: time.Now().UnixMilli(),
+	}
+	p.storeKeyRotationStatus(status)
+
+	for i, connection := range connections {
+		if err := p.reencryptConnection(connection, key); err != nil {
+			status.Failed++
+			p.client.Log.Warn("Error re-encrypting the token", "UserID", connection.mattermostUserID, "InstanceID", connection.instanceID, "error", err.Error())
+		} else {
+			status.Reencrypted++
+		}
+
+		if (i+1)%keyRotationStatusSavePeriod == 0 {
+			p.storeKeyRotationStatus(status)
+		}
+	}
+
+	status.FinishedAt = time.Now().UnixMilli()
+	p.storeKeyRotationStatus(status)
+	p.client.Log.Info("Finished re-encrypting the stored tokens", "KeyID", keyID, "Reencrypted", status.Reencrypted, "Failed", status.Failed)
+
+	// The previous keys are only kept to decrypt the tokens that were not re-encrypted yet.
+	if status.Failed == 0 {
+		p.removePreviousEncryptionKeys(key)
+	}
+}
+
+// removePreviousEncryptionKeys removes the previous keys from the configuration, unless the key was rotated meanwhile.
+func (p *Plugin) removePreviousEncryptionKeys(key string) {
+	configuration := *config.GetConfig()
+	if configuration.EncryptionKey != key || configuration.PreviousEncryptionKeys == "" {
+		return
+	}
+
+	configuration.PreviousEncryptionKeys = ""
+	if err := p.savePluginConfig(&configuration); err != nil {
+		p.client.Log.Warn("Error removing the previous encryption keys", "error", err.Error())
+	}
+}
+
+func (p *Plugin) storeKeyRotationStatus(status *types.KeyRotationStatus) {
+	if err := store.StoreKeyRotationStatus(status); err != nil {
+		p.client.Log.Warn("Error storing the key rotation status", "error", err.Error())
+	}
+}
+
+// listConnectionKeys returns the instance and user of every stored connection, including the admin connection.
+func (p *Plugin) listConnectionKeys() ([]connectionKey, error) {
+	userIDs, err := store.ListUserIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var connections []connectionKey
+	for _, userID := range userIDs {
+		user, err := store.LoadUser(userID)
+		if err != nil {
+			p.client.Log.Warn("Error loading the user", "UserID", userID, "error", err.Error())
+			continue
+		}
+		if user.InstanceURL != "" {
+			connections = append(connections, connectionKey{instanceID: user.InstanceURL, mattermostUserID: userID})
+		}
+	}
+
+	if instanceURL := config.GetConfig().GetConfluenceBaseURL(); instanceURL != "" {
+		connections = append(connections, connectionKey{instanceID: instanceURL, mattermostUserID: AdminMattermostUserID})
+	}
+
+	return connections, nil
+}
+
+func (p *Plugin) reencryptConnection(key connectionKey, encryptionKey string) error {
+	// The connection is updated with a compare-and-set, so that a token refreshed meanwhile is not overwritten.
+	err := store.ModifyConnection(key.instanceID, key.mattermostUserID, func(connection *types.Connection) (bool, error) {
+		if connection.OAuth2Token == "" || tokenKeyID(connection.OAuth2Token) == encryptionKeyID(encryptionKey) {
+			return false, nil
+		}
+
+		token, err := p.ParseAuthToken(connection.OAuth2Token)
+		if err != nil {
+			return false, err
+		}
+
+		if connection.OAuth2Token, err = p.encodeAuthToken(token, encryptionKey); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if errors.Cause(err) == store.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func formatKeyRotationStatus() string {
+	currentKeyID := encryptionKeyID(config.GetConfig().EncryptionKey)
+	out := fmt.Sprintf("Current encryption key ID: `%s`\n", currentKeyID)
+
+	out += fmt.Sprintf("Previous encryption keys kept for decryption: %d\n", len(config.GetConfig().GetPreviousEncryptionKeys()))
+
+	status, err := store.LoadKeyRotationStatus()
+	if err != nil {
+		return out + "No token re-encryption has run yet."
+	}
+
+	out += fmt.Sprintf("Last re-encryption with key `%s`, started at %s: %d of %d tokens processed, %d failed.",
+		status.KeyID, time.UnixMilli(status.StartedAt).UTC().Format(time.RFC1123), status.Reencrypted+status.Failed, status.Total, status.Failed)
+	if status.FinishedAt == 0 {
+		out += " In progress."
+	} else {
+		out += fmt.Sprintf(" Finished at %s.", time.UnixMilli(status.FinishedAt).UTC().Format(time.RFC1123))
+	}
+	if status.KeyID != currentKeyID {
+		out += "\nThe encryption key changed since, tokens will be re-encrypted with the current key shortly."
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+func TestReencryptConnectionConcurrentRefresh(t *testing.T) {
+	oldKey := "0123456789abcdef0123456789abcdef"
+	newKey := "fedcba9876543210fedcba9876543210"
+
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	config.SetConfig(&config.Configuration{EncryptionKey: newKey, PreviousEncryptionKeys: oldKey})
+	p := &Plugin{}
+	p.SetAPI(mockAPI)
+	p.client = pluginapi.NewClient(mockAPI, nil)
+
+	connection := func(accessToken string) []byte {
+		encoded, err := p.encodeAuthToken(&oauth2.Token{AccessToken: accessToken, RefreshToken: "refresh"}, oldKey)
+		require.NoError(t, err)
+		data, _ := json.Marshal(&types.Connection{OAuth2Token: encoded, MattermostUserID: "user-id"})
+		return data
+	}
+	loaded, refreshed := connection("access"), connection("refreshed")
+
+	// The token is refreshed between the load of the connection and its update.
+	key := "https://confluence.example.com_user-id"
+	mockAPI.On("KVGet", key).Return(loaded, nil).Once()
+	mockAPI.On("KVGet", key).Return(refreshed, nil)
+	mockAPI.On("KVCompareAndSet", key, loaded, mock.Anything).Return(false, nil).Once()
+	var stored []byte
+	mockAPI.On("KVCompareAndSet", key, refreshed, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil).Once()
+
+	require.NoError(t, p.reencryptConnection(connectionKey{instanceID: "https://confluence.example.com", mattermostUserID: "user-id"}, newKey))
+	mockAPI.AssertExpectations(t)
+
+	var reencrypted types.Connection
+	require.NoError(t, json.Unmarshal(stored, &reencrypted))
+	assert.Equal(t, encryptionKeyID(newKey), tokenKeyID(reencrypted.OAuth2Token))
+	token, err := p.ParseAuthToken(reencrypted.OAuth2Token)
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed", token.AccessToken)
+}
+
+func TestRemovePreviousEncryptionKeys(t *testing.T) {
+	oldKey := "0123456789abcdef0123456789abcdef"
+	newKey := "fedcba9876543210fedcba9876543210"
+
+	for name, tc := range map[string]struct {
+		reencryptedKey string
+		expectSave     bool
+	}{
+		"tokens re-encrypted with the current key": {
+			reencryptedKey: newKey,
+			expectSave:     true,
+		},
+		"key rotated during the re-encryption": {
+			reencryptedKey: oldKey,
+			expectSave:     false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			config.SetConfig(&config.Configuration{EncryptionKey: newKey, PreviousEncryptionKeys: oldKey})
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			if tc.expectSave {
+				mockAPI.On("SavePluginConfig", mock.MatchedBy(func(configMap map[string]interface{}) bool {
+					return configMap["encryptionkey"] == newKey && configMap["previousencryptionkeys"] == ""
+				})).Return(nil).Once()
+			}
+
+			p.removePreviousEncryptionKeys(tc.reencryptedKey)
+			mockAPI.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/cache"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const cacheOnlySystemAdmin = "`/confluence cache` can only be run by a system administrator."
+
+// The lookups made for every webhook of a Confluence Data Center instance, which rarely change.
+var (
+	spaceKeyCache      = cache.New[string]("space_keys", 24*time.Hour, 1000)
+	spaceCache         = cache.New[SpaceResponse]("spaces", time.Hour, 500)
+	userCache          = cache.New[ConfluenceUser]("users", time.Hour, 1000)
+	pageAncestorsCache = cache.New[[]PageAncestor]("page_ancestors", time.Hour, 1000)
+)
+
+func lookupCacheKey(instanceID, key string) string {
+	return instanceID + "/" + key
+}
+
+// cachedSpace returns a copy of the cached space, so that the callers can modify it.
+func cachedSpace(instanceID, spaceKey string, load func() (*SpaceResponse, error)) (*SpaceResponse, error) {
+	space, err := spaceCache.GetOrLoad(lookupCacheKey(instanceID, spaceKey), func() (SpaceResponse, error) {
+		space, err := load()
+		if err != nil {
+			return SpaceResponse{}, err
+		}
+		if space == nil {
+			return SpaceResponse{}, errors.New("no space found")
+		}
+		return *space, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &space, nil
+}
+
+// cachedUser returns a copy of the cached user, so that the callers can modify it.
+func cachedUser(instanceID, userKey string, load func() (*ConfluenceUser, error)) (*ConfluenceUser, error) {
+	user, err := userCache.GetOrLoad(lookupCacheKey(instanceID, userKey), func() (ConfluenceUser, error) {
+		user, err := load()
+		if err != nil {
+			return ConfluenceUser{}, err
+		}
+		if user == nil {
+			return ConfluenceUser{}, errors.New("no user found")
+		}
+		return *user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// invalidateLookupCaches drops the cached lookups changed by the event. It returns whether the event is only used
+// to invalidate the caches, and must not be sent to the channels.
+func invalidateLookupCaches(instanceID string, event *serializer.ConfluenceServerWebhookPayload) bool {
+	switch event.Event {
+	case serializer.SpaceCreatedEvent, serializer.SpaceUpdatedEvent, serializer.SpaceRemovedEvent, serializer.SpacePermissionsUpdatedEvent:
+		// The key of a space never changes, it is kept so that the removal of the space can still be notified.
+		spaceKey := event.Space.SpaceKey
+		if spaceKey == "" && event.Space.ID != 0 {
+			spaceKey, _ = spaceKeyCache.Get(lookupCacheKey(instanceID, strconv.FormatInt(event.Space.ID, 10)))
+		}
+		if spaceKey != "" {
+			spaceCache.Invalidate(lookupCacheKey(instanceID, spaceKey))
+		}
+		return false
+	case serializer.UserRemovedEvent, serializer.UserDeactivatedEvent, serializer.UserReactivatedEvent:
+		userKey := event.User.UserKey
+		if userKey == "" {
+			userKey = event.UserKey
+		}
+		if userKey != "" {
+			userCache.Invalidate(lookupCacheKey(instanceID, userKey))
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func executeCache(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, cacheOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, formatCacheStats(cache.AllStats()))
+	return &model.CommandResponse{}
+}
+
+func formatCacheStats(stats []cache.Stats) string {
+	var sb strings.Builder
+	sb.WriteString("#### Confluence API caches\n")
+	sb.WriteString("The counts are kept since the plugin started on this server.\n\n")
+	sb.WriteString("| Cache | Memory hits | KV store hits | Misses | Hit rate |\n")
+	sb.WriteString("| :---- | ----------: | ------------: | -----: | -------: |\n")
+	for _, s := range stats {
+		fmt.Fprintf(&sb, "| %s | %d | %d | %d | %.1f%% |\n", s.Name, s.MemoryHits, s.KVHits, s.Misses, s.HitRate()*100)
+	}
+	return sb.String()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func TestCachedLookupsWithoutResult(t *testing.T) {
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+
+	space, err := cachedSpace(t.Name(), "DEV", func() (*SpaceResponse, error) { return nil, nil })
+	assert.Error(t, err)
+	assert.Nil(t, space)
+
+	user, err := cachedUser(t.Name(), "jane", func() (*ConfluenceUser, error) { return nil, nil })
+	assert.Error(t, err)
+	assert.Nil(t, user)
+
+	mockAPI.AssertNotCalled(t, "KVSetWithExpiry", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCheckSpaceAccessNotCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer allowed" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1,"key":"DEV","name":"Development"}`))
+	}))
+	defer server.Close()
+
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	mockAPI.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+	clientFor := func(token string) Client {
+		return newServerClient(server.URL, oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	}
+
+	space, err := clientFor("allowed").GetSpaceData("DEV")
+	require.NoError(t, err)
+	assert.Equal(t, "Development", space.Name)
+
+	assert.NoError(t, clientFor("allowed").CheckSpaceAccess("DEV"))
+	assert.Error(t, clientFor("denied").CheckSpaceAccess("DEV"))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+// getMetrics exposes the plugin metrics in the Prometheus text format. Monitoring systems can scrape it with
+// the personal access token of a system administrator.
+var getMetrics = &Endpoint{
+	Path:            "/metrics",
+	Method:          http.MethodGet,
+	Execute:         handleGetMetrics,
+	IsAuthenticated: true,
+}
+
+func handleGetMetrics(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	if !util.IsSystemAdmin(userID) {
+		p.client.Log.Error("Non admin user does not have access to the metrics", "UserID", userID)
+		http.Error(w, "only system admin can read the metrics", http.StatusForbidden)
+		return
+	}
+
+	metrics.Handler().ServeHTTP(w, r)
+}
@@ -0,0 +1,96 @@
+// Package metrics counts the webhook and Confluence REST API traffic of the plugin, and exposes it in the
+// Prometheus text format. The values are kept in memory, they are reset when the plugin restarts and each
+// server of a cluster reports its own traffic.
+package metrics
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Results of a token refresh
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Stages at which a webhook delivery can fail
+const (
+	StagePayload       = "payload"
+	StageEventData     = "event_data"
+	StageSubscriptions = "subscriptions"
+	StagePost          = "post"
+	StageHold          = "hold"
+)
+
+var apiLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// registry only holds the metrics of the plugin, the Mattermost server exposes its own.
+var registry = prometheus.NewRegistry()
+
+var (
+	factory = promauto.With(registry)
+
+	WebhooksReceived    = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_webhooks_received_total", Help: "Webhook requests received from Confluence."}, []string{"source", "event"})
+	NotificationsPosted = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_notifications_posted_total", Help: "Notifications posted in Mattermost channels."}, []string{"event"})
+	DeliveryFailures    = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_delivery_failures_total", Help: "Failures to turn a webhook delivery into notifications."}, []string{"stage"})
+	APIRequests         = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_api_requests_total", Help: "Requests made to the Confluence REST API."}, []string{"endpoint", "status"})
+	APIRequestDuration  = factory.NewHistogramVec(prometheus.HistogramOpts{Name: "confluence_api_request_duration_seconds", Help: "Latency of the Confluence REST API.", Buckets: apiLatencyBuckets}, []string{"endpoint"})
+	APIRetries          = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_api_retries_total", Help: "Requests to the Confluence REST API retried."}, []string{"reason"})
+	CircuitOpened       = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_api_circuit_opened_total", Help: "Times the requests to a Confluence instance were paused after repeated failures."}, []string{"host"})
+	CacheRequests       = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_cache_requests_total", Help: "Lookups of the Confluence API caches."}, []string{"cache", "result"})
+	TokenRefreshes      = factory.NewCounterVec(prometheus.CounterOpts{Name: "confluence_token_refreshes_total", Help: "OAuth token refreshes."}, []string{"result"})
+	AtomicModifyRetries = factory.NewCounter(prometheus.CounterOpts{Name: "confluence_kv_atomic_modify_retries_total", Help: "KV store writes retried after a concurrent modification."})
+)
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveAPIRequest records a request to the Confluence REST API. A zero status code means that no response was received.
+func ObserveAPIRequest(requestURL string, statusCode int, duration time.Duration) {
+	endpoint := EndpointLabel(requestURL)
+	status := "error"
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+
+	APIRequests.WithLabelValues(endpoint, status).Inc()
+	APIRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+var endpointSegment = regexp.MustCompile(`^[a-z][a-z0-9.-]*$`)
+
+// EndpointLabel returns the path of the request without its query, and with the segments identifying content,
+// spaces or users replaced, so that the number of label values stays small.
+func EndpointLabel(requestURL string) string {
+	path := requestURL
+	if u, err := url.Parse(requestURL); err == nil {
+		path = u.Path
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	// The segments up to the resource name, e.g. rest/api/content, are kept. Confluence may be served under a context path.
+	prefixLength := 3
+	for i, segment := range segments {
+		if segment == "rest" {
+			prefixLength += i
+			break
+		}
+	}
+	for i, segment := range segments {
+		if i >= prefixLength && (!endpointSegment.MatchString(segment) || strings.ContainsAny(segment, "0123456789")) {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
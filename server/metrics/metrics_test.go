@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	WebhooksReceived.WithLabelValues("test", `quote"d`).Inc()
+	WebhooksReceived.WithLabelValues("test", `quote"d`).Inc()
+	ObserveAPIRequest("https://confluence.example.com/rest/api/test/123", 200, 50*time.Millisecond)
+	ObserveAPIRequest("https://confluence.example.com/rest/api/test/456", 0, 2*time.Second)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE confluence_webhooks_received_total counter",
+		`confluence_webhooks_received_total{event="quote\"d",source="test"} 2`,
+		`confluence_api_requests_total{endpoint="/rest/api/test/{id}",status="200"} 1`,
+		`confluence_api_requests_total{endpoint="/rest/api/test/{id}",status="error"} 1`,
+		"# TYPE confluence_api_request_duration_seconds histogram",
+		`confluence_api_request_duration_seconds_bucket{endpoint="/rest/api/test/{id}",le="0.05"} 1`,
+		`confluence_api_request_duration_seconds_bucket{endpoint="/rest/api/test/{id}",le="1"} 1`,
+		`confluence_api_request_duration_seconds_bucket{endpoint="/rest/api/test/{id}",le="+Inf"} 2`,
+		`confluence_api_request_duration_seconds_sum{endpoint="/rest/api/test/{id}"} 2.05`,
+		`confluence_api_request_duration_seconds_count{endpoint="/rest/api/test/{id}"} 2`,
+		"confluence_kv_atomic_modify_retries_total 0",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
+
+func TestMetricsLint(t *testing.T) {
+	problems, err := testutil.GatherAndLint(registry)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestEndpointLabel(t *testing.T) {
+	for name, tc := range map[string]struct {
+		url      string
+		expected string
+	}{
+		"content ID": {
+			url:      "https://confluence.example.com/rest/api/content/123?expand=body.view",
+			expected: "/rest/api/content/{id}",
+		},
+		"space key": {
+			url:      "https://confluence.example.com/rest/api/space/DEV?status=any",
+			expected: "/rest/api/space/{id}",
+		},
+		"named resource": {
+			url:      "https://confluence.example.com/rest/api/user/current",
+			expected: "/rest/api/user/current",
+		},
+		"context path": {
+			url:      "https://example.com/confluence/rest/api/content/123",
+			expected: "/confluence/rest/api/content/{id}",
+		},
+		"webhook test": {
+			url:      "https://confluence.example.com/rest/api/webhooks/test?url=https%3A%2F%2Fmattermost.example.com",
+			expected: "/rest/api/webhooks/test",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, EndpointLabel(tc.url))
+		})
+	}
+}
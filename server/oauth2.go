@@ -5,6 +5,8 @@ import "net/http"
 const (
 	routeUserConnect        = "/oauth2/connect"
 	routeUserComplete       = "/oauth2/complete.html"
+	routeUserConnectCloud   = "/oauth2/cloud/connect"
+	routeUserCompleteCloud  = "/oauth2/cloud/complete.html"
 	routeUserConnectionInfo = "/user-connection-info"
 )
 
@@ -22,6 +24,20 @@ var userConnectComplete = &Endpoint{
 	IsAuthenticated: true,
 }
 
+var userConnectCloud = &Endpoint{
+	Path:            routeUserConnectCloud,
+	Method:          http.MethodGet,
+	Execute:         httpOAuth2ConnectCloud,
+	IsAuthenticated: true,
+}
+
+var userConnectCompleteCloud = &Endpoint{
+	Path:            routeUserCompleteCloud,
+	Method:          http.MethodGet,
+	Execute:         httpOAuth2CompleteCloud,
+	IsAuthenticated: true,
+}
+
 var userConnectionInfo = &Endpoint{
 	Path:            routeUserConnectionInfo,
 	Method:          http.MethodGet,
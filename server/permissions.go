@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	commandsNoSubscriptionPermission = "You do not have permission to manage Confluence subscriptions in this channel."
+	subscriptionOnlySystemAdminV8    = "Only system administrators can subscribe to Confluence Server 8 and below, the permissions of the user cannot be checked on Confluence."
+)
+
+// canManageSubscriptions checks if the user is allowed to create, edit or delete subscriptions in the channel,
+// based on the roles and teams allowed in the plugin configuration. System admins can always manage subscriptions.
+func (p *Plugin) canManageSubscriptions(userID, channelID string) bool {
+	if util.IsSystemAdmin(userID) {
+		return true
+	}
+
+	pluginConfig := config.GetConfig()
+	role := pluginConfig.RolesAllowedToManageSubscriptions
+	if role == "" || role == config.SubscriptionRoleSystemAdmin {
+		return false
+	}
+
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		p.client.Log.Error("Unable to get the channel", "ChannelID", channelID, "Error", appErr.Error())
+		return false
+	}
+
+	// Direct and group messages do not belong to a team.
+	if channel.TeamId == "" {
+		return false
+	}
+
+	if allowedTeams := pluginConfig.GetTeamsAllowedToManageSubscriptions(); len(allowedTeams) > 0 {
+		team, tErr := p.API.GetTeam(channel.TeamId)
+		if tErr != nil {
+			p.client.Log.Error("Unable to get the team", "TeamID", channel.TeamId, "Error", tErr.Error())
+			return false
+		}
+		if !slices.Contains(allowedTeams, team.Name) {
+			return false
+		}
+	}
+
+	channelMember, appErr := p.API.GetChannelMember(channelID, userID)
+	if appErr != nil {
+		return false
+	}
+
+	switch role {
+	case config.SubscriptionRoleUsers:
+		return true
+	case config.SubscriptionRoleChannelAdmin:
+		if channelMember.SchemeAdmin || hasRole(channelMember.Roles, model.ChannelAdminRoleId) {
+			return true
+		}
+		return p.isTeamAdmin(userID, channel.TeamId)
+	case config.SubscriptionRoleTeamAdmin:
+		return p.isTeamAdmin(userID, channel.TeamId)
+	default:
+		return false
+	}
+}
+
+func (p *Plugin) isTeamAdmin(userID, teamID string) bool {
+	teamMember, appErr := p.API.GetTeamMember(teamID, userID)
+	if appErr != nil {
+		return false
+	}
+	return teamMember.SchemeAdmin || hasRole(teamMember.Roles, model.TeamAdminRoleId)
+}
+
+func hasRole(roles, role string) bool {
+	return slices.Contains(strings.Fields(roles), role)
+}
+
+// validateSubscriptionAccess checks that the user can see the space or page of the subscription on Confluence.
+// Confluence Server 8 and below offers no way to check the permissions of the user, so only system admins can
+// subscribe there.
+func (p *Plugin) validateSubscriptionAccess(userID string, pluginConfig *config.Configuration, subscriptionType string, subscription serializer.Subscription) (int, error) {
+	if pluginConfig.ServerVersionGreaterthan9 {
+		return p.validateUserConfluenceAccess(userID, pluginConfig.ConfluenceURL, subscriptionType, subscription)
+	}
+	if !util.IsSystemAdmin(userID) {
+		return http.StatusForbidden, errors.New(subscriptionOnlySystemAdminV8)
+	}
+	return http.StatusOK, nil
+}
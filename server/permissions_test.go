@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func TestCanManageSubscriptions(t *testing.T) {
+	for name, val := range map[string]struct {
+		role          string
+		teams         string
+		userRoles     string
+		channelAdmin  bool
+		teamAdmin     bool
+		channelTeamID string
+		isAllowed     bool
+	}{
+		"system admin is always allowed": {
+			role:      config.SubscriptionRoleSystemAdmin,
+			userRoles: "system_user system_admin",
+			isAllowed: true,
+		},
+		"default only allows system admins": {
+			channelAdmin: true,
+		},
+		"channel admin allowed": {
+			role:         config.SubscriptionRoleChannelAdmin,
+			channelAdmin: true,
+			isAllowed:    true,
+		},
+		"team admin allowed as channel admin": {
+			role:      config.SubscriptionRoleChannelAdmin,
+			teamAdmin: true,
+			isAllowed: true,
+		},
+		"channel member not allowed as channel admin": {
+			role: config.SubscriptionRoleChannelAdmin,
+		},
+		"channel admin not allowed as team admin": {
+			role:         config.SubscriptionRoleTeamAdmin,
+			channelAdmin: true,
+		},
+		"any channel member allowed": {
+			role:      config.SubscriptionRoleUsers,
+			isAllowed: true,
+		},
+		"team in allowed list": {
+			role:      config.SubscriptionRoleUsers,
+			teams:     "engineering, sales",
+			isAllowed: true,
+		},
+		"team not in allowed list": {
+			role:  config.SubscriptionRoleUsers,
+			teams: "sales",
+		},
+		"direct message channel": {
+			role:          config.SubscriptionRoleUsers,
+			channelTeamID: "-",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := baseMock()
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+
+			config.SetConfig(&config.Configuration{
+				RolesAllowedToManageSubscriptions: val.role,
+				TeamsAllowedToManageSubscriptions: val.teams,
+			})
+
+			userRoles := val.userRoles
+			if userRoles == "" {
+				userRoles = "system_user"
+			}
+			channelRoles := "channel_user"
+			if val.channelAdmin {
+				channelRoles += " channel_admin"
+			}
+			teamRoles := "team_user"
+			if val.teamAdmin {
+				teamRoles += " team_admin"
+			}
+			teamID := "team1"
+			if val.channelTeamID == "-" {
+				teamID = ""
+			}
+
+			mockAPI.On("GetUser", "user1").Return(&model.User{Id: "user1", Roles: userRoles}, nil)
+			mockAPI.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: teamID}, nil)
+			mockAPI.On("GetTeam", "team1").Return(&model.Team{Id: "team1", Name: "engineering"}, nil)
+			mockAPI.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{Roles: channelRoles}, nil)
+			mockAPI.On("GetTeamMember", "team1", "user1").Return(&model.TeamMember{Roles: teamRoles}, nil)
+			mockAPI.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			assert.Equal(t, val.isAllowed, p.canManageSubscriptions("user1", "channel1"))
+		})
+	}
+}
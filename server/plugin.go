@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -15,8 +16,12 @@ import (
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 
+	"github.com/mattermost/mattermost-plugin-confluence/server/cache"
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
 	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 )
 
@@ -40,11 +45,15 @@ type Plugin struct {
 
 	// templates are loaded on startup
 	templates map[string]*template.Template
+
+	// jobs are the background jobs scheduled on activation
+	jobs []*cluster.Job
 }
 
 func (p *Plugin) OnActivate() error {
 	config.Mattermost = p.API
 	p.client = pluginapi.NewClient(p.API, p.Driver)
+	store.OnAtomicModifyRetry = metrics.AtomicModifyRetries.Inc
 
 	if err := p.setUpBotUser(); err != nil {
 		config.Mattermost.LogError("Failed to create a bot user", "Error", err.Error())
@@ -83,9 +92,32 @@ func (p *Plugin) OnActivate() error {
 		return err
 	}
 
+	if err := p.scheduleJobs(); err != nil {
+		return err
+	}
+
+	go p.refreshServerVersion()
+
 	return nil
 }
 
+func (p *Plugin) OnDeactivate() error {
+	p.closeJobs()
+	return nil
+}
+
+// OnPluginClusterEvent receives the events sent by the plugin on the other servers of the cluster.
+func (p *Plugin) OnPluginClusterEvent(_ *plugin.Context, event model.PluginClusterEvent) {
+	if event.Id == cache.ClusterEventInvalidate {
+		cache.HandleClusterEvent(event)
+	}
+}
+
+// UserHasBeenDeactivated disconnects the deactivated user from Confluence.
+func (p *Plugin) UserHasBeenDeactivated(_ *plugin.Context, user *model.User) {
+	p.disconnectDeactivatedUser(user.Id)
+}
+
 func (p *Plugin) OnConfigurationChange() error {
 	// If OnActivate has not been run yet.
 	if config.Mattermost == nil {
@@ -123,7 +155,25 @@ func (p *Plugin) OnConfigurationChange() error {
 		return err
 	}
 
+	// Keep the previous encryption key when it is regenerated, so that the tokens encrypted with it can still be
+	// read until they are re-encrypted with the new key.
+	if previous := config.GetConfigIfSet(); previous != nil && previous.EncryptionKey != configuration.EncryptionKey {
+		previousKeys := configuration.PreviousEncryptionKeys
+		configuration.AddPreviousEncryptionKey(previous.EncryptionKey)
+		if configuration.PreviousEncryptionKeys != previousKeys {
+			if err := p.savePluginConfig(&configuration); err != nil {
+				config.Mattermost.LogError("Error saving the previous encryption key.", "Error", err.Error())
+			}
+		}
+	}
+
 	config.SetConfig(&configuration)
+
+	// Keep the previous webhook secret, so that it is still accepted during the grace period.
+	if err := recordWebhookSecret(configuration.Secret, time.Now()); err != nil {
+		config.Mattermost.LogError("Error recording the webhook secret.", "Error", err.Error())
+	}
+
 	return nil
 }
 
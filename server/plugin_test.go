@@ -105,6 +105,7 @@ func TestOnConfigurationChange_AutoGenerateEncryptionKey(t *testing.T) {
 
 		mockAPI.On("LogInfo", "Auto-generated missing Encryption Key.").Return()
 		mockAPI.On("SavePluginConfig", mock.AnythingOfType("map[string]interface {}")).Return(nil)
+		mockWebhookSecretState(mockAPI)
 
 		err := p.OnConfigurationChange()
 		require.NoError(t, err)
@@ -121,12 +122,14 @@ func TestOnConfigurationChange_AutoGenerateEncryptionKey(t *testing.T) {
 		p.SetAPI(mockAPI)
 
 		existingKey := "abcdefghijklmnopqrstuvwxyz123456" // 32 chars
+		config.SetConfig(&config.Configuration{EncryptionKey: existingKey})
 
 		mockAPI.On("LoadPluginConfiguration", mock.AnythingOfType("*config.Configuration")).Run(func(args mock.Arguments) {
 			cfg := args.Get(0).(*config.Configuration)
 			cfg.Secret = "12345678901234567890123456789012"
 			cfg.EncryptionKey = existingKey
 		}).Return(nil)
+		mockWebhookSecretState(mockAPI)
 
 		err := p.OnConfigurationChange()
 		require.NoError(t, err)
@@ -134,4 +137,38 @@ func TestOnConfigurationChange_AutoGenerateEncryptionKey(t *testing.T) {
 		// SavePluginConfig should NOT be called since key is valid
 		mockAPI.AssertNotCalled(t, "SavePluginConfig", mock.Anything)
 	})
+
+	t.Run("keeps the previous encryption key when it is regenerated", func(t *testing.T) {
+		mockAPI := &plugintest.API{}
+		config.Mattermost = mockAPI
+
+		p := &Plugin{}
+		p.SetAPI(mockAPI)
+
+		previousKey := "abcdefghijklmnopqrstuvwxyz123456"
+		newKey := "654321zyxwvutsrqponmlkjihgfedcba"
+		config.SetConfig(&config.Configuration{EncryptionKey: previousKey})
+
+		mockAPI.On("LoadPluginConfiguration", mock.AnythingOfType("*config.Configuration")).Run(func(args mock.Arguments) {
+			cfg := args.Get(0).(*config.Configuration)
+			cfg.Secret = "12345678901234567890123456789012"
+			cfg.EncryptionKey = newKey
+		}).Return(nil)
+		mockAPI.On("SavePluginConfig", mock.MatchedBy(func(configMap map[string]interface{}) bool {
+			return configMap["encryptionkey"] == newKey && configMap["previousencryptionkeys"] == previousKey
+		})).Return(nil).Once()
+		mockWebhookSecretState(mockAPI)
+
+		err := p.OnConfigurationChange()
+		require.NoError(t, err)
+
+		mockAPI.AssertExpectations(t)
+		assert.Equal(t, []string{previousKey}, config.GetConfig().GetPreviousEncryptionKeys())
+	})
+}
+
+// mockWebhookSecretState mocks the KV calls made to record the webhook secret.
+func mockWebhookSecretState(mockAPI *plugintest.API) {
+	mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	mockAPI.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(true, nil)
 }
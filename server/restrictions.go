@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+// restrictionReader reads the view restrictions of Confluence content.
+type restrictionReader interface {
+	GetPageAncestors(int) ([]PageAncestor, error)
+	HasReadRestrictions(string) (bool, error)
+}
+
+// pageRestrictionCheck returns a check of the view restrictions of the page, or nil without a reader. The ancestors
+// of the page are checked as well, since their restrictions apply to all of their descendants.
+func pageRestrictionCheck(reader restrictionReader, pageID string) func() (bool, error) {
+	if reader == nil {
+		return nil
+	}
+	return func() (bool, error) {
+		id, err := strconv.Atoi(pageID)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid page ID %q", pageID)
+		}
+
+		restricted, err := reader.HasReadRestrictions(pageID)
+		if err != nil || restricted {
+			return restricted, err
+		}
+
+		ancestors, err := reader.GetPageAncestors(id)
+		if err != nil {
+			return false, err
+		}
+		for _, ancestor := range ancestors {
+			if restricted, err = reader.HasReadRestrictions(ancestor.ID); err != nil || restricted {
+				return restricted, err
+			}
+		}
+
+		return false, nil
+	}
+}
+
+// apiTokenRestrictionReader reads the view restrictions of Confluence Data Center content with the admin API token.
+type apiTokenRestrictionReader struct {
+	p       *Plugin
+	baseURL string
+}
+
+// getAPITokenRestrictionReader returns a reader using the admin API token, or nil when no token is configured.
+func (p *Plugin) getAPITokenRestrictionReader(pluginConfig *config.Configuration) restrictionReader {
+	if pluginConfig.AdminAPIToken == "" {
+		return nil
+	}
+	return &apiTokenRestrictionReader{p: p, baseURL: pluginConfig.ConfluenceURL}
+}
+
+func (r *apiTokenRestrictionReader) HasReadRestrictions(contentID string) (bool, error) {
+	restrictions := &ContentRestrictions{}
+	if err := r.get(fmt.Sprintf("%s%s/restriction/byOperation/read", PathContentData, url.PathEscape(contentID)), restrictions); err != nil {
+		return false, errors.Wrap(err, "error getting the content restrictions with API token")
+	}
+
+	return restrictions.IsRestricted(), nil
+}
+
+func (r *apiTokenRestrictionReader) GetPageAncestors(pageID int) ([]PageAncestor, error) {
+	return pageAncestorsCache.GetOrLoad(lookupCacheKey(r.baseURL, strconv.Itoa(pageID)), func() ([]PageAncestor, error) {
+		pageResponse := &PageResponse{}
+		if err := r.get(fmt.Sprintf("%s%s?status=any&expand=ancestors", PathContentData, strconv.Itoa(pageID)), pageResponse); err != nil {
+			return nil, errors.Wrap(err, "error getting the page ancestors with API token")
+		}
+		return pageResponse.Ancestors, nil
+	})
+}
+
+func (r *apiTokenRestrictionReader) get(path string, out interface{}) error {
+	body, statusCode, err := r.p.MakeHTTPCallWithAPIToken(r.baseURL + path)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d", statusCode)
+	}
+
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func TestPageRestrictionCheck(t *testing.T) {
+	for name, tc := range map[string]struct {
+		restrictions  map[string]string
+		expected      bool
+		expectedError bool
+	}{
+		"not restricted": {
+			restrictions: map[string]string{
+				"42": `{"restrictions":{"user":{"size":0},"group":{"size":0}}}`,
+				"7":  `{"restrictions":{"user":{"size":0},"group":{"size":0}}}`,
+			},
+		},
+		"page restricted to a group": {
+			restrictions: map[string]string{
+				"42": `{"restrictions":{"user":{"size":0},"group":{"size":1}}}`,
+			},
+			expected: true,
+		},
+		"ancestor restricted to a user": {
+			restrictions: map[string]string{
+				"42": `{"restrictions":{"user":{"size":0},"group":{"size":0}}}`,
+				"7":  `{"restrictions":{"user":{"size":1},"group":{"size":0}}}`,
+			},
+			expected: true,
+		},
+		"restrictions not readable": {
+			restrictions:  map[string]string{},
+			expectedError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				contentID := strings.TrimPrefix(r.URL.Path, PathContentData)
+				switch {
+				case contentID == "42":
+					_, _ = w.Write([]byte(`{"id":"42","ancestors":[{"id":"7","title":"Home"}]}`))
+				case strings.HasSuffix(contentID, "/restriction/byOperation/read"):
+					restrictions, ok := tc.restrictions[strings.TrimSuffix(contentID, "/restriction/byOperation/read")]
+					if !ok {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					_, _ = w.Write([]byte(restrictions))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+			mockAPI.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+			restricted, err := pageRestrictionCheck(newServerClient(server.URL, server.Client()), "42")()
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, restricted)
+		})
+	}
+}
+
+func TestPageRestrictionCheckWithoutReader(t *testing.T) {
+	assert.Nil(t, pageRestrictionCheck(nil, "42"))
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
 	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
 	"github.com/mattermost/mattermost-plugin-confluence/server/service"
-	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 
 	"github.com/mattermost/mattermost/server/public/model"
 )
@@ -30,9 +29,9 @@ func handleSaveSubscription(w http.ResponseWriter, r *http.Request, p *Plugin) {
 	userID := r.Header.Get(config.HeaderMattermostUserID)
 	var subscription serializer.Subscription
 
-	if !util.IsSystemAdmin(userID) {
-		p.client.Log.Error("Non admin user does not have access to create subscription for this channel", "UserID", userID, "ChannelID", channelID)
-		http.Error(w, "only system admin can save a subscription", http.StatusForbidden)
+	if !p.canManageSubscriptions(userID, channelID) {
+		p.client.Log.Error("User does not have permission to create subscription for this channel", "UserID", userID, "ChannelID", channelID)
+		http.Error(w, "User does not have permission to save a subscription in this channel", http.StatusForbidden)
 		return
 	}
 
@@ -59,13 +58,17 @@ func handleSaveSubscription(w http.ResponseWriter, r *http.Request, p *Plugin) {
 		return
 	}
 
+	if subscriptionChannelID := subscription.GetBaseSubscription().ChannelID; subscriptionChannelID != channelID {
+		p.client.Log.Error("The channel of the subscription does not match the channel of the request", "UserID", userID, "ChannelID", channelID, "SubscriptionChannelID", subscriptionChannelID)
+		http.Error(w, "The channel of the subscription does not match the channel of the request", http.StatusBadRequest)
+		return
+	}
+
 	pluginConfig := config.GetConfig()
-	if pluginConfig.ServerVersionGreaterthan9 {
-		if statusCode, err := p.validateUserConfluenceAccess(userID, pluginConfig.ConfluenceURL, subscriptionType, subscription); err != nil {
-			p.client.Log.Error("Error validating the user's Confluence access", "error", err.Error())
-			http.Error(w, err.Error(), statusCode) // safe to return the error string directly, as this function ensures all returned errors are user-friendly
-			return
-		}
+	if statusCode, err := p.validateSubscriptionAccess(userID, pluginConfig, subscriptionType, subscription); err != nil {
+		p.client.Log.Error("Error validating the user's Confluence access", "error", err.Error())
+		http.Error(w, err.Error(), statusCode) // safe to return the error string directly, as this function ensures all returned errors are user-friendly
+		return
 	}
 
 	if err := serializer.ValidateEventsForServerVersion(subscription, pluginConfig.ServerVersionGreaterthan9); err != nil {
@@ -80,6 +83,8 @@ func handleSaveSubscription(w http.ResponseWriter, r *http.Request, p *Plugin) {
 		return
 	}
 
+	service.RecordAudit(service.NewSubscriptionAuditEntry(service.AuditActionSubscriptionCreated, userID, channelID, nil, subscription))
+
 	post := &model.Post{
 		UserId:    config.BotUserID,
 		ChannelId: channelID,
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func TestSubscriptionHandlersChannelMismatch(t *testing.T) {
+	for name, tc := range map[string]struct {
+		method  string
+		handler func(w http.ResponseWriter, r *http.Request, p *Plugin)
+	}{
+		"save subscription": {method: http.MethodPost, handler: handleSaveSubscription},
+		"edit subscription": {method: http.MethodPut, handler: handleEditChannelSubscription},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			config.SetConfig(&config.Configuration{RolesAllowedToManageSubscriptions: config.SubscriptionRoleChannelAdmin})
+			mockAPI.On("GetUser", "user-id").Return(&model.User{Id: "user-id", Roles: "system_user"}, nil)
+			mockAPI.On("GetChannel", "channelA").Return(&model.Channel{Id: "channelA", TeamId: "team-id"}, nil)
+			mockAPI.On("GetChannelMember", "channelA", "user-id").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+			mockAPI.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			body := `{"alias":"test","baseURL":"https://confluence.example.com","spaceKey":"DEV","events":["page_created"],"channelID":"channelB","subscriptionType":"space_subscription"}`
+			r := httptest.NewRequest(tc.method, "/channelA/subscription/space_subscription", strings.NewReader(body))
+			r.Header.Set(config.HeaderMattermostUserID, "user-id")
+			r = mux.SetURLVars(r, map[string]string{"channelID": "channelA", "type": "space_subscription"})
+			w := httptest.NewRecorder()
+
+			tc.handler(w, r, p)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockAPI.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+			mockAPI.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 )
 
@@ -17,7 +18,16 @@ const (
 	PageTrashedEvent    = "page_trashed"
 	PageRestoredEvent   = "page_restored"
 	PageRemovedEvent    = "page_removed"
+	SpaceCreatedEvent   = "space_created"
 	SpaceUpdatedEvent   = "space_updated"
+	SpaceRemovedEvent   = "space_removed"
+
+	SpacePermissionsUpdatedEvent = "space_permissions_updated"
+
+	// Webhook events only used to invalidate the cached lookups
+	UserRemovedEvent     = "user_removed"
+	UserDeactivatedEvent = "user_deactivated"
+	UserReactivatedEvent = "user_reactivated"
 
 	// Subscription Types
 	SubscriptionTypeSpace = "space_subscription"
@@ -38,9 +48,22 @@ var eventDisplayName = map[string]string{
 	PageTrashedEvent:    "Page Trash",
 	PageRestoredEvent:   "Page Restore",
 	PageRemovedEvent:    "Page Remove",
+
+	SpaceCreatedEvent:            "Space Create",
+	SpaceUpdatedEvent:            "Space Update",
+	SpaceRemovedEvent:            "Space Remove",
+	SpacePermissionsUpdatedEvent: "Space Permissions Update",
+}
+
+// SpaceEvents are about the space itself, only the space subscriptions can subscribe to them.
+var SpaceEvents = []string{
+	SpaceCreatedEvent,
+	SpaceUpdatedEvent,
+	SpaceRemovedEvent,
+	SpacePermissionsUpdatedEvent,
 }
 
-// SupportedEventsV8AndBelow contains all events supported by Confluence Server v8 and below
+// SupportedEventsV8AndBelow contains all events supported by Confluence Server v8 and below, and by Confluence Cloud
 var SupportedEventsV8AndBelow = []string{
 	CommentCreatedEvent,
 	CommentUpdatedEvent,
@@ -50,6 +73,10 @@ var SupportedEventsV8AndBelow = []string{
 	PageTrashedEvent,
 	PageRestoredEvent,
 	PageRemovedEvent,
+	SpaceCreatedEvent,
+	SpaceUpdatedEvent,
+	SpaceRemovedEvent,
+	SpacePermissionsUpdatedEvent,
 }
 
 // SupportedEventsV9AndAbove contains events supported by Confluence Server v9+
@@ -62,6 +89,18 @@ var SupportedEventsV9AndAbove = []string{
 	PageTrashedEvent,
 	PageRestoredEvent,
 	PageRemovedEvent,
+	SpaceCreatedEvent,
+	SpaceUpdatedEvent,
+	SpaceRemovedEvent,
+	SpacePermissionsUpdatedEvent,
+}
+
+// CacheInvalidationEvents contains the events the Confluence Server v9+ webhook is registered for to keep the
+// cached user lookups up to date. They are not sent to the channels.
+var CacheInvalidationEvents = []string{
+	UserRemovedEvent,
+	UserDeactivatedEvent,
+	UserReactivatedEvent,
 }
 
 // supportedEventsV8Map is a lookup map for V8 and below events
@@ -91,15 +130,28 @@ type Subscription interface {
 	GetFormattedSubscription() string
 	IsValid() error
 	ValidateSubscription(*Subscriptions) error
+	GetBaseSubscription() BaseSubscription
 }
 
 type BaseSubscription struct {
-	Alias     string   `json:"alias"`
-	OldAlias  string   `json:"oldAlias,omitempty"`
-	BaseURL   string   `json:"baseURL"`
-	Events    []string `json:"events"`
-	ChannelID string   `json:"channelID"`
-	Type      string   `json:"subscriptionType"`
+	Alias          string      `json:"alias"`
+	OldAlias       string      `json:"oldAlias,omitempty"`
+	BaseURL        string      `json:"baseURL"`
+	Events         []string    `json:"events"`
+	ChannelID      string      `json:"channelID"`
+	Type           string      `json:"subscriptionType"`
+	SkipMinorEdits bool        `json:"skipMinorEdits,omitempty"`
+	QuietHours     *QuietHours `json:"quietHours,omitempty"`
+}
+
+// validateOptions validates the delivery options shared by all subscription types.
+func (bs BaseSubscription) validateOptions() error {
+	if bs.QuietHours != nil {
+		if err := bs.QuietHours.IsValid(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type StringSubscription map[string]Subscription
@@ -246,6 +298,11 @@ func GetSupportedEventsMap(isV9OrAbove bool) map[string]bool {
 	return supportedEventsV8Map
 }
 
+// IsSpaceEvent tells whether the event is about a space rather than about its content.
+func IsSpaceEvent(event string) bool {
+	return slices.Contains(SpaceEvents, event)
+}
+
 // EventDisplayName returns the display name for an event
 func EventDisplayName(event string) string {
 	if name, ok := eventDisplayName[event]; ok {
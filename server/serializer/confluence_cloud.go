@@ -2,23 +2,11 @@ package serializer
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 
-	"github.com/mattermost/mattermost/server/public/model"
-
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
 )
 
-const (
-	confluenceCloudPageCreateMessage    = "A new page titled [%s](%s) was created in the **%s** space."
-	confluenceCloudPageUpdateMessage    = "A page titled [%s](%s) was updated in the **%s** space."
-	confluenceCloudPageDeleteMessage    = "A page titled **%s** was removed from the **%s** space."
-	confluenceCloudCommentCreateMessage = "A new [comment](%s) was posted on the [%s](%s) page."
-	confluenceCloudCommentUpdateMessage = "A [comment](%s) was updated on the [%s](%s) page."
-	confluenceCloudCommentDeleteMessage = "A comment was deleted from the [%s](%s) page."
-)
-
 type ConfluenceCloudEvent struct {
 	UserAccountID string   `json:"userAccountId"`
 	AccountType   string   `json:"accountType"`
@@ -26,6 +14,12 @@ type ConfluenceCloudEvent struct {
 	Timestamp     int      `json:"timestamp"`
 	Comment       *Comment `json:"comment"`
 	Page          *Page    `json:"page"`
+	Space         *Space   `json:"space"`
+
+	// The fields below are looked up through the Confluence Cloud REST API, they are empty when it could not be called.
+	Actor     string `json:"-"` // The @mention or the display name of the user who triggered the event
+	SpaceName string `json:"-"`
+	Excerpt   string `json:"-"` // The changes or the body of the page, or the body of the comment
 }
 
 type Page struct {
@@ -55,6 +49,14 @@ type Comment struct {
 	InReplyTo             *ParentComment `json:"inReplyTo"`
 }
 
+type Space struct {
+	CreatorAccountID string `json:"creatorAccountId"`
+	Key              string `json:"key"`
+	Title            string `json:"title"`
+	Self             string `json:"self"`
+	CreationDate     int64  `json:"creationDate"`
+}
+
 type ParentComment struct {
 	ID string `json:"id"`
 }
@@ -68,46 +70,42 @@ func ConfluenceCloudEventFromJSON(data io.Reader) (*ConfluenceCloudEvent, error)
 	return &confluenceCloudEvent, nil
 }
 
-func (e ConfluenceCloudEvent) GetNotificationPost(eventType string) *model.Post {
-	message := ""
-	page := e.Page
-	comment := e.Comment
+// Normalize returns the common model of the event, nil when the content the event is about is missing.
+func (e ConfluenceCloudEvent) Normalize(eventType string) *Event {
+	event := &Event{
+		Type:      eventType,
+		BaseURL:   e.GetURL(),
+		SpaceName: e.SpaceName,
+		Actor:     e.Actor,
+		Excerpt:   e.Excerpt,
+	}
 
-	switch eventType {
-	case PageCreatedEvent, PageUpdatedEvent, PageRemovedEvent:
-		if page == nil {
+	switch {
+	case e.Comment != nil:
+		if e.Comment.Parent == nil {
 			return nil
 		}
-
-	case CommentCreatedEvent, CommentUpdatedEvent, CommentRemovedEvent:
-		if comment == nil || comment.Parent == nil {
-			return nil
+		event.SpaceKey = e.Comment.SpaceKey
+		event.PageID = e.Comment.Parent.ID
+		event.PageTitle = e.Comment.Parent.Title
+		event.PageURL = e.Comment.Parent.Self
+		event.CommentURL = e.Comment.Self
+	case e.Page != nil:
+		event.SpaceKey = e.Page.SpaceKey
+		event.PageID = e.Page.ID
+		event.PageTitle = e.Page.Title
+		event.PageURL = e.Page.Self
+	case e.Space != nil:
+		event.SpaceKey = e.Space.Key
+		event.SpaceURL = e.Space.Self
+		if event.SpaceName == "" {
+			event.SpaceName = e.Space.Title
 		}
-	}
-
-	switch eventType {
-	case PageCreatedEvent:
-		message = fmt.Sprintf(confluenceCloudPageCreateMessage, page.Title, page.Self, page.SpaceKey)
-	case CommentCreatedEvent:
-		message = fmt.Sprintf(confluenceCloudCommentCreateMessage, comment.Self, comment.Parent.Title, comment.Parent.Self)
-	case PageUpdatedEvent:
-		message = fmt.Sprintf(confluenceCloudPageUpdateMessage, page.Title, page.Self, page.SpaceKey)
-	case CommentUpdatedEvent:
-		message = fmt.Sprintf(confluenceCloudCommentUpdateMessage, comment.Self, comment.Parent.Title, comment.Parent.Self)
-	case PageRemovedEvent:
-		message = fmt.Sprintf(confluenceCloudPageDeleteMessage, page.Title, page.SpaceKey)
-	case CommentRemovedEvent:
-		message = fmt.Sprintf(confluenceCloudCommentDeleteMessage, comment.Parent.Title, comment.Parent.Self)
 	default:
 		return nil
 	}
 
-	post := &model.Post{
-		UserId:  config.BotUserID,
-		Type:    model.PostTypeDefault,
-		Message: message,
-	}
-	return post
+	return event
 }
 
 func (e ConfluenceCloudEvent) GetURL() string {
@@ -115,6 +113,8 @@ func (e ConfluenceCloudEvent) GetURL() string {
 		return e.Comment.Self
 	} else if e.Page != nil {
 		return e.Page.Self
+	} else if e.Space != nil {
+		return e.Space.Self
 	}
 	return ""
 }
@@ -124,6 +124,8 @@ func (e ConfluenceCloudEvent) GetSpaceKey() string {
 		return e.Comment.SpaceKey
 	} else if e.Page != nil {
 		return e.Page.SpaceKey
+	} else if e.Space != nil {
+		return e.Space.Key
 	}
 	return ""
 }
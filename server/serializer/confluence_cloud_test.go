@@ -0,0 +1,72 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfluenceCloudEventNormalize(t *testing.T) {
+	page := &Page{ID: "42", Title: "Release notes", SpaceKey: "DEV", Self: "https://example.atlassian.net/wiki/spaces/DEV/pages/42"}
+	comment := &Comment{ID: "43", SpaceKey: "DEV", Self: "https://example.atlassian.net/wiki/spaces/DEV/pages/42#comment-43", Parent: page}
+
+	for name, tc := range map[string]struct {
+		event     ConfluenceCloudEvent
+		eventType string
+		expected  *Event
+	}{
+		"page updated": {
+			event:     ConfluenceCloudEvent{Page: page, Actor: "@jane", SpaceName: "Development", Excerpt: "Fixed the typos"},
+			eventType: PageUpdatedEvent,
+			expected: &Event{
+				Type:      PageUpdatedEvent,
+				BaseURL:   page.Self,
+				SpaceKey:  "DEV",
+				SpaceName: "Development",
+				PageID:    "42",
+				PageTitle: "Release notes",
+				PageURL:   page.Self,
+				Actor:     "@jane",
+				Excerpt:   "Fixed the typos",
+			},
+		},
+		"comment created": {
+			event:     ConfluenceCloudEvent{Comment: comment, Excerpt: "Looks good"},
+			eventType: CommentCreatedEvent,
+			expected: &Event{
+				Type:       CommentCreatedEvent,
+				BaseURL:    comment.Self,
+				SpaceKey:   "DEV",
+				PageID:     "42",
+				PageTitle:  "Release notes",
+				PageURL:    page.Self,
+				CommentURL: comment.Self,
+				Excerpt:    "Looks good",
+			},
+		},
+		"space created": {
+			event:     ConfluenceCloudEvent{Space: &Space{Key: "DEV", Title: "Development", Self: "https://example.atlassian.net/wiki/spaces/DEV"}, Actor: "@jane"},
+			eventType: SpaceCreatedEvent,
+			expected: &Event{
+				Type:      SpaceCreatedEvent,
+				BaseURL:   "https://example.atlassian.net/wiki/spaces/DEV",
+				SpaceKey:  "DEV",
+				SpaceName: "Development",
+				SpaceURL:  "https://example.atlassian.net/wiki/spaces/DEV",
+				Actor:     "@jane",
+			},
+		},
+		"comment without its page": {
+			event:     ConfluenceCloudEvent{Comment: &Comment{ID: "43", SpaceKey: "DEV"}},
+			eventType: CommentCreatedEvent,
+		},
+		"no content": {
+			event:     ConfluenceCloudEvent{Actor: "@jane"},
+			eventType: PageCreatedEvent,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.event.Normalize(tc.eventType))
+		})
+	}
+}
@@ -6,30 +6,16 @@ import (
 	"io"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 
-	"github.com/mattermost/mattermost/server/public/model"
-
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
 )
 
 const (
-	ConfluenceContentTypePage                     = "page"
-	ConfluenceContentTypeBlogPost                 = "blogpost"
-	ConfluenceContentTypeComment                  = "comment"
-	confluenceServerPageCreatedMessage            = "%s published a new page in %s."
-	confluenceServerPageCreatedWithoutBodyMessage = "%s published a new page %s in %s."
-	confluenceServerPageUpdatedMessage            = "%s updated %s in %s."
-	confluenceServerPageTrashedMessage            = "%s trashed %s in %s."
-	confluenceServerPageRestoredMessage           = "%s restored %s in %s."
-	confluenceServerPageRemovedMessage            = "%s removed **%s** in %s."
-
-	confluenceServerCommentCreatedMessage      = "%s commented on %s in %s."
-	confluenceServerEmptyCommentCreatedMessage = "%s [commented](%s) on %s in %s."
-	confluenceServerCommentReplyCreatedMessage = "%s replied to a comment on %s in %s."
-	confluenceServerCommentUpdatedMessage      = "%s updated a comment on %s in %s."
-	confluenceServerEmptyCommentUpdatedMessage = "%s updated a [comment](%s) on %s in %s."
-	confluenceServerCommentRemovedMessage      = "%s removed a comment on %s in %s."
+	ConfluenceContentTypePage     = "page"
+	ConfluenceContentTypeBlogPost = "blogpost"
+	ConfluenceContentTypeComment  = "comment"
 )
 
 type ConfluenceServerUser struct {
@@ -152,6 +138,10 @@ type ConfluenceServerEvent struct {
 	User           *ConfluenceServerUser     `json:"user"`
 	Space          ConfluenceServerSpace     `json:"space"`
 	Timestamp      int64                     `json:"timestamp"`
+
+	// MattermostMention is the @mention of the Mattermost user who triggered the event, shown instead of the
+	// Confluence user when set.
+	MattermostMention string `json:"-"`
 }
 
 type CommentPayload struct {
@@ -167,6 +157,10 @@ type SpacePayload struct {
 	SpaceKey string `json:"spaceKey"`
 }
 
+type UserPayload struct {
+	UserKey string `json:"userKey"`
+}
+
 type ConfluenceServerWebhookPayload struct {
 	Timestamp int64          `json:"timestamp"`
 	Event     string         `json:"event"`
@@ -174,6 +168,19 @@ type ConfluenceServerWebhookPayload struct {
 	Comment   CommentPayload `json:"comment"`
 	Page      PagePayload    `json:"page"`
 	Space     SpacePayload   `json:"space"`
+	User      UserPayload    `json:"user"`
+}
+
+// Normalize returns the event with only what the webhook payload tells about it, for a generic notification.
+func (p ConfluenceServerWebhookPayload) Normalize(baseURL string) *Event {
+	event := &Event{
+		Type:    p.Event,
+		BaseURL: baseURL,
+	}
+	if p.Page.ID != 0 {
+		event.PageID = strconv.FormatInt(p.Page.ID, 10)
+	}
+	return event
 }
 
 func ConfluenceServerEventFromJSON(data io.Reader) (*ConfluenceServerEvent, error) {
@@ -235,205 +242,79 @@ func (e *ConfluenceServerEvent) sanitizeURLs() {
 	}
 }
 
-func (e *ConfluenceServerEvent) GetUserDisplayName(withLink bool) string {
-	name := "Someone"
+// actorName returns the @mention of the user who triggered the event, or their linked name in Confluence.
+func (e *ConfluenceServerEvent) actorName() string {
+	if e.MattermostMention != "" {
+		return e.MattermostMention
+	}
 	if e.User == nil {
-		return name
+		return ""
 	}
 
-	if strings.TrimSpace(e.User.FullName) != "" {
-		name = strings.TrimSpace(e.User.FullName)
-	} else if strings.TrimSpace(e.User.Username) != "" {
+	name := strings.TrimSpace(e.User.FullName)
+	if name == "" {
 		name = strings.TrimSpace(e.User.Username)
 	}
-
-	if withLink && e.User.URL != "" {
+	if name != "" && e.User.URL != "" {
 		name = fmt.Sprintf("[%s](%s)", name, e.User.URL)
 	}
 
 	return name
 }
 
-func (e *ConfluenceServerEvent) GetUserFirstName() string {
-	return strings.Split(e.GetUserDisplayName(false), " ")[0]
-}
-
-func (e *ConfluenceServerEvent) GetSpaceDisplayName(withLink bool) string {
-	name := e.Space.Key
-	if strings.TrimSpace(e.Space.Name) != "" {
-		name = strings.TrimSpace(e.Space.Name)
-	}
-
-	if withLink && e.Space.URL != "" {
-		name = fmt.Sprintf("[%s](%s)", name, e.Space.URL)
-	}
-
-	return name
-}
-
-func (e *ConfluenceServerEvent) GetPageDisplayName(withLink bool) string {
-	if e.Page == nil {
-		return ""
-	}
-
-	name := e.Page.Title
-	if withLink && e.Page.TinyURL != "" {
-		name = fmt.Sprintf("[%s](%s)", name, e.Page.TinyURL)
-	}
-
-	return name
-}
-
-func (e *ConfluenceServerEvent) GetBlogDisplayName(withLink bool) string {
-	if e.Blog == nil {
-		return ""
-	}
-
-	name := e.Blog.Title
-	if withLink && e.Blog.URL != "" {
-		name = fmt.Sprintf("[%s](%s)", name, e.Blog.URL)
-	}
-
-	return name
-}
-
-func (e *ConfluenceServerEvent) GetCommentPageOrBlogDisplayName(withLink bool) string {
-	commentedOn := e.GetPageDisplayName(withLink)
-	if commentedOn == "" {
-		commentedOn = e.GetBlogDisplayName(withLink)
-	}
-	return commentedOn
-}
-
-func (e ConfluenceServerEvent) GetNotificationPost(_ string) *model.Post {
-	var attachment *model.SlackAttachment
-	post := &model.Post{
-		UserId: config.BotUserID,
+// Normalize returns the common model of the event, nil when the content the event is about is missing.
+func (e ConfluenceServerEvent) Normalize() *Event {
+	event := &Event{
+		Type:        e.Event,
+		BaseURL:     e.BaseURL,
+		SpaceKey:    e.Space.Key,
+		SpaceName:   e.Space.Name,
+		SpaceURL:    e.Space.URL,
+		Actor:       e.actorName(),
+		IsMinorEdit: e.IsMinorEdit,
 	}
 
 	switch e.Event {
-	case PageCreatedEvent, PageUpdatedEvent, PageRemovedEvent:
+	case PageCreatedEvent, PageUpdatedEvent, PageTrashedEvent, PageRestoredEvent, PageRemovedEvent:
 		if e.Page == nil {
 			return nil
 		}
-
-	case CommentCreatedEvent:
-		if e.Comment == nil || e.Comment.ParentComment == nil {
-			return nil
+		event.PageID = e.Page.ID
+		event.PageTitle = e.Page.Title
+		event.PageURL = e.Page.TinyURL
+		switch e.Event {
+		case PageCreatedEvent:
+			event.Excerpt = e.Page.Excerpt
+		case PageUpdatedEvent:
+			event.Excerpt = e.VersionComment
 		}
 
-	case CommentUpdatedEvent, CommentRemovedEvent:
+	case CommentCreatedEvent, CommentUpdatedEvent, CommentRemovedEvent:
 		if e.Comment == nil {
 			return nil
 		}
-	}
-
-	switch e.Event {
-	case PageCreatedEvent:
-		message := fmt.Sprintf(confluenceServerPageCreatedMessage, e.GetUserDisplayName(true), e.GetSpaceDisplayName(true))
-		if strings.TrimSpace(e.Page.Excerpt) != "" {
-			attachment = &model.SlackAttachment{
-				Fallback:  message,
-				Pretext:   message,
-				Title:     e.Page.Title,
-				TitleLink: e.Page.TinyURL,
-				Text:      fmt.Sprintf("%s\n\n[**View in Confluence**](%s)", strings.TrimSpace(e.Page.Excerpt), e.Page.TinyURL),
-			}
-		} else {
-			post.Message = fmt.Sprintf(confluenceServerPageCreatedWithoutBodyMessage, e.GetUserDisplayName(true), e.GetPageDisplayName(true), e.GetSpaceDisplayName(true))
-		}
-
-	case PageUpdatedEvent:
-		message := fmt.Sprintf(confluenceServerPageUpdatedMessage, e.GetUserDisplayName(true), e.GetPageDisplayName(true), e.GetSpaceDisplayName(true))
-		if strings.TrimSpace(e.VersionComment) != "" {
-			attachment = &model.SlackAttachment{
-				Fallback: message,
-				Pretext:  message,
-				Text:     fmt.Sprintf("**What's Changed?**\n> %s\n\n[**View in Confluence**](%s)", strings.TrimSpace(e.VersionComment), e.Page.TinyURL),
-			}
-		} else {
-			post.Message = message
-		}
-
-	case PageTrashedEvent:
-		post.Message = fmt.Sprintf(confluenceServerPageTrashedMessage, e.GetUserDisplayName(true), e.GetPageDisplayName(true), e.GetSpaceDisplayName(true))
-
-	case PageRestoredEvent:
-		post.Message = fmt.Sprintf(confluenceServerPageRestoredMessage, e.GetUserDisplayName(true), e.GetPageDisplayName(true), e.GetSpaceDisplayName(true))
-
-	case PageRemovedEvent:
-		// No link for page since the page was removed
-		post.Message = fmt.Sprintf(confluenceServerPageRemovedMessage, e.GetUserDisplayName(true), e.GetPageDisplayName(false), e.GetSpaceDisplayName(true))
-
-	case CommentCreatedEvent:
-		message := fmt.Sprintf(confluenceServerCommentCreatedMessage, e.GetUserDisplayName(true), e.GetCommentPageOrBlogDisplayName(true), e.GetSpaceDisplayName(true))
-
-		text := ""
-		if strings.TrimSpace(e.Comment.Excerpt) != "" {
-			text += fmt.Sprintf("**%s wrote:**\n> %s\n\n", e.GetUserFirstName(), strings.TrimSpace(e.Comment.Excerpt))
-		}
-		if e.Comment.ParentComment != nil && strings.TrimSpace(e.Comment.ParentComment.Excerpt) != "" {
-			message = fmt.Sprintf(confluenceServerCommentReplyCreatedMessage, e.GetUserDisplayName(true), e.GetCommentPageOrBlogDisplayName(true), e.GetSpaceDisplayName(true))
-			text += fmt.Sprintf("**In Reply to:**\n> %s\n", strings.TrimSpace(e.Comment.ParentComment.Excerpt))
-		}
-
-		if text != "" {
-			attachment = &model.SlackAttachment{
-				Fallback: message,
-				Pretext:  message,
-				Text:     fmt.Sprintf("%s\n\n[**View in Confluence**](%s)", text, e.Comment.URL),
-			}
-		} else {
-			post.Message = fmt.Sprintf(confluenceServerEmptyCommentCreatedMessage, e.GetUserDisplayName(true), e.Comment.URL, e.GetCommentPageOrBlogDisplayName(true), e.GetSpaceDisplayName(true))
+		// Comments are on pages or on blog posts, only the pages can be subscribed to.
+		switch {
+		case e.Page != nil:
+			event.PageID = e.Page.ID
+			event.PageTitle = e.Page.Title
+			event.PageURL = e.Page.TinyURL
+		case e.Blog != nil:
+			event.PageTitle = e.Blog.Title
+			event.PageURL = e.Blog.URL
 		}
-
-	case CommentUpdatedEvent:
-		message := fmt.Sprintf(confluenceServerCommentUpdatedMessage, e.GetUserDisplayName(true), e.GetCommentPageOrBlogDisplayName(true), e.GetSpaceDisplayName(true))
-		if strings.TrimSpace(e.Comment.Excerpt) != "" {
-			attachment = &model.SlackAttachment{
-				Fallback: message,
-				Pretext:  message,
-				Text:     fmt.Sprintf("**Updated Comment:**\n> %s\n\n[**View in Confluence**](%s)", strings.TrimSpace(e.Comment.Excerpt), e.Comment.URL),
-			}
-		} else {
-			post.Message = fmt.Sprintf(confluenceServerEmptyCommentUpdatedMessage, e.GetUserDisplayName(true), e.Comment.URL, e.GetCommentPageOrBlogDisplayName(true), e.GetSpaceDisplayName(true))
+		event.CommentURL = e.Comment.URL
+		event.Excerpt = e.Comment.Excerpt
+		if e.Comment.ParentComment != nil {
+			event.ParentExcerpt = e.Comment.ParentComment.Excerpt
 		}
 
-	case CommentRemovedEvent:
-		// No link since the comment was removed.
-		message := fmt.Sprintf(confluenceServerCommentRemovedMessage, e.GetUserDisplayName(true), e.GetCommentPageOrBlogDisplayName(true), e.GetSpaceDisplayName(true))
-		if strings.TrimSpace(e.Comment.Excerpt) != "" {
-			attachment = &model.SlackAttachment{
-				Fallback: message,
-				Pretext:  message,
-				Text:     fmt.Sprintf("**Deleted Comment:**\n> %s", strings.TrimSpace(e.Comment.Excerpt)),
-			}
-		} else {
-			post.Message = message
+	case SpaceCreatedEvent, SpaceUpdatedEvent, SpaceRemovedEvent, SpacePermissionsUpdatedEvent:
+		if e.Space.Key == "" {
+			return nil
 		}
-
-	default:
-		return nil
-	}
-
-	if attachment != nil {
-		model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+		event.Excerpt = e.Space.Description
 	}
 
-	return post
-}
-
-func (e ConfluenceServerEvent) GetURL() string {
-	return e.BaseURL
-}
-
-func (e ConfluenceServerEvent) GetSpaceKey() string {
-	return e.Space.Key
-}
-
-func (e ConfluenceServerEvent) GetPageID() string {
-	if e.Page != nil {
-		return e.Page.ID
-	}
-	return ""
+	return event
 }
@@ -103,3 +103,44 @@ func TestSanitizeURLs(t *testing.T) {
 	assert.Equal(t, "https://host.example.com/comments/parent", event.Comment.ParentComment.URL)
 	assert.Equal(t, "https://host.example.com/blog/789", event.Blog.URL)
 }
+
+func TestConfluenceServerEventNormalize(t *testing.T) {
+	event := ConfluenceServerEvent{
+		Event:          CommentCreatedEvent,
+		BaseURL:        "https://confluence.example.com",
+		VersionComment: "Fixed the typos",
+		User:           &ConfluenceServerUser{FullName: "Jane Doe", URL: "https://confluence.example.com/display/~jane"},
+		Space:          ConfluenceServerSpace{Key: "DEV", Name: "Development", URL: "https://confluence.example.com/display/DEV"},
+		Page:           &ConfluenceServerPage{ID: "42", Title: "Release notes", TinyURL: "https://confluence.example.com/x/42", Excerpt: "The release notes"},
+		Comment: &ConfluenceServerComment{
+			URL:           "https://confluence.example.com/x/42#comment-43",
+			Excerpt:       "Agreed",
+			ParentComment: &ConfluenceServerParentComment{Excerpt: "Looks good"},
+		},
+	}
+
+	assert.Equal(t, &Event{
+		Type:          CommentCreatedEvent,
+		BaseURL:       "https://confluence.example.com",
+		SpaceKey:      "DEV",
+		SpaceName:     "Development",
+		SpaceURL:      "https://confluence.example.com/display/DEV",
+		PageID:        "42",
+		PageTitle:     "Release notes",
+		PageURL:       "https://confluence.example.com/x/42",
+		CommentURL:    "https://confluence.example.com/x/42#comment-43",
+		Actor:         "[Jane Doe](https://confluence.example.com/display/~jane)",
+		Excerpt:       "Agreed",
+		ParentExcerpt: "Looks good",
+	}, event.Normalize())
+
+	event.Event = PageUpdatedEvent
+	event.MattermostMention = "@jane"
+	normalized := event.Normalize()
+	assert.Equal(t, "@jane", normalized.Actor)
+	assert.Equal(t, "Fixed the typos", normalized.Excerpt)
+	assert.Empty(t, normalized.CommentURL)
+
+	event.Page = nil
+	assert.Nil(t, event.Normalize())
+}
@@ -0,0 +1,227 @@
+package serializer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+const (
+	genericEventMessage = "Someone %s a page on Confluence with the id %s"
+	viewInConfluence    = "[**View in Confluence**](%s)"
+)
+
+// eventMessages describe the events, they are followed by the space of the content.
+var eventMessages = map[string]string{
+	PageCreatedEvent:    "%s published a new page %s",
+	PageUpdatedEvent:    "%s updated %s",
+	PageTrashedEvent:    "%s trashed %s",
+	PageRestoredEvent:   "%s restored %s",
+	PageRemovedEvent:    "%s removed %s",
+	CommentCreatedEvent: "%s commented on %s",
+	CommentUpdatedEvent: "%s updated a comment on %s",
+	CommentRemovedEvent: "%s removed a comment from %s",
+}
+
+// commentLinkMessages replace eventMessages when there is no excerpt to link the comment from.
+var commentLinkMessages = map[string]string{
+	CommentCreatedEvent: "%s [commented](%s) on %s",
+	CommentUpdatedEvent: "%s updated a [comment](%s) on %s",
+}
+
+// spaceEventMessages describe the events about a space.
+var spaceEventMessages = map[string]string{
+	SpaceCreatedEvent:            "%s created the %s space.",
+	SpaceUpdatedEvent:            "%s updated the %s space.",
+	SpaceRemovedEvent:            "%s removed the %s space.",
+	SpacePermissionsUpdatedEvent: "%s updated the permissions of the %s space.",
+}
+
+// eventActions are used by the generic notifications, sent when only the ID of the page is known.
+var eventActions = map[string]string{
+	PageCreatedEvent:  "published",
+	PageUpdatedEvent:  "updated",
+	PageTrashedEvent:  "trashed",
+	PageRestoredEvent: "restored",
+	PageRemovedEvent:  "removed",
+}
+
+var excerptHeadings = map[string]string{
+	PageUpdatedEvent:    "**What's Changed?**",
+	CommentUpdatedEvent: "**Updated Comment:**",
+	CommentRemovedEvent: "**Deleted Comment:**",
+}
+
+// Event is the common model of the events sent by Confluence Cloud, Server and Data Center. Every payload format is
+// normalized to it, so that the events are matched against the subscriptions and rendered the same way.
+type Event struct {
+	Type string
+	// BaseURL is the URL of the Confluence instance, the subscriptions are matched against its host.
+	BaseURL string
+
+	SpaceKey  string
+	SpaceName string
+	SpaceURL  string
+
+	// PageID, PageTitle and PageURL are about the page of the event, or the content commented on for comment events.
+	PageID    string
+	PageTitle string
+	PageURL   string
+
+	CommentURL string
+
+	// Actor is the @mention or the name of the user who triggered the event, empty when unknown.
+	Actor string
+	// Excerpt is the version comment or the body of the page, the body of the comment, or the description of the space.
+	Excerpt string
+	// ParentExcerpt is the body of the comment replied to.
+	ParentExcerpt string
+	IsMinorEdit   bool
+}
+
+// GetNotificationPost renders the event. A generic notification is rendered when only the ID of the page is known,
+// and nil is returned for the events which are not supported.
+func (e *Event) GetNotificationPost() *model.Post {
+	post := &model.Post{
+		UserId: config.BotUserID,
+	}
+
+	if IsSpaceEvent(e.Type) {
+		return e.getSpaceNotificationPost(post)
+	}
+
+	if e.PageTitle == "" {
+		action, ok := eventActions[e.Type]
+		if !ok || e.PageID == "" {
+			return nil
+		}
+		post.Message = fmt.Sprintf(genericEventMessage, action, e.PageID)
+		return post
+	}
+
+	template, ok := eventMessages[e.Type]
+	if !ok {
+		return nil
+	}
+	message := fmt.Sprintf(template, e.actorName(), e.pageDisplayName())
+
+	text := e.excerptText()
+	if linkTemplate, ok := commentLinkMessages[e.Type]; ok && text == "" && e.CommentURL != "" {
+		message = fmt.Sprintf(linkTemplate, e.actorName(), e.CommentURL, e.pageDisplayName())
+	}
+	if space := e.spaceDisplayName(true); space != "" {
+		message += " in " + space
+	}
+	message += "."
+
+	if text == "" {
+		post.Message = message
+		return post
+	}
+
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Fallback: message,
+		Pretext:  message,
+		Text:     text,
+	}})
+	return post
+}
+
+// getSpaceNotificationPost renders the events about a space, with its description when it was created or updated.
+func (e *Event) getSpaceNotificationPost(post *model.Post) *model.Post {
+	// The removed space cannot be viewed anymore.
+	space := e.spaceDisplayName(e.Type != SpaceRemovedEvent)
+	if space == "" {
+		return nil
+	}
+	message := fmt.Sprintf(spaceEventMessages[e.Type], e.actorName(), space)
+
+	description := strings.TrimSpace(e.Excerpt)
+	if description == "" || (e.Type != SpaceCreatedEvent && e.Type != SpaceUpdatedEvent) {
+		post.Message = message
+		return post
+	}
+
+	text := fmt.Sprintf("> %s", description)
+	if e.SpaceURL != "" {
+		text += "\n\n" + fmt.Sprintf(viewInConfluence, e.SpaceURL)
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Fallback: message,
+		Pretext:  message,
+		Text:     text,
+	}})
+	return post
+}
+
+func (e *Event) actorName() string {
+	if e.Actor == "" {
+		return "Someone"
+	}
+	return e.Actor
+}
+
+// pageDisplayName links the page, unless it was removed.
+func (e *Event) pageDisplayName() string {
+	if e.PageURL == "" || e.Type == PageRemovedEvent {
+		return fmt.Sprintf("**%s**", e.PageTitle)
+	}
+	return fmt.Sprintf("[%s](%s)", e.PageTitle, e.PageURL)
+}
+
+func (e *Event) spaceDisplayName(withLink bool) string {
+	name := strings.TrimSpace(e.SpaceName)
+	if name == "" {
+		name = e.SpaceKey
+	}
+
+	switch {
+	case name == "":
+		return ""
+	case e.SpaceURL == "" || !withLink:
+		return fmt.Sprintf("**%s**", name)
+	default:
+		return fmt.Sprintf("[%s](%s)", name, e.SpaceURL)
+	}
+}
+
+// excerptText returns the excerpts of the content, empty when there are none.
+func (e *Event) excerptText() string {
+	excerpt := strings.TrimSpace(e.Excerpt)
+	parentExcerpt := strings.TrimSpace(e.ParentExcerpt)
+	if e.Type == PageRemovedEvent || (excerpt == "" && parentExcerpt == "") {
+		return ""
+	}
+
+	var sb strings.Builder
+	if excerpt != "" {
+		heading := excerptHeadings[e.Type]
+		if e.Type == CommentCreatedEvent && e.Actor != "" {
+			heading = fmt.Sprintf("**%s wrote:**", e.Actor)
+		}
+		if heading != "" {
+			sb.WriteString(heading + "\n")
+		}
+		fmt.Fprintf(&sb, "> %s\n\n", excerpt)
+	}
+	if parentExcerpt != "" {
+		fmt.Fprintf(&sb, "**In Reply to:**\n> %s\n\n", parentExcerpt)
+	}
+
+	// The removed content cannot be viewed anymore.
+	if link := e.contentURL(); link != "" && e.Type != CommentRemovedEvent {
+		fmt.Fprintf(&sb, viewInConfluence, link)
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+func (e *Event) contentURL() string {
+	if e.CommentURL != "" {
+		return e.CommentURL
+	}
+	return e.PageURL
+}
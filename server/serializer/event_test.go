@@ -0,0 +1,137 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventGetNotificationPost(t *testing.T) {
+	page := Event{
+		BaseURL:   "https://confluence.example.com",
+		SpaceKey:  "DEV",
+		SpaceName: "Development",
+		SpaceURL:  "https://confluence.example.com/display/DEV",
+		PageID:    "42",
+		PageTitle: "Release notes",
+		PageURL:   "https://confluence.example.com/x/42",
+	}
+	withType := func(event Event, eventType string) Event {
+		event.Type = eventType
+		return event
+	}
+
+	for name, tc := range map[string]struct {
+		event              Event
+		expectedMessage    string
+		expectedAttachment string
+	}{
+		"page created without excerpt by an unknown user": {
+			event:           withType(page, PageCreatedEvent),
+			expectedMessage: "Someone published a new page [Release notes](https://confluence.example.com/x/42) in [Development](https://confluence.example.com/display/DEV).",
+		},
+		"page updated with the changes": {
+			event: func() Event {
+				event := withType(page, PageUpdatedEvent)
+				event.Actor = "@jane"
+				event.Excerpt = "Fixed the typos"
+				return event
+			}(),
+			expectedMessage:    "@jane updated [Release notes](https://confluence.example.com/x/42) in [Development](https://confluence.example.com/display/DEV).",
+			expectedAttachment: "**What's Changed?**\n> Fixed the typos\n\n[**View in Confluence**](https://confluence.example.com/x/42)",
+		},
+		"page removed without link nor excerpt": {
+			event: func() Event {
+				event := withType(page, PageRemovedEvent)
+				event.Excerpt = "Old content"
+				event.SpaceName, event.SpaceURL = "", ""
+				return event
+			}(),
+			expectedMessage: "Someone removed **Release notes** in **DEV**.",
+		},
+		"comment created as a reply": {
+			event: func() Event {
+				event := withType(page, CommentCreatedEvent)
+				event.Actor = "Jane Doe"
+				event.CommentURL = "https://confluence.example.com/x/42#comment-43"
+				event.Excerpt = "Agreed"
+				event.ParentExcerpt = "Looks good"
+				return event
+			}(),
+			expectedMessage:    "Jane Doe commented on [Release notes](https://confluence.example.com/x/42) in [Development](https://confluence.example.com/display/DEV).",
+			expectedAttachment: "**Jane Doe wrote:**\n> Agreed\n\n**In Reply to:**\n> Looks good\n\n[**View in Confluence**](https://confluence.example.com/x/42#comment-43)",
+		},
+		"comment updated without excerpt": {
+			event: func() Event {
+				event := withType(page, CommentUpdatedEvent)
+				event.CommentURL = "https://confluence.example.com/x/42#comment-43"
+				return event
+			}(),
+			expectedMessage: "Someone updated a [comment](https://confluence.example.com/x/42#comment-43) on [Release notes](https://confluence.example.com/x/42) in [Development](https://confluence.example.com/display/DEV).",
+		},
+		"comment removed": {
+			event: func() Event {
+				event := withType(page, CommentRemovedEvent)
+				event.Excerpt = "Agreed"
+				return event
+			}(),
+			expectedMessage:    "Someone removed a comment from [Release notes](https://confluence.example.com/x/42) in [Development](https://confluence.example.com/display/DEV).",
+			expectedAttachment: "**Deleted Comment:**\n> Agreed",
+		},
+		"space created with its description": {
+			event: Event{
+				Type:      SpaceCreatedEvent,
+				SpaceKey:  "DEV",
+				SpaceName: "Development",
+				SpaceURL:  "https://confluence.example.com/display/DEV",
+				Actor:     "@jane",
+				Excerpt:   "Everything about development",
+			},
+			expectedMessage:    "@jane created the [Development](https://confluence.example.com/display/DEV) space.",
+			expectedAttachment: "> Everything about development\n\n[**View in Confluence**](https://confluence.example.com/display/DEV)",
+		},
+		"space permissions updated": {
+			event:           Event{Type: SpacePermissionsUpdatedEvent, SpaceKey: "DEV", SpaceURL: "https://confluence.example.com/display/DEV", Excerpt: "Everything about development"},
+			expectedMessage: "Someone updated the permissions of the [DEV](https://confluence.example.com/display/DEV) space.",
+		},
+		"space removed without link": {
+			event:           Event{Type: SpaceRemovedEvent, SpaceKey: "DEV", SpaceName: "Development", SpaceURL: "https://confluence.example.com/display/DEV"},
+			expectedMessage: "Someone removed the **Development** space.",
+		},
+		"generic notification when only the page ID is known": {
+			event:           Event{Type: PageTrashedEvent, BaseURL: "https://confluence.example.com", PageID: "42"},
+			expectedMessage: "Someone trashed a page on Confluence with the id 42",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			post := tc.event.GetNotificationPost()
+			require.NotNil(t, post)
+
+			if tc.expectedAttachment == "" {
+				assert.Equal(t, tc.expectedMessage, post.Message)
+				assert.Empty(t, post.Attachments())
+				return
+			}
+
+			assert.Empty(t, post.Message)
+			attachments := post.Attachments()
+			require.Len(t, attachments, 1)
+			assert.Equal(t, tc.expectedMessage, attachments[0].Pretext)
+			assert.Equal(t, tc.expectedAttachment, attachments[0].Text)
+		})
+	}
+}
+
+func TestEventGetNotificationPostUnsupported(t *testing.T) {
+	for name, event := range map[string]Event{
+		"unknown event":                   {Type: "label_added", PageID: "42", PageTitle: "Release notes"},
+		"generic comment notification":    {Type: CommentCreatedEvent, PageID: "42"},
+		"generic notification without ID": {Type: PageCreatedEvent},
+		"space event without space":       {Type: SpaceUpdatedEvent},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, event.GetNotificationPost())
+		})
+	}
+}
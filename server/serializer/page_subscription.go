@@ -93,6 +93,10 @@ func (ps PageSubscription) GetAlias() string {
 	return ps.Alias
 }
 
+func (ps PageSubscription) GetBaseSubscription() BaseSubscription {
+	return ps.BaseSubscription
+}
+
 func (ps PageSubscription) GetFormattedSubscription() string {
 	var events []string
 	for _, event := range ps.Events {
@@ -117,7 +121,12 @@ func (ps PageSubscription) IsValid() error {
 	if ps.ChannelID == "" {
 		return errors.New("channel id can not be empty")
 	}
-	return nil
+	for _, event := range ps.Events {
+		if IsSpaceEvent(event) {
+			return fmt.Errorf("the %s event can only be subscribed to for a space", EventDisplayName(event))
+		}
+	}
+	return ps.validateOptions()
 }
 
 func PageSubscriptionFromJSON(data io.Reader, subscriptionType string) (PageSubscription, error) {
@@ -0,0 +1,80 @@
+package serializer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// Quiet hours actions
+	QuietHoursActionDrop = "drop"
+	QuietHoursActionHold = "hold"
+
+	quietHoursTimeLayout = "15:04"
+)
+
+// QuietHours describes a daily window during which notifications for a subscription are not posted.
+// Start and End are wall-clock times ("HH:MM") in TimeZone. A window where End is before Start spans midnight.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"timeZone"`
+	Action   string `json:"action"`
+}
+
+func (q *QuietHours) IsValid() error {
+	if _, err := time.Parse(quietHoursTimeLayout, q.Start); err != nil {
+		return errors.New("quiet hours start must be in HH:MM format")
+	}
+	if _, err := time.Parse(quietHoursTimeLayout, q.End); err != nil {
+		return errors.New("quiet hours end must be in HH:MM format")
+	}
+	if q.Start == q.End {
+		return errors.New("quiet hours start and end can not be the same")
+	}
+	if _, err := time.LoadLocation(q.TimeZone); err != nil {
+		return fmt.Errorf("unknown quiet hours time zone %q", q.TimeZone)
+	}
+	if q.Action != QuietHoursActionDrop && q.Action != QuietHoursActionHold {
+		return fmt.Errorf("quiet hours action must be %q or %q", QuietHoursActionDrop, QuietHoursActionHold)
+	}
+	return nil
+}
+
+// Window reports whether t falls inside the quiet hours and, if it does, the time at which the window ends.
+func (q *QuietHours) Window(t time.Time) (bool, time.Time) {
+	location, err := time.LoadLocation(q.TimeZone)
+	if err != nil {
+		return false, time.Time{}
+	}
+	start, err := time.Parse(quietHoursTimeLayout, q.Start)
+	if err != nil {
+		return false, time.Time{}
+	}
+	end, err := time.Parse(quietHoursTimeLayout, q.End)
+	if err != nil {
+		return false, time.Time{}
+	}
+
+	local := t.In(location)
+	year, month, day := local.Date()
+	startToday := time.Date(year, month, day, start.Hour(), start.Minute(), 0, 0, location)
+	endToday := time.Date(year, month, day, end.Hour(), end.Minute(), 0, 0, location)
+
+	if startToday.Before(endToday) {
+		if !local.Before(startToday) && local.Before(endToday) {
+			return true, endToday
+		}
+		return false, time.Time{}
+	}
+
+	// The window spans midnight, e.g. 22:00 - 07:00.
+	if local.Before(endToday) {
+		return true, endToday
+	}
+	if !local.Before(startToday) {
+		return true, endToday.AddDate(0, 0, 1)
+	}
+	return false, time.Time{}
+}
@@ -0,0 +1,88 @@
+package serializer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuietHoursIsValid(t *testing.T) {
+	for name, val := range map[string]struct {
+		quietHours QuietHours
+		valid      bool
+	}{
+		"valid": {
+			quietHours: QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Berlin", Action: QuietHoursActionHold},
+			valid:      true,
+		},
+		"invalid start": {
+			quietHours: QuietHours{Start: "10pm", End: "07:00", TimeZone: "UTC", Action: QuietHoursActionDrop},
+		},
+		"same start and end": {
+			quietHours: QuietHours{Start: "07:00", End: "07:00", TimeZone: "UTC", Action: QuietHoursActionDrop},
+		},
+		"unknown time zone": {
+			quietHours: QuietHours{Start: "22:00", End: "07:00", TimeZone: "Mars/Olympus", Action: QuietHoursActionDrop},
+		},
+		"unknown action": {
+			quietHours: QuietHours{Start: "22:00", End: "07:00", TimeZone: "UTC", Action: "snooze"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := val.quietHours.IsValid()
+			if val.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestQuietHoursWindow(t *testing.T) {
+	overnight := QuietHours{Start: "22:00", End: "07:00", TimeZone: "UTC", Action: QuietHoursActionHold}
+	daytime := QuietHours{Start: "12:00", End: "13:30", TimeZone: "UTC", Action: QuietHoursActionDrop}
+
+	for name, val := range map[string]struct {
+		quietHours QuietHours
+		at         time.Time
+		inWindow   bool
+		windowEnd  time.Time
+	}{
+		"overnight window before midnight": {
+			quietHours: overnight,
+			at:         time.Date(2024, 5, 1, 23, 15, 0, 0, time.UTC),
+			inWindow:   true,
+			windowEnd:  time.Date(2024, 5, 2, 7, 0, 0, 0, time.UTC),
+		},
+		"overnight window after midnight": {
+			quietHours: overnight,
+			at:         time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC),
+			inWindow:   true,
+			windowEnd:  time.Date(2024, 5, 2, 7, 0, 0, 0, time.UTC),
+		},
+		"outside overnight window": {
+			quietHours: overnight,
+			at:         time.Date(2024, 5, 2, 7, 0, 0, 0, time.UTC),
+		},
+		"inside daytime window": {
+			quietHours: daytime,
+			at:         time.Date(2024, 5, 2, 12, 45, 0, 0, time.UTC),
+			inWindow:   true,
+			windowEnd:  time.Date(2024, 5, 2, 13, 30, 0, 0, time.UTC),
+		},
+		"outside daytime window": {
+			quietHours: daytime,
+			at:         time.Date(2024, 5, 2, 11, 59, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			inWindow, windowEnd := val.quietHours.Window(val.at)
+			assert.Equal(t, val.inWindow, inWindow)
+			if val.inWindow {
+				assert.True(t, val.windowEnd.Equal(windowEnd), "expected %s, got %s", val.windowEnd, windowEnd)
+			}
+		})
+	}
+}
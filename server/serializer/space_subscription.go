@@ -11,8 +11,16 @@ import (
 	"github.com/mattermost/mattermost-plugin-confluence/server/store"
 )
 
+const (
+	// What space subscriptions do with the events about pages that have view restrictions
+	RestrictedContentSkip   = "skip"
+	RestrictedContentRedact = "redact"
+)
+
 type SpaceSubscription struct {
 	SpaceKey string `json:"spaceKey"`
+	// RestrictedContent is RestrictedContentSkip or RestrictedContentRedact, restricted pages are posted as is when empty.
+	RestrictedContent string `json:"restrictedContent,omitempty"`
 	BaseSubscription
 }
 
@@ -93,6 +101,10 @@ func (ss SpaceSubscription) GetAlias() string {
 	return ss.Alias
 }
 
+func (ss SpaceSubscription) GetBaseSubscription() BaseSubscription {
+	return ss.BaseSubscription
+}
+
 func (ss SpaceSubscription) GetFormattedSubscription() string {
 	var events []string
 	for _, event := range ss.Events {
@@ -117,7 +129,10 @@ func (ss SpaceSubscription) IsValid() error {
 	if ss.ChannelID == "" {
 		return errors.New("channel id can not be empty")
 	}
-	return nil
+	if ss.RestrictedContent != "" && ss.RestrictedContent != RestrictedContentSkip && ss.RestrictedContent != RestrictedContentRedact {
+		return fmt.Errorf("restricted content must be %q or %q", RestrictedContentSkip, RestrictedContentRedact)
+	}
+	return ss.validateOptions()
 }
 
 func SpaceSubscriptionFromJSON(data io.Reader, subscriptionType string) (SpaceSubscription, error) {
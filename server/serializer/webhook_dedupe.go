@@ -0,0 +1,83 @@
+package serializer
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// webhookDedupeKey identifies a webhook delivery, so that the retries of the same delivery can be detected.
+func webhookDedupeKey(eventType, contentID string, version int, timestamp int64) string {
+	return fmt.Sprintf("%s/%s/%d/%d", eventType, contentID, version, timestamp)
+}
+
+// ContentID returns the ID of the comment or page the event is about, or the key of the space.
+func (e *ConfluenceCloudEvent) ContentID() string {
+	switch {
+	case e.Comment != nil:
+		return e.Comment.ID
+	case e.Page != nil:
+		return e.Page.ID
+	case e.Space != nil:
+		return e.Space.Key
+	}
+	return ""
+}
+
+// DedupeKey returns the key identifying the delivery of this event.
+func (e *ConfluenceCloudEvent) DedupeKey(eventType string) string {
+	version := 0
+	switch {
+	case e.Comment != nil:
+		version = e.Comment.Version
+	case e.Page != nil:
+		version = e.Page.Version
+	}
+	return webhookDedupeKey(eventType, e.ContentID(), version, int64(e.Timestamp))
+}
+
+// ContentID returns the ID of the comment, page or blog post the event is about, or the key of its space.
+func (e *ConfluenceServerEvent) ContentID() string {
+	switch {
+	case e.Comment != nil:
+		return e.Comment.ID
+	case e.Page != nil:
+		return e.Page.ID
+	case e.Blog != nil:
+		return e.Blog.ID
+	}
+	return e.Space.Key
+}
+
+// DedupeKey returns the key identifying the delivery of this event.
+func (e *ConfluenceServerEvent) DedupeKey() string {
+	version := 0
+	switch {
+	case e.Comment != nil:
+		version = e.Comment.Version
+	case e.Page != nil:
+		version = e.Page.Version
+	case e.Blog != nil:
+		version = e.Blog.Version
+	}
+	return webhookDedupeKey(e.Event, e.ContentID(), version, e.Timestamp)
+}
+
+// ContentID returns the ID of the comment, page or space the event is about.
+func (e *ConfluenceServerWebhookPayload) ContentID() string {
+	var contentID int64
+	switch {
+	case e.Comment.ID != 0:
+		contentID = e.Comment.ID
+	case e.Page.ID != 0:
+		contentID = e.Page.ID
+	default:
+		contentID = e.Space.ID
+	}
+	return strconv.FormatInt(contentID, 10)
+}
+
+// DedupeKey returns the key identifying the delivery of this event. Confluence Data Center 9 payloads do not include
+// the content version, the timestamp tells the versions apart.
+func (e *ConfluenceServerWebhookPayload) DedupeKey() string {
+	return webhookDedupeKey(e.Event, e.ContentID(), 0, e.Timestamp)
+}
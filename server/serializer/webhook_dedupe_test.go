@@ -0,0 +1,39 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupeKey(t *testing.T) {
+	for name, val := range map[string]struct {
+		key      string
+		expected string
+	}{
+		"cloud page event": {
+			key:      (&ConfluenceCloudEvent{Timestamp: 1000, Page: &Page{ID: "12", Version: 3}}).DedupeKey(PageUpdatedEvent),
+			expected: "page_updated/12/3/1000",
+		},
+		"cloud comment event": {
+			key:      (&ConfluenceCloudEvent{Timestamp: 1000, Page: &Page{ID: "12"}, Comment: &Comment{ID: "34", Version: 1}}).DedupeKey(CommentCreatedEvent),
+			expected: "comment_created/34/1/1000",
+		},
+		"server page event": {
+			key:      (&ConfluenceServerEvent{Event: PageUpdatedEvent, Timestamp: 2000, Page: &ConfluenceServerPage{ID: "56", Version: 2}}).DedupeKey(),
+			expected: "page_updated/56/2/2000",
+		},
+		"server space event": {
+			key:      (&ConfluenceServerEvent{Event: SpaceUpdatedEvent, Timestamp: 2000, Space: ConfluenceServerSpace{Key: "DEV"}}).DedupeKey(),
+			expected: "space_updated/DEV/0/2000",
+		},
+		"server webhook payload": {
+			key:      (&ConfluenceServerWebhookPayload{Event: "comment_created", Timestamp: 3000, Page: PagePayload{ID: 7}, Comment: CommentPayload{ID: 8}}).DedupeKey(),
+			expected: "comment_created/8/0/3000",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, val.expected, val.key)
+		})
+	}
+}
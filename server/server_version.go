@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+)
+
+const serverVersionTimeout = 10 * time.Second
+
+// detectServerVersion records the version of the Confluence instance in the configuration, along with whether it uses
+// the events and webhook payloads of version 9. The configuration is not saved.
+func detectServerVersion(configuration *config.Configuration) error {
+	version, err := service.GetConfluenceServerVersion(configuration.ConfluenceURL, &http.Client{Timeout: serverVersionTimeout})
+	if err != nil {
+		return err
+	}
+
+	isV9OrAbove, err := service.IsServerVersion9OrAbove(version)
+	if err != nil {
+		return err
+	}
+
+	configuration.ServerVersion = version
+	configuration.ServerVersionGreaterthan9 = isV9OrAbove
+	return nil
+}
+
+// refreshServerVersion detects the version of the configured instance again, since it may have been upgraded.
+func (p *Plugin) refreshServerVersion() {
+	current := config.GetConfig()
+	if current.ConfluenceURL == "" {
+		return
+	}
+
+	configuration := *current
+	if err := detectServerVersion(&configuration); err != nil {
+		p.client.Log.Warn("Unable to detect the Confluence server version", "ConfluenceURL", current.ConfluenceURL, "error", err.Error())
+		return
+	}
+	if configuration.ServerVersion == current.ServerVersion && configuration.ServerVersionGreaterthan9 == current.ServerVersionGreaterthan9 {
+		return
+	}
+
+	p.client.Log.Info("Detected a new Confluence server version", "ConfluenceURL", current.ConfluenceURL, "Version", configuration.ServerVersion)
+	if err := p.savePluginConfig(&configuration); err != nil {
+		p.client.Log.Error("Error saving the Confluence server version", "error", err.Error())
+	}
+}
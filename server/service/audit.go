@@ -0,0 +1,131 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+const (
+	// Audit log actions
+	AuditActionSubscriptionCreated = "subscription_created"
+	AuditActionSubscriptionEdited  = "subscription_edited"
+	AuditActionSubscriptionDeleted = "subscription_deleted"
+	AuditActionUserConnected       = "user_connected"
+	AuditActionUserDisconnected    = "user_disconnected"
+
+	// MaxAuditLogDays is the longest period that can be read from the audit log at once.
+	MaxAuditLogDays = 366
+)
+
+// AuditEntry records a single subscription or connection change. Entries are only ever appended,
+// grouped in one KV bucket per UTC day. UserID is the user whose connection changed, which is not always the actor.
+type AuditEntry struct {
+	ID        string          `json:"id"`
+	Timestamp int64           `json:"timestamp"`
+	Action    string          `json:"action"`
+	ActorID   string          `json:"actorID"`
+	UserID    string          `json:"userID,omitempty"`
+	ChannelID string          `json:"channelID,omitempty"`
+	Alias     string          `json:"alias,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+}
+
+// NewSubscriptionAuditEntry creates an audit entry for a subscription change. Either before or after can be nil.
+func NewSubscriptionAuditEntry(action, actorID, channelID string, before, after serializer.Subscription) AuditEntry {
+	entry := AuditEntry{
+		Action:    action,
+		ActorID:   actorID,
+		ChannelID: channelID,
+	}
+	if before != nil {
+		entry.Alias = before.GetAlias()
+		entry.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		entry.Alias = after.GetAlias()
+		entry.After, _ = json.Marshal(after)
+	}
+	return entry
+}
+
+// RecordAudit appends the entry to the audit log. Failures are logged, as they should never block the audited change.
+func RecordAudit(entry AuditEntry) {
+	if err := appendAuditEntry(entry, time.Now()); err != nil {
+		config.Mattermost.LogError("Unable to record audit log entry", "Action", entry.Action, "ActorID", entry.ActorID, "Error", err.Error())
+	}
+}
+
+func appendAuditEntry(entry AuditEntry, now time.Time) error {
+	entry.ID = model.NewId()
+	entry.Timestamp = now.UnixMilli()
+
+	return store.AtomicModify(store.GetAuditLogKey(now), func(initialBytes []byte) ([]byte, error) {
+		entries, err := auditEntriesFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(append(entries, entry))
+	})
+}
+
+// GetAuditEntries returns the audit entries recorded between since and until, oldest first.
+// If channelID is set, only the entries for that channel are returned.
+func GetAuditEntries(since, until time.Time, channelID string) ([]AuditEntry, error) {
+	if until.Sub(since) > MaxAuditLogDays*24*time.Hour {
+		return nil, errors.Errorf("the audit log can be read for at most %d days at once", MaxAuditLogDays)
+	}
+
+	entries := []AuditEntry{}
+	for _, day := range auditLogDays(since, until) {
+		data, appErr := config.Mattermost.KVGet(store.GetAuditLogKey(day))
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to read the audit log")
+		}
+
+		dayEntries, err := auditEntriesFromJSON(data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range dayEntries {
+			if entry.Timestamp < since.UnixMilli() || entry.Timestamp > until.UnixMilli() {
+				continue
+			}
+			if channelID != "" && entry.ChannelID != channelID {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// auditLogDays returns the UTC days between since and until, both included.
+func auditLogDays(since, until time.Time) []time.Time {
+	var days []time.Time
+	last := until.UTC().Truncate(24 * time.Hour)
+	for day := since.UTC().Truncate(24 * time.Hour); !day.After(last); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+func auditEntriesFromJSON(data []byte) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	if len(data) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
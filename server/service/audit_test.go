@@ -0,0 +1,83 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+func TestGetAuditEntries(t *testing.T) {
+	day1 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	buckets := map[string][]AuditEntry{
+		store.GetAuditLogKey(day1): {
+			{ID: "1", Timestamp: day1.UnixMilli(), Action: AuditActionSubscriptionCreated, ChannelID: testChannelID1},
+			{ID: "2", Timestamp: day1.Add(time.Hour).UnixMilli(), Action: AuditActionUserConnected},
+		},
+		store.GetAuditLogKey(day2): {
+			{ID: "3", Timestamp: day2.UnixMilli(), Action: AuditActionSubscriptionDeleted, ChannelID: testChannelID1},
+		},
+	}
+
+	for name, val := range map[string]struct {
+		since     time.Time
+		until     time.Time
+		channelID string
+		expected  []string
+		isError   bool
+	}{
+		"all entries": {
+			since:    day1.Add(-time.Hour),
+			until:    day2.Add(time.Hour),
+			expected: []string{"1", "2", "3"},
+		},
+		"filtered by channel": {
+			since:     day1.Add(-time.Hour),
+			until:     day2.Add(time.Hour),
+			channelID: testChannelID1,
+			expected:  []string{"1", "3"},
+		},
+		"filtered by time": {
+			since:    day1.Add(30 * time.Minute),
+			until:    day2.Add(time.Hour),
+			expected: []string{"2", "3"},
+		},
+		"period too long": {
+			since:   day1.AddDate(-2, 0, 0),
+			until:   day2,
+			isError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+				entries, ok := buckets[key]
+				if !ok {
+					return nil
+				}
+				data, _ := json.Marshal(entries)
+				return data
+			}, nil)
+
+			entries, err := GetAuditEntries(val.since, val.until, val.channelID)
+			if val.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var ids []string
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+			}
+			assert.Equal(t, val.expected, ids)
+		})
+	}
+}
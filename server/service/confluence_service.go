@@ -9,10 +9,12 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
 )
 
 const ErrorStatusNotFound = "No content found"
@@ -134,11 +136,14 @@ func call(basePath, method, path, contentType string, inBody io.Reader, out inte
 		req.Header.Set("Content-Type", contentType)
 	}
 
+	start := time.Now()
 	resp, err := httpClient.Do(req)
 	if err != nil {
+		metrics.ObserveAPIRequest(pathURL.String(), 0, time.Since(start))
 		return nil, 0, errors.Wrap(err, "request failed")
 	}
 	defer resp.Body.Close()
+	metrics.ObserveAPIRequest(pathURL.String(), resp.StatusCode, time.Since(start))
 
 	statusCode = resp.StatusCode
 
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+// NotificationDetails carries the event attributes that subscription delivery options are evaluated against.
+type NotificationDetails struct {
+	BaseURL     string
+	SpaceKey    string
+	PageID      string
+	EventType   string
+	IsMinorEdit bool
+	// IsRestricted tells whether the page has view restrictions. It is only called when a matching space
+	// subscription filters restricted content. The page is treated as restricted when it is nil or fails.
+	IsRestricted func() (bool, error)
+}
+
+// restrictedEventMessages replace the notifications about restricted pages for the subscriptions redacting them.
+var restrictedEventMessages = map[string]string{
+	serializer.PageCreatedEvent:    "A restricted page was created in the **%s** space.",
+	serializer.PageUpdatedEvent:    "A restricted page was updated in the **%s** space.",
+	serializer.PageTrashedEvent:    "A restricted page was trashed in the **%s** space.",
+	serializer.PageRestoredEvent:   "A restricted page was restored in the **%s** space.",
+	serializer.PageRemovedEvent:    "A restricted page was removed from the **%s** space.",
+	serializer.CommentCreatedEvent: "A comment was posted on a restricted page in the **%s** space.",
+	serializer.CommentUpdatedEvent: "A comment was updated on a restricted page in the **%s** space.",
+	serializer.CommentRemovedEvent: "A comment was removed from a restricted page in the **%s** space.",
+}
+
+// GetMatchingSubscriptionsWithDeps returns the subscriptions matching the event, grouped by channel ID. Only the
+// channels found through the space key and page ID indexes are looked at.
+func GetMatchingSubscriptionsWithDeps(details NotificationDetails, repo SubscriptionRepository) (map[string][]serializer.Subscription, error) {
+	subscriptions, err := repo.GetSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	spaceKeyCombination := store.GetURLSpaceKeyCombinationKey(details.BaseURL, details.SpaceKey)
+	pageIDCombination := store.GetURLPageIDCombinationKey(details.BaseURL, details.PageID)
+
+	var channelIDs []string
+	if details.SpaceKey != "" {
+		channelIDs = append(channelIDs, subscribedChannelIDs(subscriptions.ByURLSpaceKey[spaceKeyCombination], details.EventType)...)
+	}
+	if details.PageID != "" {
+		channelIDs = append(channelIDs, subscribedChannelIDs(subscriptions.ByURLPageID[pageIDCombination], details.EventType)...)
+	}
+
+	matching := make(map[string][]serializer.Subscription)
+	for _, channelID := range util.Deduplicate(channelIDs) {
+		for _, subscription := range subscriptions.ByChannelID[channelID] {
+			base := subscription.GetBaseSubscription()
+			if !slices.Contains(base.Events, details.EventType) {
+				continue
+			}
+
+			switch sub := subscription.(type) {
+			case serializer.SpaceSubscription:
+				if details.SpaceKey == "" || store.GetURLSpaceKeyCombinationKey(sub.BaseURL, sub.SpaceKey) != spaceKeyCombination {
+					continue
+				}
+			case serializer.PageSubscription:
+				if details.PageID == "" || store.GetURLPageIDCombinationKey(sub.BaseURL, sub.PageID) != pageIDCombination {
+					continue
+				}
+			default:
+				continue
+			}
+
+			matching[channelID] = append(matching[channelID], subscription)
+		}
+	}
+
+	return matching, nil
+}
+
+// subscribedChannelIDs returns the channels of the index entry that are subscribed to the event.
+func subscribedChannelIDs(index serializer.StringArrayMap, eventType string) []string {
+	var channelIDs []string
+	for channelID, events := range index {
+		if slices.Contains(events, eventType) {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	return channelIDs
+}
+
+// deliveryDecision applies the delivery options of the subscriptions matching an event in a single channel.
+// The post is delivered right away if any subscription accepts it, otherwise it is held until the earliest
+// end of a "hold" quiet hours window. A zero holdUntil with deliver set to false means the post is dropped.
+// heldBy has the aliases of the subscriptions holding the post.
+func deliveryDecision(subscriptions []serializer.Subscription, details NotificationDetails, now time.Time) (deliver bool, holdUntil time.Time, heldBy []string) {
+	for _, subscription := range subscriptions {
+		base := subscription.GetBaseSubscription()
+		if base.SkipMinorEdits && details.IsMinorEdit {
+			continue
+		}
+
+		if base.QuietHours == nil {
+			return true, time.Time{}, nil
+		}
+
+		inWindow, windowEnd := base.QuietHours.Window(now)
+		if !inWindow {
+			return true, time.Time{}, nil
+		}
+
+		if base.QuietHours.Action == serializer.QuietHoursActionHold {
+			heldBy = append(heldBy, base.Alias)
+			if holdUntil.IsZero() || windowEnd.Before(holdUntil) {
+				holdUntil = windowEnd
+			}
+		}
+	}
+
+	return false, holdUntil, heldBy
+}
+
+// restrictionCheck returns whether the page of the event has view restrictions, asking Confluence at most once.
+// Events without a page are never restricted.
+func restrictionCheck(details NotificationDetails) func() bool {
+	checked, restricted := false, false
+	return func() bool {
+		if checked {
+			return restricted
+		}
+		checked = true
+
+		switch {
+		case details.PageID == "":
+			restricted = false
+		case details.IsRestricted == nil:
+			restricted = true
+		default:
+			var err error
+			if restricted, err = details.IsRestricted(); err != nil {
+				config.Mattermost.LogWarn("Unable to check the view restrictions of the page, treating it as restricted", "PageID", details.PageID, "Error", err.Error())
+				restricted = true
+			}
+		}
+		return restricted
+	}
+}
+
+// filterRestrictedContent returns the subscriptions of a channel that accept the notification as is. When none do
+// but some accept a redacted notice, they are returned with redact set to true.
+func filterRestrictedContent(subscriptions []serializer.Subscription, isRestricted func() bool) (filtered []serializer.Subscription, redact bool) {
+	var redacting []serializer.Subscription
+	for _, subscription := range subscriptions {
+		spaceSubscription, ok := subscription.(serializer.SpaceSubscription)
+		if !ok || spaceSubscription.RestrictedContent == "" || !isRestricted() {
+			filtered = append(filtered, subscription)
+			continue
+		}
+		if spaceSubscription.RestrictedContent == serializer.RestrictedContentRedact {
+			redacting = append(redacting, subscription)
+		}
+	}
+
+	if len(filtered) > 0 || len(redacting) == 0 {
+		return filtered, false
+	}
+	return redacting, true
+}
+
+// redactedPost returns a notice about the event which does not reveal anything about the restricted page.
+func redactedPost(post *model.Post, details NotificationDetails) *model.Post {
+	message, ok := restrictedEventMessages[details.EventType]
+	if !ok {
+		message = "A restricted page was changed in the **%s** space."
+	}
+	return &model.Post{
+		UserId:  post.UserId,
+		Message: fmt.Sprintf(message, details.SpaceKey),
+	}
+}
+
+// DeliveryResult tells how a notification was delivered to the subscribed channels.
+type DeliveryResult struct {
+	MatchedChannels int
+	// Err is the last error hit while delivering, the remaining channels are still notified.
+	Err error
+}
+
+func deliverNotificationWithDeps(post *model.Post, details NotificationDetails, now time.Time, repo SubscriptionRepository) DeliveryResult {
+	matching, err := GetMatchingSubscriptionsWithDeps(details, repo)
+	if err != nil {
+		config.Mattermost.LogError("Unable to get subscribed channels.", "Error", err.Error())
+		metrics.DeliveryFailures.WithLabelValues(metrics.StageSubscriptions).Inc()
+		return DeliveryResult{Err: errors.Wrap(err, "unable to get subscribed channels")}
+	}
+
+	result := DeliveryResult{MatchedChannels: len(matching)}
+	isRestricted := restrictionCheck(details)
+	for channelID, subscriptions := range matching {
+		subscriptions, redact := filterRestrictedContent(subscriptions, isRestricted)
+		if len(subscriptions) == 0 {
+			config.Mattermost.LogDebug("Notification about a restricted page skipped", "ChannelID", channelID, "EventType", details.EventType)
+			continue
+		}
+
+		channelPost := post
+		if redact {
+			channelPost = redactedPost(post, details)
+		}
+
+		deliver, holdUntil, heldBy := deliveryDecision(subscriptions, details, now)
+		switch {
+		case deliver:
+			channelPost.ChannelId = channelID
+			if _, appErr := config.Mattermost.CreatePost(channelPost); appErr != nil {
+				config.Mattermost.LogError("Unable to create Post in Mattermost", "Error", appErr.Error())
+				metrics.DeliveryFailures.WithLabelValues(metrics.StagePost).Inc()
+				result.Err = errors.Wrapf(appErr, "unable to post in channel %s", channelID)
+			} else {
+				metrics.NotificationsPosted.WithLabelValues(details.EventType).Inc()
+			}
+		case !holdUntil.IsZero():
+			if hErr := HoldNotification(channelPost, channelID, holdUntil, heldBy); hErr != nil {
+				config.Mattermost.LogError("Unable to hold notification until the end of quiet hours", "ChannelID", channelID, "Error", hErr.Error())
+				metrics.DeliveryFailures.WithLabelValues(metrics.StageHold).Inc()
+				result.Err = errors.Wrapf(hErr, "unable to hold the notification for channel %s", channelID)
+			}
+		default:
+			config.Mattermost.LogDebug("Notification suppressed by subscription delivery options", "ChannelID", channelID, "EventType", details.EventType)
+		}
+	}
+
+	return result
+}
+
+// DeliverNotification posts the notification to every subscribed channel,
+// honoring the restricted content, minor edit and quiet hours options of the matching subscriptions.
+func DeliverNotification(post *model.Post, details NotificationDetails) DeliveryResult {
+	return deliverNotificationWithDeps(post, details, time.Now(), NewDefaultSubscriptionRepository())
+}
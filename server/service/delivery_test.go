@@ -0,0 +1,212 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service/mocks"
+)
+
+func TestGetMatchingSubscriptions(t *testing.T) {
+	for name, val := range map[string]struct {
+		details  NotificationDetails
+		expected map[string]int
+	}{
+		"space and page subscriptions": {
+			details: NotificationDetails{
+				BaseURL:   testBaseURL,
+				SpaceKey:  testSpaceKey1,
+				PageID:    testPageID2,
+				EventType: serializer.CommentUpdatedEvent,
+			},
+			expected: map[string]int{testChannelID1: 2, testChannelID2: 1},
+		},
+		"event not subscribed": {
+			details: NotificationDetails{
+				BaseURL:   testBaseURL,
+				SpaceKey:  testSpaceKey2,
+				PageID:    testPageID1,
+				EventType: serializer.PageCreatedEvent,
+			},
+			expected: map[string]int{},
+		},
+		"page only": {
+			details: NotificationDetails{
+				BaseURL:   testBaseURL,
+				PageID:    testPageID1,
+				EventType: serializer.CommentCreatedEvent,
+			},
+			expected: map[string]int{testChannelID2: 1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockSubscriptionRepository(ctrl)
+			mockRepo.EXPECT().GetSubscriptions().Return(getExtendedTestSubscriptions(), nil).AnyTimes()
+
+			matching, err := GetMatchingSubscriptionsWithDeps(val.details, mockRepo)
+			assert.Nil(t, err)
+			assert.Equal(t, len(val.expected), len(matching))
+			for channelID, count := range val.expected {
+				assert.Len(t, matching[channelID], count)
+			}
+		})
+	}
+}
+
+func TestDeliveryDecision(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	quietHoursEnd := time.Date(2024, 5, 2, 7, 0, 0, 0, time.UTC)
+
+	subscriptionWith := func(skipMinorEdits bool, quietHours *serializer.QuietHours) serializer.Subscription {
+		return serializer.SpaceSubscription{
+			SpaceKey: testSpaceKey1,
+			BaseSubscription: serializer.BaseSubscription{
+				Alias:          testAliasSpace1,
+				BaseURL:        testBaseURL,
+				ChannelID:      testChannelID1,
+				Events:         []string{serializer.PageUpdatedEvent},
+				SkipMinorEdits: skipMinorEdits,
+				QuietHours:     quietHours,
+			},
+		}
+	}
+	hold := &serializer.QuietHours{Start: "22:00", End: "07:00", TimeZone: "UTC", Action: serializer.QuietHoursActionHold}
+	drop := &serializer.QuietHours{Start: "22:00", End: "07:00", TimeZone: "UTC", Action: serializer.QuietHoursActionDrop}
+	daytime := &serializer.QuietHours{Start: "09:00", End: "17:00", TimeZone: "UTC", Action: serializer.QuietHoursActionDrop}
+
+	for name, val := range map[string]struct {
+		subscriptions []serializer.Subscription
+		isMinorEdit   bool
+		deliver       bool
+		holdUntil     time.Time
+	}{
+		"no options": {
+			subscriptions: []serializer.Subscription{subscriptionWith(false, nil)},
+			isMinorEdit:   true,
+			deliver:       true,
+		},
+		"minor edit skipped": {
+			subscriptions: []serializer.Subscription{subscriptionWith(true, nil)},
+			isMinorEdit:   true,
+		},
+		"major edit delivered": {
+			subscriptions: []serializer.Subscription{subscriptionWith(true, nil)},
+			deliver:       true,
+		},
+		"quiet hours drop": {
+			subscriptions: []serializer.Subscription{subscriptionWith(false, drop)},
+		},
+		"quiet hours hold": {
+			subscriptions: []serializer.Subscription{subscriptionWith(false, hold)},
+			holdUntil:     quietHoursEnd,
+		},
+		"outside quiet hours": {
+			subscriptions: []serializer.Subscription{subscriptionWith(false, daytime)},
+			deliver:       true,
+		},
+		"any subscription delivering wins": {
+			subscriptions: []serializer.Subscription{subscriptionWith(false, hold), subscriptionWith(false, nil)},
+			deliver:       true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			deliver, holdUntil, _ := deliveryDecision(val.subscriptions, NotificationDetails{EventType: serializer.PageUpdatedEvent, IsMinorEdit: val.isMinorEdit}, now)
+			assert.Equal(t, val.deliver, deliver)
+			assert.True(t, val.holdUntil.Equal(holdUntil), "expected %s, got %s", val.holdUntil, holdUntil)
+		})
+	}
+}
+
+func TestFilterRestrictedContent(t *testing.T) {
+	subscriptionWith := func(restrictedContent string) serializer.Subscription {
+		return serializer.SpaceSubscription{
+			SpaceKey:          testSpaceKey1,
+			RestrictedContent: restrictedContent,
+			BaseSubscription: serializer.BaseSubscription{
+				Alias:     testAliasSpace1,
+				BaseURL:   testBaseURL,
+				ChannelID: testChannelID1,
+				Events:    []string{serializer.PageUpdatedEvent},
+			},
+		}
+	}
+	pageSubscription := serializer.PageSubscription{
+		PageID: testPageID1,
+		BaseSubscription: serializer.BaseSubscription{
+			Alias:     testAliasPage1,
+			BaseURL:   testBaseURL,
+			ChannelID: testChannelID1,
+			Events:    []string{serializer.PageUpdatedEvent},
+		},
+	}
+
+	for name, val := range map[string]struct {
+		subscriptions  []serializer.Subscription
+		restricted     bool
+		expectedCount  int
+		expectedRedact bool
+		expectChecked  bool
+	}{
+		"option not set": {
+			subscriptions: []serializer.Subscription{subscriptionWith("")},
+			restricted:    true,
+			expectedCount: 1,
+		},
+		"page not restricted": {
+			subscriptions: []serializer.Subscription{subscriptionWith(serializer.RestrictedContentSkip)},
+			expectedCount: 1,
+			expectChecked: true,
+		},
+		"restricted page skipped": {
+			subscriptions: []serializer.Subscription{subscriptionWith(serializer.RestrictedContentSkip)},
+			restricted:    true,
+			expectChecked: true,
+		},
+		"restricted page redacted": {
+			subscriptions:  []serializer.Subscription{subscriptionWith(serializer.RestrictedContentSkip), subscriptionWith(serializer.RestrictedContentRedact)},
+			restricted:     true,
+			expectedCount:  1,
+			expectedRedact: true,
+			expectChecked:  true,
+		},
+		"full post wins over the redacted notice": {
+			subscriptions: []serializer.Subscription{subscriptionWith(serializer.RestrictedContentRedact), pageSubscription},
+			restricted:    true,
+			expectedCount: 1,
+			expectChecked: true,
+		},
+		"page subscriptions are not filtered": {
+			subscriptions: []serializer.Subscription{pageSubscription},
+			restricted:    true,
+			expectedCount: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			checked := false
+			filtered, redact := filterRestrictedContent(val.subscriptions, func() bool {
+				checked = true
+				return val.restricted
+			})
+			assert.Len(t, filtered, val.expectedCount)
+			assert.Equal(t, val.expectedRedact, redact)
+			assert.Equal(t, val.expectChecked, checked)
+		})
+	}
+}
+
+func TestRedactedPost(t *testing.T) {
+	post := &model.Post{UserId: "bot-id", Message: "[Release notes](https://example.com/pages/42) was updated"}
+
+	redacted := redactedPost(post, NotificationDetails{SpaceKey: testSpaceKey1, EventType: serializer.CommentCreatedEvent})
+	assert.Equal(t, "bot-id", redacted.UserId)
+	assert.Equal(t, "A comment was posted on a restricted page in the **"+testSpaceKey1+"** space.", redacted.Message)
+	assert.Empty(t, redacted.Attachments())
+}
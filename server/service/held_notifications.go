@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+// maxHeldNotifications is the number of held notifications kept in the KV store. Once it is reached, the oldest
+// notifications are dropped.
+const maxHeldNotifications = 500
+
+// HeldNotification is a post that was held back by subscription quiet hours.
+type HeldNotification struct {
+	ChannelID     string      `json:"channelID"`
+	Post          *model.Post `json:"post"`
+	ReleaseAt     int64       `json:"releaseAt"`
+	Subscriptions []string    `json:"subscriptions,omitempty"` // Aliases of the subscriptions whose quiet hours hold the post
+}
+
+func heldNotificationsFromJSON(data []byte) ([]HeldNotification, error) {
+	var held []HeldNotification
+	if len(data) == 0 {
+		return held, nil
+	}
+	if err := json.Unmarshal(data, &held); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+// HoldNotification stores a copy of the post so that it can be posted in the channel once the quiet hours of the
+// subscriptions end.
+func HoldNotification(post *model.Post, channelID string, releaseAt time.Time, subscriptions []string) error {
+	heldPost := post.Clone()
+	heldPost.ChannelId = channelID
+
+	return store.AtomicModify(store.GetHeldNotificationsKey(), func(initialBytes []byte) ([]byte, error) {
+		held, err := heldNotificationsFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		held = append(held, HeldNotification{
+			ChannelID:     channelID,
+			Post:          heldPost,
+			ReleaseAt:     releaseAt.UnixMilli(),
+			Subscriptions: subscriptions,
+		})
+		if dropped := len(held) - maxHeldNotifications; dropped > 0 {
+			config.Mattermost.LogWarn("Too many held notifications, dropping the oldest ones", "Dropped", dropped)
+			held = held[dropped:]
+		}
+		return json.Marshal(held)
+	})
+}
+
+// ReleaseHeldNotifications posts every held notification whose quiet hours have ended by now.
+func ReleaseHeldNotifications(now time.Time) {
+	releaseHeldNotificationsWithDeps(now, NewDefaultSubscriptionRepository())
+}
+
+func releaseHeldNotificationsWithDeps(now time.Time, repo SubscriptionRepository) {
+	data, appErr := config.Mattermost.KVGet(store.GetHeldNotificationsKey())
+	if appErr != nil {
+		config.Mattermost.LogError("Unable to load held notifications", "Error", appErr.Error())
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	// The subscriptions are loaded before releasing anything, so that the notifications are kept for the next run
+	// when they cannot be checked.
+	subscriptions, err := repo.GetSubscriptions()
+	if err != nil {
+		config.Mattermost.LogError("Unable to load the subscriptions to release held notifications", "Error", err.Error())
+		return
+	}
+
+	var released []HeldNotification
+	err = store.AtomicModify(store.GetHeldNotificationsKey(), func(initialBytes []byte) ([]byte, error) {
+		held, err := heldNotificationsFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		released = nil
+		remaining := make([]HeldNotification, 0, len(held))
+		for _, notification := range held {
+			if notification.ReleaseAt <= now.UnixMilli() {
+				released = append(released, notification)
+			} else {
+				remaining = append(remaining, notification)
+			}
+		}
+
+		if len(released) == 0 {
+			return initialBytes, nil
+		}
+		return json.Marshal(remaining)
+	})
+	if err != nil {
+		config.Mattermost.LogError("Unable to release held notifications", "Error", err.Error())
+		return
+	}
+
+	for _, notification := range released {
+		if !notification.stillHeld(subscriptions) {
+			config.Mattermost.LogDebug("Held notification dropped, its subscriptions were deleted or have no quiet hours anymore", "ChannelID", notification.ChannelID)
+			continue
+		}
+
+		notification.Post.ChannelId = notification.ChannelID
+		if _, cErr := config.Mattermost.CreatePost(notification.Post); cErr != nil {
+			config.Mattermost.LogError("Unable to create held Post in Mattermost", "ChannelID", notification.ChannelID, "Error", cErr.Error())
+		}
+	}
+}
+
+// stillHeld returns whether one of the subscriptions holding the notification still exists with quiet hours. The
+// notifications held before their subscriptions were recorded are always released.
+func (n HeldNotification) stillHeld(subscriptions serializer.Subscriptions) bool {
+	if len(n.Subscriptions) == 0 {
+		return true
+	}
+
+	for _, alias := range n.Subscriptions {
+		if subscription, ok := subscriptions.ByChannelID[n.ChannelID].GetInsensitiveCase(alias); ok && subscription.GetBaseSubscription().QuietHours != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service/mocks"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+func TestHoldNotification(t *testing.T) {
+	for name, tc := range map[string]struct {
+		held          int
+		expectedFirst string
+		expectedCount int
+	}{
+		"first notification": {
+			expectedFirst: "new",
+			expectedCount: 1,
+		},
+		"below the cap": {
+			held:          10,
+			expectedFirst: "post-0",
+			expectedCount: 11,
+		},
+		"cap reached": {
+			held:          maxHeldNotifications,
+			expectedFirst: "post-1",
+			expectedCount: maxHeldNotifications,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var held []HeldNotification
+			for i := 0; i < tc.held; i++ {
+				held = append(held, HeldNotification{ChannelID: "channel-id", Post: &model.Post{Message: fmt.Sprintf("post-%d", i)}})
+			}
+			var initial []byte
+			if len(held) > 0 {
+				initial, _ = json.Marshal(held)
+			}
+
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			mockAPI.On("LogWarn", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Maybe()
+			mockAPI.On("KVGet", store.GetHeldNotificationsKey()).Return(initial, nil)
+			var stored []byte
+			mockAPI.On("KVCompareAndSet", store.GetHeldNotificationsKey(), initial, mock.Anything).Run(func(args mock.Arguments) {
+				stored = args.Get(2).([]byte)
+			}).Return(true, nil)
+
+			require.NoError(t, HoldNotification(&model.Post{Message: "new"}, "channel-id", time.Now(), []string{"alias"}))
+
+			result, err := heldNotificationsFromJSON(stored)
+			require.NoError(t, err)
+			assert.Len(t, result, tc.expectedCount)
+			assert.Equal(t, tc.expectedFirst, result[0].Post.Message)
+			assert.Equal(t, "new", result[len(result)-1].Post.Message)
+		})
+	}
+}
+
+func TestReleaseHeldNotifications(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	quietHours := &serializer.QuietHours{Start: "22:00", End: "08:00", TimeZone: "UTC", Action: serializer.QuietHoursActionHold}
+	subscriptions := serializer.Subscriptions{ByChannelID: map[string]serializer.StringSubscription{
+		"channel-id": {
+			"Quiet": serializer.SpaceSubscription{SpaceKey: "KEY", BaseSubscription: serializer.BaseSubscription{Alias: "Quiet", ChannelID: "channel-id", QuietHours: quietHours}},
+			"Loud":  serializer.SpaceSubscription{SpaceKey: "KEY", BaseSubscription: serializer.BaseSubscription{Alias: "Loud", ChannelID: "channel-id"}},
+		},
+	}}
+
+	held := []HeldNotification{
+		{ChannelID: "channel-id", Post: &model.Post{Message: "held by an existing subscription"}, ReleaseAt: now.Add(-time.Hour).UnixMilli(), Subscriptions: []string{"quiet"}},
+		{ChannelID: "channel-id", Post: &model.Post{Message: "held by a deleted subscription"}, ReleaseAt: now.Add(-time.Hour).UnixMilli(), Subscriptions: []string{"deleted"}},
+		{ChannelID: "channel-id", Post: &model.Post{Message: "held by a subscription without quiet hours anymore"}, ReleaseAt: now.Add(-time.Hour).UnixMilli(), Subscriptions: []string{"Loud"}},
+		{ChannelID: "other-channel-id", Post: &model.Post{Message: "held in a channel without the subscription"}, ReleaseAt: now.Add(-time.Hour).UnixMilli(), Subscriptions: []string{"Quiet"}},
+		{ChannelID: "channel-id", Post: &model.Post{Message: "held without its subscriptions"}, ReleaseAt: now.Add(-time.Hour).UnixMilli()},
+		{ChannelID: "channel-id", Post: &model.Post{Message: "still in the quiet hours"}, ReleaseAt: now.Add(time.Hour).UnixMilli(), Subscriptions: []string{"Quiet"}},
+	}
+	initial, _ := json.Marshal(held)
+
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	mockAPI.On("KVGet", store.GetHeldNotificationsKey()).Return(initial, nil)
+	var stored []byte
+	mockAPI.On("KVCompareAndSet", store.GetHeldNotificationsKey(), initial, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil)
+	mockAPI.On("LogDebug", mock.AnythingOfType("string"), "ChannelID", mock.Anything).Times(3)
+	var posted []string
+	mockAPI.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		posted = append(posted, args.Get(0).(*model.Post).Message)
+	}).Return(&model.Post{}, nil)
+
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockSubscriptionRepository(ctrl)
+	mockRepo.EXPECT().GetSubscriptions().Return(subscriptions, nil)
+
+	releaseHeldNotificationsWithDeps(now, mockRepo)
+
+	assert.Equal(t, []string{"held by an existing subscription", "held without its subscriptions"}, posted)
+	remaining, err := heldNotificationsFromJSON(stored)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "still in the quiet hours", remaining[0].Post.Message)
+	mockAPI.AssertExpectations(t)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+const (
+	// Subscription export formats
+	ExportFormatJSON = "json"
+	ExportFormatYAML = "yaml"
+
+	subscriptionsExportVersion = 1
+)
+
+// SubscriptionsExport is the document produced by an export and accepted by an import.
+type SubscriptionsExport struct {
+	Version       int                       `json:"version"`
+	Subscriptions []serializer.Subscription `json:"subscriptions"`
+}
+
+type subscriptionsImport struct {
+	Version       int               `json:"version"`
+	Subscriptions []json.RawMessage `json:"subscriptions"`
+}
+
+// ImportResult describes the outcome of an import. Each entry is a human readable line about a single subscription.
+type ImportResult struct {
+	DryRun    bool                      `json:"dryRun"`
+	Imported  []serializer.Subscription `json:"imported"`
+	Conflicts []string                  `json:"conflicts"`
+	Invalid   []string                  `json:"invalid"`
+}
+
+// ExportSubscriptionsWithDeps returns the subscriptions of the channel, or all the subscriptions if channelID is empty.
+func ExportSubscriptionsWithDeps(channelID string, repo SubscriptionRepository) (*SubscriptionsExport, error) {
+	subscriptions, err := repo.GetSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	export := &SubscriptionsExport{
+		Version:       subscriptionsExportVersion,
+		Subscriptions: []serializer.Subscription{},
+	}
+	for subscriptionChannelID, channelSubscriptions := range subscriptions.ByChannelID {
+		if channelID != "" && subscriptionChannelID != channelID {
+			continue
+		}
+		for _, subscription := range channelSubscriptions {
+			export.Subscriptions = append(export.Subscriptions, subscription)
+		}
+	}
+
+	sortSubscriptions(export.Subscriptions)
+	return export, nil
+}
+
+// ExportSubscriptions returns the subscriptions of the channel, or all the subscriptions if channelID is empty.
+func ExportSubscriptions(channelID string) (*SubscriptionsExport, error) {
+	return ExportSubscriptionsWithDeps(channelID, NewDefaultSubscriptionRepository())
+}
+
+// EncodeSubscriptionsExport encodes the export in the given format.
+func EncodeSubscriptionsExport(export *SubscriptionsExport, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case ExportFormatJSON:
+		return data, nil
+	case ExportFormatYAML:
+		// Going through JSON keeps the field names identical in both formats.
+		var document interface{}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(document)
+	default:
+		return nil, errors.Errorf("unsupported export format %q", format)
+	}
+}
+
+// DecodeSubscriptionsImport parses an export document. JSON being valid YAML, both formats are accepted.
+func DecodeSubscriptionsImport(data []byte) ([]serializer.Subscription, []string, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, errors.Wrap(err, "the file is neither valid JSON nor valid YAML")
+	}
+
+	jsonData, err := json.Marshal(document)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to read the file")
+	}
+
+	var doc subscriptionsImport
+	if err := json.Unmarshal(jsonData, &doc); err != nil {
+		return nil, nil, errors.Wrap(err, "the file is not a subscriptions export")
+	}
+	if doc.Version != subscriptionsExportVersion {
+		return nil, nil, errors.Errorf("unsupported export version %d", doc.Version)
+	}
+
+	var subscriptions []serializer.Subscription
+	var invalid []string
+	for i, raw := range doc.Subscriptions {
+		subscription, err := decodeSubscription(raw)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("entry %d: %s", i+1, err.Error()))
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, invalid, nil
+}
+
+func decodeSubscription(data []byte) (serializer.Subscription, error) {
+	var base serializer.BaseSubscription
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	switch base.Type {
+	case serializer.SubscriptionTypeSpace:
+		var subscription serializer.SpaceSubscription
+		if err := json.Unmarshal(data, &subscription); err != nil {
+			return nil, err
+		}
+		return subscription, nil
+	case serializer.SubscriptionTypePage:
+		var subscription serializer.PageSubscription
+		if err := json.Unmarshal(data, &subscription); err != nil {
+			return nil, err
+		}
+		return subscription, nil
+	default:
+		return nil, errors.Errorf("unknown subscription type %q", base.Type)
+	}
+}
+
+// importSubscriptions adds the valid, non conflicting subscriptions to subs.
+func importSubscriptions(subs *serializer.Subscriptions, toImport []serializer.Subscription) ImportResult {
+	subs.EnsureDefaults()
+
+	result := ImportResult{}
+	for _, subscription := range toImport {
+		base := subscription.GetBaseSubscription()
+		if err := subscription.IsValid(); err != nil {
+			result.Invalid = append(result.Invalid, fmt.Sprintf("%s (channel %s): %s", base.Alias, base.ChannelID, err.Error()))
+			continue
+		}
+		if err := subscription.ValidateSubscription(subs); err != nil {
+			result.Conflicts = append(result.Conflicts, fmt.Sprintf("%s (channel %s): %s", base.Alias, base.ChannelID, err.Error()))
+			continue
+		}
+		if err := subscription.Add(subs); err != nil {
+			result.Invalid = append(result.Invalid, fmt.Sprintf("%s (channel %s): %s", base.Alias, base.ChannelID, err.Error()))
+			continue
+		}
+		result.Imported = append(result.Imported, subscription)
+	}
+
+	return result
+}
+
+// ImportSubscriptionsWithDeps saves the valid, non conflicting subscriptions.
+// In dry-run mode, nothing is saved and the result describes what would have been imported.
+func ImportSubscriptionsWithDeps(toImport []serializer.Subscription, dryRun bool, repo SubscriptionRepository, storeService Store) (ImportResult, error) {
+	if dryRun {
+		subscriptions, err := repo.GetSubscriptions()
+		if err != nil {
+			return ImportResult{}, err
+		}
+		result := importSubscriptions(&subscriptions, toImport)
+		result.DryRun = true
+		return result, nil
+	}
+
+	var result ImportResult
+	err := storeService.AtomicModify(store.GetSubscriptionKey(), func(initialBytes []byte) ([]byte, error) {
+		subscriptions, err := serializer.SubscriptionsFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		result = importSubscriptions(subscriptions, toImport)
+		if len(result.Imported) == 0 {
+			return initialBytes, nil
+		}
+		return json.Marshal(subscriptions)
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return result, nil
+}
+
+// ImportSubscriptions saves the valid, non conflicting subscriptions using default dependencies.
+func ImportSubscriptions(toImport []serializer.Subscription, dryRun bool) (ImportResult, error) {
+	return ImportSubscriptionsWithDeps(toImport, dryRun, NewDefaultSubscriptionRepository(), NewDefaultStore())
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service/mocks"
+)
+
+func TestExportSubscriptions(t *testing.T) {
+	for name, val := range map[string]struct {
+		channelID string
+		expected  []string
+	}{
+		"all channels": {
+			expected: []string{testAliasSpace1, testAliasSpace2, testAliasPage1},
+		},
+		"single channel": {
+			channelID: testChannelID2,
+			expected:  []string{testAliasSpace2, testAliasPage1},
+		},
+		"channel without subscriptions": {
+			channelID: testChannelID3,
+			expected:  []string{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockSubscriptionRepository(ctrl)
+			mockRepo.EXPECT().GetSubscriptions().Return(getBaseTestSubscriptions(), nil)
+
+			export, err := ExportSubscriptionsWithDeps(val.channelID, mockRepo)
+			require.NoError(t, err)
+
+			aliases := []string{}
+			for _, subscription := range export.Subscriptions {
+				aliases = append(aliases, subscription.GetAlias())
+			}
+			assert.Equal(t, val.expected, aliases)
+		})
+	}
+}
+
+func TestSubscriptionsExportRoundTrip(t *testing.T) {
+	export := &SubscriptionsExport{
+		Version: subscriptionsExportVersion,
+		Subscriptions: []serializer.Subscription{
+			serializer.SpaceSubscription{
+				SpaceKey: testSpaceKey1,
+				BaseSubscription: serializer.BaseSubscription{
+					Alias:          testAliasSpace1,
+					BaseURL:        testBaseURL,
+					ChannelID:      testChannelID1,
+					Events:         []string{serializer.PageCreatedEvent},
+					Type:           serializer.SubscriptionTypeSpace,
+					SkipMinorEdits: true,
+				},
+			},
+			serializer.PageSubscription{
+				PageID: testPageID1,
+				BaseSubscription: serializer.BaseSubscription{
+					Alias:     testAliasPage1,
+					BaseURL:   testBaseURL,
+					ChannelID: testChannelID2,
+					Events:    []string{serializer.CommentCreatedEvent},
+					Type:      serializer.SubscriptionTypePage,
+				},
+			},
+		},
+	}
+
+	for _, format := range []string{ExportFormatJSON, ExportFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := EncodeSubscriptionsExport(export, format)
+			require.NoError(t, err)
+
+			subscriptions, invalid, err := DecodeSubscriptionsImport(data)
+			require.NoError(t, err)
+			assert.Empty(t, invalid)
+			assert.Equal(t, export.Subscriptions, subscriptions)
+		})
+	}
+}
+
+func TestDecodeSubscriptionsImport(t *testing.T) {
+	for name, val := range map[string]struct {
+		data          string
+		subscriptions int
+		invalid       int
+		isError       bool
+	}{
+		"unknown subscription type": {
+			data:    `{"version": 1, "subscriptions": [{"alias": "a", "subscriptionType": "blog_subscription"}]}`,
+			invalid: 1,
+		},
+		"yaml document": {
+			data:          "version: 1\nsubscriptions:\n  - alias: a\n    subscriptionType: page_subscription\n    pageID: '1'\n",
+			subscriptions: 1,
+		},
+		"unsupported version": {
+			data:    `{"version": 2, "subscriptions": []}`,
+			isError: true,
+		},
+		"not a document": {
+			data:    "{ not valid",
+			isError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			subscriptions, invalid, err := DecodeSubscriptionsImport([]byte(val.data))
+			if val.isError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, subscriptions, val.subscriptions)
+			assert.Len(t, invalid, val.invalid)
+		})
+	}
+}
+
+func TestImportSubscriptionsDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockSubscriptionRepository(ctrl)
+	mockStore := mocks.NewMockStore(ctrl)
+	mockRepo.EXPECT().GetSubscriptions().Return(getBaseTestSubscriptions(), nil)
+
+	newSubscription := serializer.SpaceSubscription{
+		SpaceKey: testSpaceKey2,
+		BaseSubscription: serializer.BaseSubscription{
+			Alias:     "new-space-subscription",
+			BaseURL:   testBaseURL,
+			ChannelID: testChannelID1,
+			Events:    []string{serializer.PageCreatedEvent},
+		},
+	}
+	duplicateAlias := newSubscription
+	duplicateAlias.SpaceKey = "OTHER"
+	sameSpace := newSubscription
+	sameSpace.Alias = testAliasSpace1
+	sameSpace.SpaceKey = testSpaceKey1
+	invalid := newSubscription
+	invalid.Alias = "no-space-key"
+	invalid.SpaceKey = ""
+
+	result, err := ImportSubscriptionsWithDeps([]serializer.Subscription{newSubscription, duplicateAlias, sameSpace, invalid}, true, mockRepo, mockStore)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []serializer.Subscription{newSubscription}, result.Imported)
+	assert.Len(t, result.Conflicts, 2)
+	assert.Len(t, result.Invalid, 1)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+)
+
+// SubscriptionFilter narrows down the subscriptions listed across all channels. Empty fields match everything.
+type SubscriptionFilter struct {
+	SpaceKey  string
+	PageID    string
+	EventType string
+	BaseURL   string
+}
+
+// Matches checks if the subscription matches every field set in the filter.
+func (f SubscriptionFilter) Matches(subscription serializer.Subscription) bool {
+	base := subscription.GetBaseSubscription()
+	if f.EventType != "" && !slices.Contains(base.Events, f.EventType) {
+		return false
+	}
+	if f.BaseURL != "" && !sameInstanceURL(f.BaseURL, base.BaseURL) {
+		return false
+	}
+
+	switch sub := subscription.(type) {
+	case serializer.SpaceSubscription:
+		if f.PageID != "" || (f.SpaceKey != "" && !strings.EqualFold(f.SpaceKey, sub.SpaceKey)) {
+			return false
+		}
+	case serializer.PageSubscription:
+		if f.SpaceKey != "" || (f.PageID != "" && f.PageID != sub.PageID) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameInstanceURL(a, b string) bool {
+	urlA, errA := url.Parse(strings.TrimRight(a, "/"))
+	urlB, errB := url.Parse(strings.TrimRight(b, "/"))
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return strings.EqualFold(urlA.Host, urlB.Host) && urlA.Path == urlB.Path
+}
+
+// ListSubscriptionsWithDeps returns the subscriptions of every channel matching the filter, ordered by channel and name.
+func ListSubscriptionsWithDeps(filter SubscriptionFilter, repo SubscriptionRepository) ([]serializer.Subscription, error) {
+	subscriptions, err := repo.GetSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	list := []serializer.Subscription{}
+	for _, channelSubscriptions := range subscriptions.ByChannelID {
+		for _, subscription := range channelSubscriptions {
+			if filter.Matches(subscription) {
+				list = append(list, subscription)
+			}
+		}
+	}
+
+	sortSubscriptions(list)
+	return list, nil
+}
+
+// ListSubscriptions returns the subscriptions of every channel matching the filter using default dependencies.
+func ListSubscriptions(filter SubscriptionFilter) ([]serializer.Subscription, error) {
+	return ListSubscriptionsWithDeps(filter, NewDefaultSubscriptionRepository())
+}
+
+func sortSubscriptions(subscriptions []serializer.Subscription) {
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i].GetBaseSubscription(), subscriptions[j].GetBaseSubscription()
+		if a.ChannelID != b.ChannelID {
+			return a.ChannelID < b.ChannelID
+		}
+		return a.Alias < b.Alias
+	})
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service/mocks"
+)
+
+func TestListSubscriptions(t *testing.T) {
+	for name, val := range map[string]struct {
+		filter   SubscriptionFilter
+		expected []string
+	}{
+		"no filter": {
+			expected: []string{testAliasSpace1, testAliasSpace2, testAliasPage1},
+		},
+		"space key": {
+			filter:   SubscriptionFilter{SpaceKey: "test"},
+			expected: []string{testAliasSpace1, testAliasSpace2},
+		},
+		"page ID": {
+			filter:   SubscriptionFilter{PageID: testPageID1},
+			expected: []string{testAliasPage1},
+		},
+		"event type": {
+			filter:   SubscriptionFilter{EventType: serializer.CommentCreatedEvent},
+			expected: []string{testAliasPage1},
+		},
+		"instance URL with trailing slash": {
+			filter:   SubscriptionFilter{BaseURL: "https://TEST.confluence.com/"},
+			expected: []string{testAliasSpace1, testAliasSpace2, testAliasPage1},
+		},
+		"other instance URL": {
+			filter:   SubscriptionFilter{BaseURL: "https://other.confluence.com"},
+			expected: []string{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockSubscriptionRepository(ctrl)
+			mockRepo.EXPECT().GetSubscriptions().Return(getBaseTestSubscriptions(), nil)
+
+			subscriptions, err := ListSubscriptionsWithDeps(val.filter, mockRepo)
+			require.NoError(t, err)
+
+			aliases := []string{}
+			for _, subscription := range subscriptions {
+				aliases = append(aliases, subscription.GetAlias())
+			}
+			assert.Equal(t, val.expected, aliases)
+		})
+	}
+}
@@ -8,23 +8,27 @@ import (
 	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 )
 
-func SendConfluenceNotifications(event serializer.ConfluenceEvent, eventType string) {
-	url := event.GetURL()
-	spaceKey := event.GetSpaceKey()
-	pageID := event.GetPageID()
-	post := event.GetNotificationPost(eventType)
-
-	if post == nil || pageID == "" || url == "" || spaceKey == "" {
-		return
+// SendEventNotifications posts the event to the subscribed channels. isRestricted checks the view restrictions of
+// the page of the event, it can be nil when there is no way to check them.
+func SendEventNotifications(event *serializer.Event, isRestricted func() (bool, error)) DeliveryResult {
+	// The events about a space have no page.
+	if event.BaseURL == "" || (event.PageID == "" && event.SpaceKey == "") {
+		return DeliveryResult{}
 	}
 
-	subscriptionChannelIDs := getNotificationChannelIDs(url, spaceKey, pageID, eventType)
-	for _, channelID := range subscriptionChannelIDs {
-		post.ChannelId = channelID
-		if _, err := config.Mattermost.CreatePost(post); err != nil {
-			config.Mattermost.LogError("Unable to create Post in Mattermost", "Error", err.Error())
-		}
+	post := event.GetNotificationPost()
+	if post == nil {
+		return DeliveryResult{}
 	}
+
+	return DeliverNotification(post, NotificationDetails{
+		BaseURL:      event.BaseURL,
+		SpaceKey:     event.SpaceKey,
+		PageID:       event.PageID,
+		EventType:    event.Type,
+		IsMinorEdit:  event.IsMinorEdit,
+		IsRestricted: isRestricted,
+	})
 }
 
 func getNotificationChannelIDsWithDeps(url, spaceKey, pageID, eventType string, repo SubscriptionRepository) []string {
@@ -58,7 +62,3 @@ func getNotificationChannelIDsWithDeps(url, spaceKey, pageID, eventType string,
 
 	return util.Deduplicate(channelIDs)
 }
-
-func getNotificationChannelIDs(url, spaceKey, pageID, eventType string) []string {
-	return getNotificationChannelIDsWithDeps(url, spaceKey, pageID, eventType, NewDefaultSubscriptionRepository())
-}
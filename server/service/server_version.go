@@ -0,0 +1,59 @@
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// pathApplinksManifest is readable without authentication, and describes the application and its version.
+const pathApplinksManifest = "/rest/applinks/1.0/manifest"
+
+type applinksManifest struct {
+	TypeID  string `xml:"typeId"`
+	Version string `xml:"version"`
+}
+
+// GetConfluenceServerVersion returns the version of a Confluence Server or Data Center instance, e.g. "9.2.1".
+func GetConfluenceServerVersion(confluenceURL string, httpClient *http.Client) (string, error) {
+	endpointURL, err := GetEndpointURL(confluenceURL, pathApplinksManifest)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := httpClient.Get(endpointURL)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the server info")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected response status when getting the server info: %d", resp.StatusCode)
+	}
+
+	var manifest applinksManifest
+	if err = xml.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return "", errors.Wrap(err, "failed to parse the server info")
+	}
+	if manifest.TypeID != "" && manifest.TypeID != "confluence" {
+		return "", errors.Errorf("%s is not a Confluence instance", confluenceURL)
+	}
+	if manifest.Version == "" {
+		return "", errors.New("the server info does not include the version")
+	}
+
+	return manifest.Version, nil
+}
+
+// IsServerVersion9OrAbove checks if the Confluence version uses the webhook events and payloads introduced in version 9.
+func IsServerVersion9OrAbove(version string) (bool, error) {
+	major, err := strconv.Atoi(strings.SplitN(strings.TrimSpace(version), ".", 2)[0])
+	if err != nil {
+		return false, fmt.Errorf("invalid Confluence version %q", version)
+	}
+	return major >= 9, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfluenceServerVersion(t *testing.T) {
+	for name, val := range map[string]struct {
+		status          int
+		body            string
+		expectedVersion string
+		expectError     bool
+	}{
+		"version 9": {
+			status:          http.StatusOK,
+			body:            `<manifest><typeId>confluence</typeId><name>Confluence</name><version>9.2.1</version><buildNumber>9012</buildNumber></manifest>`,
+			expectedVersion: "9.2.1",
+		},
+		"version 8": {
+			status:          http.StatusOK,
+			body:            `<manifest><typeId>confluence</typeId><version>8.5.4</version></manifest>`,
+			expectedVersion: "8.5.4",
+		},
+		"not confluence": {
+			status:      http.StatusOK,
+			body:        `<manifest><typeId>jira</typeId><version>9.12.0</version></manifest>`,
+			expectError: true,
+		},
+		"no version": {
+			status:      http.StatusOK,
+			body:        `<manifest><typeId>confluence</typeId></manifest>`,
+			expectError: true,
+		},
+		"not found": {
+			status:      http.StatusNotFound,
+			expectError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, pathApplinksManifest, r.URL.Path)
+				w.WriteHeader(val.status)
+				_, _ = w.Write([]byte(val.body))
+			}))
+			defer server.Close()
+
+			version, err := GetConfluenceServerVersion(server.URL, server.Client())
+			if val.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, val.expectedVersion, version)
+		})
+	}
+}
+
+func TestIsServerVersion9OrAbove(t *testing.T) {
+	for version, expected := range map[string]bool{
+		"9.0.0":  true,
+		"10.1.2": true,
+		"8.9.3":  false,
+		"7":      false,
+	} {
+		t.Run(version, func(t *testing.T) {
+			isV9, err := IsServerVersion9OrAbove(version)
+			require.NoError(t, err)
+			assert.Equal(t, expected, isV9)
+		})
+	}
+
+	_, err := IsServerVersion9OrAbove("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+const (
+	// Sources of a webhook delivery, they tell how its payload is replayed
+	DeliverySourceCloud         = "cloud"
+	DeliverySourceServer        = "server"
+	DeliverySourceServerWebhook = "server_webhook"
+
+	// Outcomes of a webhook delivery
+	DeliveryOutcomeDelivered = "delivered"
+	DeliveryOutcomeNoMatch   = "no_match"
+	DeliveryOutcomeDuplicate = "duplicate"
+	DeliveryOutcomeFailed    = "failed"
+
+	// MaxRecordedDeliveries is the number of webhook deliveries kept per instance, the oldest ones are dropped first.
+	MaxRecordedDeliveries = 50
+
+	// Larger payloads are not recorded, even once their content is stripped.
+	maxRecordedPayloadSize = 16 * 1024
+
+	// The recorded deliveries expire even when they are not pushed out by newer ones.
+	webhookDeliveryTTL = 7 * 24 * time.Hour
+)
+
+// strippedPayloadFields hold the content of the pages and comments in the webhook payloads. They are not recorded,
+// a delivery only keeps what identifies the content it is about.
+var strippedPayloadFields = map[string]bool{
+	"body":            true,
+	"content":         true,
+	"html_content":    true,
+	"excerpt":         true,
+	"version_comment": true,
+}
+
+// WebhookDelivery records a webhook request received from Confluence and what the plugin did with it.
+type WebhookDelivery struct {
+	ID              string          `json:"id"`
+	Timestamp       int64           `json:"timestamp"`
+	Source          string          `json:"source"`
+	EventType       string          `json:"eventType"`
+	ContentID       string          `json:"contentID,omitempty"`
+	MatchedChannels int             `json:"matchedChannels"`
+	Outcome         string          `json:"outcome"`
+	Error           string          `json:"error,omitempty"`
+	ReplayOf        string          `json:"replayOf,omitempty"`
+	MetadataOnly    bool            `json:"metadataOnly,omitempty"` // The replay was built from the stripped payload, without the content of the pages and comments
+	Payload         json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewWebhookDelivery creates the record of a webhook request. The payload is kept without the content of the pages
+// and comments to replay the delivery, unless it is too large.
+func NewWebhookDelivery(source, eventType, contentID string, payload []byte) WebhookDelivery {
+	delivery := WebhookDelivery{
+		Source:    source,
+		EventType: eventType,
+		ContentID: contentID,
+	}
+	if stripped := stripPayload(payload); len(stripped) <= maxRecordedPayloadSize {
+		delivery.Payload = stripped
+	}
+	return delivery
+}
+
+// stripPayload removes the content of the pages and comments from the payload, nil when the payload is not JSON.
+func stripPayload(payload []byte) json.RawMessage {
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return nil
+	}
+	stripped, err := json.Marshal(stripPayloadValue(value))
+	if err != nil {
+		return nil
+	}
+	return stripped
+}
+
+func stripPayloadValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if strippedPayloadFields[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = stripPayloadValue(field)
+		}
+	case []any:
+		for i := range v {
+			v[i] = stripPayloadValue(v[i])
+		}
+	}
+	return value
+}
+
+// SetResult sets the outcome of the delivery. err is set when the event could not be processed at all.
+func (d *WebhookDelivery) SetResult(result DeliveryResult, err error) {
+	d.MatchedChannels = result.MatchedChannels
+	if err == nil {
+		err = result.Err
+	}
+
+	switch {
+	case err != nil:
+		d.Outcome = DeliveryOutcomeFailed
+		d.Error = err.Error()
+	case result.MatchedChannels == 0:
+		d.Outcome = DeliveryOutcomeNoMatch
+	default:
+		d.Outcome = DeliveryOutcomeDelivered
+	}
+}
+
+// RecordWebhookDelivery adds the delivery to the recent deliveries of the instance.
+// Failures are logged, as they should never block the delivery itself.
+func RecordWebhookDelivery(instanceID string, delivery WebhookDelivery) {
+	if err := appendWebhookDelivery(instanceID, delivery, time.Now()); err != nil {
+		config.Mattermost.LogError("Unable to record the webhook delivery", "InstanceID", instanceID, "EventType", delivery.EventType, "Error", err.Error())
+	}
+}
+
+// appendWebhookDelivery stores the delivery under its own key, and adds it to the small index of the recent
+// deliveries of the instance.
+func appendWebhookDelivery(instanceID string, delivery WebhookDelivery, now time.Time) error {
+	delivery.ID = model.NewId()
+	delivery.Timestamp = now.UnixMilli()
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	if appErr := config.Mattermost.KVSetWithExpiry(store.GetWebhookDeliveryKey(instanceID, delivery.ID), data, int64(webhookDeliveryTTL.Seconds())); appErr != nil {
+		return errors.Wrap(appErr, "unable to store the webhook delivery")
+	}
+
+	var dropped []string
+	err = store.AtomicModify(store.GetWebhookDeliveriesKey(instanceID), func(initialBytes []byte) ([]byte, error) {
+		ids, err := webhookDeliveryIDsFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, delivery.ID)
+		dropped = nil
+		if len(ids) > MaxRecordedDeliveries {
+			dropped = ids[:len(ids)-MaxRecordedDeliveries]
+			ids = ids[len(ids)-MaxRecordedDeliveries:]
+		}
+		return json.Marshal(ids)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range dropped {
+		if appErr := config.Mattermost.KVDelete(store.GetWebhookDeliveryKey(instanceID, id)); appErr != nil {
+			config.Mattermost.LogWarn("Unable to delete the webhook delivery", "InstanceID", instanceID, "DeliveryID", id, "Error", appErr.Error())
+		}
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns the recent webhook deliveries of the instance, oldest first.
+func GetWebhookDeliveries(instanceID string) ([]WebhookDelivery, error) {
+	data, appErr := config.Mattermost.KVGet(store.GetWebhookDeliveriesKey(instanceID))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to read the webhook deliveries")
+	}
+	ids, err := webhookDeliveryIDsFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, id := range ids {
+		delivery, err := GetWebhookDelivery(instanceID, id)
+		if err != nil {
+			// The delivery expired.
+			continue
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery returns the recent webhook delivery of the instance with the given ID.
+func GetWebhookDelivery(instanceID, deliveryID string) (*WebhookDelivery, error) {
+	data, appErr := config.Mattermost.KVGet(store.GetWebhookDeliveryKey(instanceID, deliveryID))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to read the webhook delivery")
+	}
+	if data == nil {
+		return nil, errors.Errorf("no recent webhook delivery with ID %q", deliveryID)
+	}
+
+	delivery := &WebhookDelivery{}
+	if err := json.Unmarshal(data, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// webhookDeliveryIDsFromJSON reads the index of the recent deliveries. The deliveries recorded with their payload in
+// a single entry by the previous versions are dropped.
+func webhookDeliveryIDsFromJSON(data []byte) ([]string, error) {
+	ids := []string{}
+	if len(data) == 0 {
+		return ids, nil
+	}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		var legacy []json.RawMessage
+		if json.Unmarshal(data, &legacy) == nil {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	return ids, nil
+}
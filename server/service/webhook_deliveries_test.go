@@ -0,0 +1,152 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+func TestAppendWebhookDelivery(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	recorded := func(count int) []string {
+		ids := make([]string, count)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("%d", i)
+		}
+		return ids
+	}
+
+	for name, val := range map[string]struct {
+		existing        []byte
+		expectedCount   int
+		expectedFirst   string
+		expectedDropped string
+	}{
+		"first delivery": {
+			expectedCount: 1,
+		},
+		"appended": {
+			existing:      mustMarshal(recorded(3)),
+			expectedCount: 4,
+			expectedFirst: "0",
+		},
+		"oldest delivery dropped": {
+			existing:        mustMarshal(recorded(MaxRecordedDeliveries)),
+			expectedCount:   MaxRecordedDeliveries,
+			expectedFirst:   "1",
+			expectedDropped: "0",
+		},
+		"deliveries of the previous format dropped": {
+			existing:      []byte(`[{"id":"0","payload":{"page":{"id":"42"}}}]`),
+			expectedCount: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+
+			var stored WebhookDelivery
+			mockAPI.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(webhookDeliveryTTL.Seconds())).Run(func(args mock.Arguments) {
+				require.NoError(t, json.Unmarshal(args.Get(1).([]byte), &stored))
+			}).Return(nil)
+
+			key := store.GetWebhookDeliveriesKey(testBaseURL)
+			mockAPI.On("KVGet", key).Return(val.existing, nil)
+
+			var saved []string
+			mockAPI.On("KVCompareAndSet", key, val.existing, mock.Anything).Run(func(args mock.Arguments) {
+				require.NoError(t, json.Unmarshal(args.Get(2).([]byte), &saved))
+			}).Return(true, nil)
+			if val.expectedDropped != "" {
+				mockAPI.On("KVDelete", store.GetWebhookDeliveryKey(testBaseURL, val.expectedDropped)).Return(nil).Once()
+			}
+
+			delivery := NewWebhookDelivery(DeliverySourceServerWebhook, "page_updated", "42", []byte(`{"event":"page_updated"}`))
+			delivery.SetResult(DeliveryResult{MatchedChannels: 2}, nil)
+			require.NoError(t, appendWebhookDelivery(testBaseURL, delivery, now))
+			mockAPI.AssertExpectations(t)
+
+			require.Len(t, saved, val.expectedCount)
+			if val.expectedFirst != "" {
+				assert.Equal(t, val.expectedFirst, saved[0])
+			}
+
+			assert.NotEmpty(t, stored.ID)
+			assert.Equal(t, stored.ID, saved[len(saved)-1])
+			assert.Equal(t, now.UnixMilli(), stored.Timestamp)
+			assert.Equal(t, "42", stored.ContentID)
+			assert.Equal(t, 2, stored.MatchedChannels)
+			assert.Equal(t, DeliveryOutcomeDelivered, stored.Outcome)
+			assert.JSONEq(t, `{"event":"page_updated"}`, string(stored.Payload))
+		})
+	}
+}
+
+func mustMarshal(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func TestWebhookDeliverySetResult(t *testing.T) {
+	for name, val := range map[string]struct {
+		result          DeliveryResult
+		err             error
+		expectedOutcome string
+		expectedError   string
+	}{
+		"delivered": {
+			result:          DeliveryResult{MatchedChannels: 3},
+			expectedOutcome: DeliveryOutcomeDelivered,
+		},
+		"no subscription matched": {
+			expectedOutcome: DeliveryOutcomeNoMatch,
+		},
+		"event not processed": {
+			err:             errors.New("unable to get the page"),
+			expectedOutcome: DeliveryOutcomeFailed,
+			expectedError:   "unable to get the page",
+		},
+		"post failed": {
+			result:          DeliveryResult{MatchedChannels: 1, Err: &model.AppError{Message: "post failed"}},
+			expectedOutcome: DeliveryOutcomeFailed,
+			expectedError:   "post failed",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			delivery := WebhookDelivery{}
+			delivery.SetResult(val.result, val.err)
+			assert.Equal(t, val.expectedOutcome, delivery.Outcome)
+			assert.Equal(t, val.result.MatchedChannels, delivery.MatchedChannels)
+			assert.Contains(t, delivery.Error, val.expectedError)
+		})
+	}
+}
+
+func TestNewWebhookDeliveryPayload(t *testing.T) {
+	small := NewWebhookDelivery(DeliverySourceCloud, "page_created", "1", []byte(`{"page":{"id":"1"}}`))
+	assert.NotEmpty(t, small.Payload)
+
+	large := NewWebhookDelivery(DeliverySourceCloud, "page_created", "1", []byte(`{"labels":"`+strings.Repeat("a", maxRecordedPayloadSize)+`"}`))
+	assert.Empty(t, large.Payload)
+
+	invalid := NewWebhookDelivery(DeliverySourceCloud, "page_created", "1", []byte(`{"page":`))
+	assert.Empty(t, invalid.Payload)
+
+	withContent := NewWebhookDelivery(DeliverySourceServer, "comment_created", "1", []byte(`{"event":"comment_created","page":{"id":"1","excerpt":"Secret plans"},"comment":{"id":"2","content":"Secret","html_content":"<p>Secret</p>","parent":{"excerpt":"Secret"}}}`))
+	assert.JSONEq(t, `{"event":"comment_created","page":{"id":"1"},"comment":{"id":"2","parent":{}}}`, string(withContent.Payload))
+
+	stripped := NewWebhookDelivery(DeliverySourceServer, "page_created", "1", []byte(`{"page":{"id":"1","content":"`+strings.Repeat("a", maxRecordedPayloadSize)+`"}}`))
+	assert.JSONEq(t, `{"page":{"id":"1"}}`, string(stripped.Payload))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	url2 "net/url"
+	"strings"
 	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
@@ -23,10 +24,26 @@ const (
 	keyRSAKey                       = "rsa_key"
 	prefixUser                      = "user_"
 	AdminMattermostUserID           = "admin"
+	keyHeldNotifications            = "held_notifications"
+	prefixAuditLog                  = "audit_log_"
+	keyKeyRotationStatus            = "key_rotation_status"
+	keyWebhookSecretState           = "webhook_secret_state"
+	prefixPreviousSecretUse         = "previous_secret_use_"
+	keyWebhookSigningSecret         = "webhook_signing_secret"
+	prefixWebhookDelivery           = "webhook_delivery_"
+	keyLastWebhookDelivery          = "last_webhook_delivery"
+	keyWebhookDeliveries            = "webhook_deliveries"
+	prefixWebhookDeliveryRecord     = "webhook_delivery_record_"
+	prefixCache                     = "cache_"
+	prefixTokenHealth               = "token_health_"
+	listKeysPerPage                 = 1000
 )
 
 var ErrNotFound = errors.New("not found")
 
+// OnAtomicModifyRetry is called each time AtomicModify retries a write after a concurrent modification.
+var OnAtomicModifyRetry = func() {}
+
 // lint is suggesting to rename the function names from `storeConnection` to `Connection` so that when the function is accessed from any other package
 // it looks like `store.Connection, but this reduces the readability within the function`
 
@@ -52,6 +69,30 @@ func GetSubscriptionKey() string {
 	return util.GetKeyHash(ConfluenceSubscriptionKeyPrefix)
 }
 
+func GetHeldNotificationsKey() string {
+	return util.GetKeyHash(keyHeldNotifications)
+}
+
+// GetWebhookDeliveriesKey returns the key of the index of the recent webhook deliveries of the instance.
+func GetWebhookDeliveriesKey(instanceID string) string {
+	return util.GetKeyHash(keyWithInstanceID(instanceID, keyWebhookDeliveries))
+}
+
+// GetWebhookDeliveryKey returns the key of a recorded webhook delivery of the instance.
+func GetWebhookDeliveryKey(instanceID, deliveryID string) string {
+	return util.GetKeyHash(keyWithInstanceID(instanceID, prefixWebhookDeliveryRecord+deliveryID))
+}
+
+// GetCacheKey returns the key of an entry of the named lookup cache.
+func GetCacheKey(name, key string) string {
+	return util.GetKeyHash(prefixCache + name + "_" + key)
+}
+
+// GetAuditLogKey returns the key of the audit log bucket holding the entries recorded on the given UTC day.
+func GetAuditLogKey(day time.Time) string {
+	return util.GetKeyHash(prefixAuditLog + day.UTC().Format("2006-01-02"))
+}
+
 // from https://github.com/mattermost/mattermost-plugin-jira/blob/master/server/subscribe.go#L625
 func AtomicModify(key string, modify func(initialValue []byte) ([]byte, error)) error {
 	readModify := func() ([]byte, []byte, error) {
@@ -86,6 +127,9 @@ func AtomicModify(key string, modify func(initialValue []byte) ([]byte, error))
 		if setError != nil {
 			return errors.Wrap(setError, "problem writing value")
 		}
+		if !success {
+			OnAtomicModifyRetry()
+		}
 
 		if currentAttempt == 0 && bytes.Equal(initialBytes, newValue) {
 			return nil
@@ -158,6 +202,47 @@ func VerifyOAuth2State(state string) error {
 	return nil
 }
 
+// MarkWebhookDelivery records the webhook delivery identified by the dedupe key for the given duration.
+// It returns false if the delivery was already recorded. The delivery is recorded atomically, so that only one of
+// concurrent retries of the same delivery is processed.
+func MarkWebhookDelivery(dedupeKey string, ttl time.Duration) (bool, error) {
+	key := hashkey(prefixWebhookDelivery, util.GetKeyHash(dedupeKey))
+	stored, appErr := config.Mattermost.KVSetWithOptions(key, []byte(dedupeKey), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: int64(ttl.Seconds()),
+	})
+	if appErr != nil {
+		return false, errors.WithMessage(appErr, "failed to store the webhook delivery")
+	}
+	return stored, nil
+}
+
+// StoreLastWebhookDelivery records when the last authenticated webhook request was received.
+func StoreLastWebhookDelivery(at time.Time) error {
+	return set(util.GetKeyHash(keyLastWebhookDelivery), at.UnixMilli())
+}
+
+// LoadLastWebhookDelivery returns when the last authenticated webhook request was received, or the zero time if none was.
+func LoadLastWebhookDelivery() (time.Time, error) {
+	var at int64
+	if err := get(util.GetKeyHash(keyLastWebhookDelivery), &at); err != nil {
+		if err == ErrNotFound {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return time.UnixMilli(at), nil
+}
+
+// ForgetWebhookDelivery removes the record of the webhook delivery, so that a retry of the delivery is handled again.
+func ForgetWebhookDelivery(dedupeKey string) error {
+	if appErr := config.Mattermost.KVDelete(hashkey(prefixWebhookDelivery, util.GetKeyHash(dedupeKey))); appErr != nil {
+		return errors.WithMessage(appErr, "failed to delete the webhook delivery")
+	}
+	return nil
+}
+
 func StoreConnection(instanceID, mattermostUserID string, connection *types.Connection) (returnErr error) {
 	if err := set(keyWithInstanceID(instanceID, mattermostUserID), connection); err != nil {
 		return err
@@ -222,6 +307,10 @@ func DeleteConnectionFromKVStore(instanceID, mattermostUserID string, c *types.C
 		return appErr
 	}
 
+	if appErr := config.Mattermost.KVDelete(tokenHealthKey(instanceID, mattermostUserID)); appErr != nil {
+		return appErr
+	}
+
 	config.Mattermost.LogDebug("Deleted: user, keys: %s(%s), %s(%s)",
 		mattermostUserID, keyWithInstanceID(instanceID, mattermostUserID),
 		c.ConfluenceAccountID(), keyWithInstanceID(instanceID, c.ConfluenceAccountID()))
@@ -246,3 +335,156 @@ func StoreUser(user *types.User) (returnErr error) {
 	config.Mattermost.LogDebug("Stored: user %s key:%s: connected to:%q", user.MattermostUserID, key, user.InstanceURL)
 	return nil
 }
+
+// ModifyConnection applies the change to the stored connection with a compare-and-set, retrying when the connection
+// is changed concurrently. The modify function returns whether it changed the connection.
+func ModifyConnection(instanceID, mattermostUserID string, modify func(connection *types.Connection) (bool, error)) error {
+	return AtomicModify(keyWithInstanceID(instanceID, mattermostUserID), func(initialBytes []byte) ([]byte, error) {
+		if initialBytes == nil {
+			return nil, ErrNotFound
+		}
+
+		connection := &types.Connection{}
+		if err := json.Unmarshal(initialBytes, connection); err != nil {
+			return nil, err
+		}
+
+		changed, err := modify(connection)
+		if err != nil || !changed {
+			return initialBytes, err
+		}
+		return json.Marshal(connection)
+	})
+}
+
+// ListUserIDs returns the IDs of every Mattermost user that has been stored.
+func ListUserIDs() ([]string, error) {
+	var userIDs []string
+	for page := 0; ; page++ {
+		keys, appErr := config.Mattermost.KVList(page, listKeysPerPage)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		for _, key := range keys {
+			if userID, ok := strings.CutPrefix(key, hashkey(prefixUser, "")); ok {
+				userIDs = append(userIDs, userID)
+			}
+		}
+
+		if len(keys) < listKeysPerPage {
+			return userIDs, nil
+		}
+	}
+}
+
+func LoadKeyRotationStatus() (*types.KeyRotationStatus, error) {
+	status := &types.KeyRotationStatus{}
+	if err := get(util.GetKeyHash(keyKeyRotationStatus), status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func StoreKeyRotationStatus(status *types.KeyRotationStatus) error {
+	return set(util.GetKeyHash(keyKeyRotationStatus), status)
+}
+
+func tokenHealthKey(instanceID, mattermostUserID string) string {
+	return util.GetKeyHash(keyWithInstanceID(instanceID, prefixTokenHealth+mattermostUserID))
+}
+
+// LoadTokenHealth returns the health of the token of the connection, or nil if it was never refreshed.
+func LoadTokenHealth(instanceID, mattermostUserID string) (*types.TokenHealth, error) {
+	health := &types.TokenHealth{}
+	if err := get(tokenHealthKey(instanceID, mattermostUserID), health); err != nil {
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return health, nil
+}
+
+func StoreTokenHealth(instanceID, mattermostUserID string, health *types.TokenHealth) error {
+	return set(tokenHealthKey(instanceID, mattermostUserID), health)
+}
+
+func LoadWebhookSecretState() (*types.WebhookSecretState, error) {
+	state := &types.WebhookSecretState{}
+	if err := get(util.GetKeyHash(keyWebhookSecretState), state); err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	return state, nil
+}
+
+// ModifyWebhookSecretState atomically updates the webhook secret state.
+func ModifyWebhookSecretState(modify func(state *types.WebhookSecretState) error) error {
+	return AtomicModify(util.GetKeyHash(keyWebhookSecretState), func(initialBytes []byte) ([]byte, error) {
+		state := &types.WebhookSecretState{}
+		if len(initialBytes) != 0 {
+			if err := json.Unmarshal(initialBytes, state); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := modify(state); err != nil {
+			return nil, err
+		}
+		return json.Marshal(state)
+	})
+}
+
+// AddPreviousSecretUse atomically adds the requests made with the previous webhook secret to the use stored under the key.
+func AddPreviousSecretUse(key, endpoint, remoteAddr string, count int, usedAt int64) error {
+	return AtomicModify(hashkey(prefixPreviousSecretUse, util.GetKeyHash(key)), func(initialBytes []byte) ([]byte, error) {
+		use := &types.PreviousSecretUse{Endpoint: endpoint, RemoteAddr: remoteAddr}
+		if len(initialBytes) != 0 {
+			if err := json.Unmarshal(initialBytes, use); err != nil {
+				return nil, err
+			}
+		}
+
+		use.Count += count
+		use.LastUsedAt = usedAt
+		return json.Marshal(use)
+	})
+}
+
+func LoadPreviousSecretUse(key string) (*types.PreviousSecretUse, error) {
+	use := &types.PreviousSecretUse{}
+	if err := get(hashkey(prefixPreviousSecretUse, util.GetKeyHash(key)), use); err != nil {
+		return nil, err
+	}
+	return use, nil
+}
+
+// DeletePreviousSecretUses deletes the uses of a previous webhook secret that is no longer accepted.
+func DeletePreviousSecretUses(keys []string) error {
+	for _, key := range keys {
+		if appErr := config.Mattermost.KVDelete(hashkey(prefixPreviousSecretUse, util.GetKeyHash(key))); appErr != nil {
+			return appErr
+		}
+	}
+	return nil
+}
+
+// LoadWebhookSigningSecret returns the secret used to sign the webhooks of the instance, or an empty string if none is set.
+func LoadWebhookSigningSecret(instanceID string) (string, error) {
+	var secret string
+	if err := get(keyWithInstanceID(instanceID, keyWebhookSigningSecret), &secret); err != nil && err != ErrNotFound {
+		return "", err
+	}
+	return secret, nil
+}
+
+func StoreWebhookSigningSecret(instanceID, secret string) error {
+	return set(keyWithInstanceID(instanceID, keyWebhookSigningSecret), secret)
+}
+
+func DeleteWebhookSigningSecret(instanceID string) error {
+	if appErr := config.Mattermost.KVDelete(keyWithInstanceID(instanceID, keyWebhookSigningSecret)); appErr != nil {
+		return appErr
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	listAllOnlySystemAdmin  = "`/confluence list --all` can only be run by a system administrator."
+	noSubscriptions         = "No subscriptions found."
+	maxListCommandEntries   = 50
+	defaultOverviewPageSize = 50
+	maxOverviewPageSize     = 200
+
+	// Channel statuses of a subscription
+	channelStatusActive   = "active"
+	channelStatusArchived = "archived"
+	channelStatusDeleted  = "deleted"
+	channelStatusUnknown  = "unknown"
+)
+
+var getAllSubscriptions = &Endpoint{
+	Path:            "/subscriptions",
+	Method:          http.MethodGet,
+	Execute:         handleGetAllSubscriptions,
+	IsAuthenticated: true,
+}
+
+// SubscriptionOverview is a subscription along with the channel and team it belongs to.
+type SubscriptionOverview struct {
+	Subscription  serializer.Subscription `json:"subscription"`
+	ChannelName   string                  `json:"channelName"`
+	TeamName      string                  `json:"teamName"`
+	ChannelStatus string                  `json:"channelStatus"`
+}
+
+type SubscriptionOverviewPage struct {
+	Total         int                    `json:"total"`
+	Page          int                    `json:"page"`
+	PerPage       int                    `json:"perPage"`
+	Subscriptions []SubscriptionOverview `json:"subscriptions"`
+}
+
+func handleGetAllSubscriptions(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	if !util.IsSystemAdmin(userID) {
+		p.client.Log.Error("Non admin user does not have access to list all subscriptions", "UserID", userID)
+		http.Error(w, "only system admin can list all subscriptions", http.StatusForbidden)
+		return
+	}
+
+	page, err := strconv.Atoi(r.FormValue("page"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+	perPage, err := strconv.Atoi(r.FormValue("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = defaultOverviewPageSize
+	}
+	perPage = min(perPage, maxOverviewPageSize)
+
+	filter := service.SubscriptionFilter{
+		SpaceKey:  r.FormValue("space_key"),
+		PageID:    r.FormValue("page_id"),
+		EventType: r.FormValue("event"),
+		BaseURL:   r.FormValue("base_url"),
+	}
+
+	subscriptions, err := service.ListSubscriptions(filter)
+	if err != nil {
+		p.client.Log.Error("Error listing subscriptions", "error", err.Error())
+		http.Error(w, "Failed to list subscriptions.", http.StatusInternalServerError)
+		return
+	}
+
+	start := min(page*perPage, len(subscriptions))
+	end := min(start+perPage, len(subscriptions))
+	out := SubscriptionOverviewPage{
+		Total:         len(subscriptions),
+		Page:          page,
+		PerPage:       perPage,
+		Subscriptions: p.describeSubscriptions(subscriptions[start:end]),
+	}
+
+	b, _ := json.Marshal(out)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func listAllSubscriptions(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, listAllOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	filter, err := parseSubscriptionFilter(args)
+	if err != nil {
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	subscriptions, err := service.ListSubscriptions(filter)
+	if err != nil {
+		p.client.Log.Error("Error listing subscriptions", "UserID", context.UserId, "error", err.Error())
+		postCommandResponse(context, errorExecutingCommand)
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, formatSubscriptionOverview(p.describeSubscriptions(subscriptions[:min(len(subscriptions), maxListCommandEntries)]), len(subscriptions)))
+	return &model.CommandResponse{}
+}
+
+// parseSubscriptionFilter parses the `--space`, `--page`, `--event` and `--url` flags of `/confluence list --all`.
+func parseSubscriptionFilter(args []string) (service.SubscriptionFilter, error) {
+	filter := service.SubscriptionFilter{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--all" {
+			continue
+		}
+		if i+1 >= len(args) {
+			return filter, errors.Errorf("Please specify a value for `%s`.", args[i])
+		}
+
+		value := args[i+1]
+		switch args[i] {
+		case "--space":
+			filter.SpaceKey = value
+		case "--page":
+			filter.PageID = value
+		case "--event":
+			filter.EventType = value
+		case "--url":
+			filter.BaseURL = value
+		default:
+			return filter, errors.Errorf("Unexpected argument %q.", args[i])
+		}
+		i++
+	}
+	return filter, nil
+}
+
+// describeSubscriptions adds the channel and team names, and the channel status, to each subscription.
+func (p *Plugin) describeSubscriptions(subscriptions []serializer.Subscription) []SubscriptionOverview {
+	channels := map[string]*model.Channel{}
+	channelErrors := map[string]*model.AppError{}
+	teamNames := map[string]string{}
+
+	out := make([]SubscriptionOverview, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		channelID := subscription.GetBaseSubscription().ChannelID
+		channel, ok := channels[channelID]
+		if !ok {
+			var appErr *model.AppError
+			if channel, appErr = p.API.GetChannel(channelID); appErr != nil && appErr.StatusCode != http.StatusNotFound {
+				p.client.Log.Warn("Error getting the channel of the subscription", "ChannelID", channelID, "error", appErr.Error())
+			}
+			channels[channelID] = channel
+			channelErrors[channelID] = appErr
+		}
+
+		overview := SubscriptionOverview{
+			Subscription:  subscription,
+			ChannelName:   channelID,
+			ChannelStatus: channelStatusDeleted,
+		}
+		// Only a channel that is not found is deleted, it may just be unavailable for now otherwise.
+		if appErr := channelErrors[channelID]; appErr != nil && appErr.StatusCode != http.StatusNotFound {
+			overview.ChannelStatus = channelStatusUnknown
+		}
+		if channel != nil {
+			overview.ChannelName = channel.Name
+			overview.ChannelStatus = channelStatusActive
+			if channel.DeleteAt != 0 {
+				overview.ChannelStatus = channelStatusArchived
+			}
+
+			if channel.TeamId != "" {
+				if _, ok := teamNames[channel.TeamId]; !ok {
+					teamNames[channel.TeamId] = channel.TeamId
+					if team, appErr := p.API.GetTeam(channel.TeamId); appErr == nil {
+						teamNames[channel.TeamId] = team.Name
+					}
+				}
+				overview.TeamName = teamNames[channel.TeamId]
+			}
+		}
+		out = append(out, overview)
+	}
+	return out
+}
+
+func formatSubscriptionOverview(overviews []SubscriptionOverview, total int) string {
+	if total == 0 {
+		return noSubscriptions
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| Team | Channel | Name | Space Key/Page Id | Base Url | Events | Channel Status |\n| :--- | :--- | :--- | :--- | :--- | :--- | :--- |\n")
+	for _, overview := range overviews {
+		base := overview.Subscription.GetBaseSubscription()
+		target := ""
+		switch sub := overview.Subscription.(type) {
+		case serializer.SpaceSubscription:
+			target = "Space " + sub.SpaceKey
+		case serializer.PageSubscription:
+			target = "Page " + sub.PageID
+		}
+
+		status := overview.ChannelStatus
+		if status != channelStatusActive {
+			status = "**" + status + "**"
+		}
+		fmt.Fprintf(&sb, "| %s | ~%s | %s | %s | %s | %s | %s |\n", overview.TeamName, overview.ChannelName, base.Alias, target, base.BaseURL, strings.Join(base.Events, ", "), status)
+	}
+
+	if total > len(overviews) {
+		fmt.Fprintf(&sb, "\nShowing %d of %d subscriptions. Use filters, or the `%s/api/v1%s` endpoint, to see the rest.", len(overviews), total, util.GetPluginURL(), getAllSubscriptions.Path)
+	}
+	return sb.String()
+}
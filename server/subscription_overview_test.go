@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+)
+
+func TestDescribeSubscriptions(t *testing.T) {
+	mockAPI := baseMock()
+	p := &Plugin{}
+	p.SetAPI(mockAPI)
+	p.client = pluginapi.NewClient(mockAPI, nil)
+
+	mockAPI.On("GetChannel", "active").Return(&model.Channel{Id: "active", Name: "town-square", TeamId: "team1"}, nil)
+	mockAPI.On("GetChannel", "archived").Return(&model.Channel{Id: "archived", Name: "old", TeamId: "team1", DeleteAt: 1}, nil)
+	mockAPI.On("GetChannel", "deleted").Return(nil, &model.AppError{Message: "not found", StatusCode: http.StatusNotFound})
+	mockAPI.On("GetChannel", "unavailable").Return(nil, &model.AppError{Message: "database unavailable", StatusCode: http.StatusInternalServerError}).Once()
+	mockAPI.On("LogWarn", "Error getting the channel of the subscription", "ChannelID", "unavailable", "error", mock.Anything).Once()
+	mockAPI.On("GetTeam", "team1").Return(&model.Team{Id: "team1", Name: "engineering"}, nil).Once()
+
+	subscriptionIn := func(channelID string) serializer.Subscription {
+		return serializer.SpaceSubscription{SpaceKey: "KEY", BaseSubscription: serializer.BaseSubscription{Alias: channelID, ChannelID: channelID}}
+	}
+
+	overviews := p.describeSubscriptions([]serializer.Subscription{subscriptionIn("active"), subscriptionIn("archived"), subscriptionIn("deleted"), subscriptionIn("unavailable"), subscriptionIn("unavailable")})
+
+	assert.Len(t, overviews, 5)
+	assert.Equal(t, SubscriptionOverview{Subscription: subscriptionIn("active"), ChannelName: "town-square", TeamName: "engineering", ChannelStatus: channelStatusActive}, overviews[0])
+	assert.Equal(t, channelStatusArchived, overviews[1].ChannelStatus)
+	assert.Equal(t, "engineering", overviews[1].TeamName)
+	assert.Equal(t, SubscriptionOverview{Subscription: subscriptionIn("deleted"), ChannelName: "deleted", ChannelStatus: channelStatusDeleted}, overviews[2])
+	assert.Equal(t, channelStatusUnknown, overviews[3].ChannelStatus)
+	assert.Equal(t, channelStatusUnknown, overviews[4].ChannelStatus)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestParseSubscriptionFilter(t *testing.T) {
+	filter, err := parseSubscriptionFilter([]string{"--space", "KEY", "--event", "page_created", "--url", "https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "KEY", filter.SpaceKey)
+	assert.Equal(t, "page_created", filter.EventType)
+	assert.Equal(t, "https://example.com", filter.BaseURL)
+
+	_, err = parseSubscriptionFilter([]string{"--space"})
+	assert.Error(t, err)
+
+	_, err = parseSubscriptionFilter([]string{"--unknown", "value"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+const (
+	refreshTokensJobKey      = "refresh_tokens"
+	refreshTokensJobInterval = 1 * time.Hour
+
+	// Tokens expiring before the next run of the job are refreshed.
+	tokenRefreshWindow = 2 * refreshTokensJobInterval
+
+	reconnectMessage = "Your Confluence account could not stay connected: Confluence rejected the stored authorization. " +
+		"Run `/confluence disconnect` and then `/confluence connect` to connect your account again, so that you keep receiving detailed notifications."
+)
+
+// refreshExpiringTokens refreshes the tokens of the connections that expire soon, so that the connections of the users
+// who have not used the plugin in a while stay valid.
+func (p *Plugin) refreshExpiringTokens() {
+	connections, err := p.listConnectionKeys()
+	if err != nil {
+		p.client.Log.Error("Error listing the stored connections", "error", err.Error())
+		return
+	}
+
+	refreshed, failed := 0, 0
+	for _, key := range connections {
+		ok, err := p.refreshExpiringToken(key, time.Now())
+		switch {
+		case err != nil:
+			failed++
+			p.client.Log.Warn("Error refreshing the token", "UserID", key.mattermostUserID, "InstanceID", key.instanceID, "error", err.Error())
+		case ok:
+			refreshed++
+		}
+	}
+
+	if refreshed > 0 || failed > 0 {
+		p.client.Log.Info("Refreshed the expiring tokens", "Refreshed", refreshed, "Failed", failed)
+	}
+}
+
+// refreshExpiringToken refreshes the token of the connection if it expires within the refresh window.
+// It returns whether the token was refreshed.
+func (p *Plugin) refreshExpiringToken(key connectionKey, now time.Time) (bool, error) {
+	connection, err := store.LoadConnection(key.instanceID, key.mattermostUserID)
+	if err != nil {
+		if errors.Cause(err) == store.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	if connection.OAuth2Token == "" {
+		return false, nil
+	}
+
+	token, err := p.ParseAuthToken(connection.OAuth2Token)
+	if err != nil {
+		return false, err
+	}
+	if token.Expiry.IsZero() || token.Expiry.Sub(now) > tokenRefreshWindow || token.RefreshToken == "" {
+		return false, nil
+	}
+
+	// The health of a connection rejected by Confluence is only recorded once, until the user reconnects.
+	if health, _ := store.LoadTokenHealth(key.instanceID, connection.MattermostUserID); health != nil && health.Status == types.TokenRejected {
+		return false, nil
+	}
+
+	oconf, err := p.getOAuth2Config(key.instanceID, connection)
+	if err != nil {
+		return false, err
+	}
+
+	// The token source only refreshes expired tokens.
+	expired := *token
+	expired.Expiry = now.Add(-time.Minute)
+	if _, err = p.refreshToken(connection, key.instanceID, oconf, &expired); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordTokenHealth records the outcome of a token refresh. When Confluence rejects the refresh token, the user is
+// asked to reconnect.
+func (p *Plugin) recordTokenHealth(instanceID, mattermostUserID string, token *oauth2.Token, refreshErr error) {
+	previous, err := store.LoadTokenHealth(instanceID, mattermostUserID)
+	if err != nil {
+		p.client.Log.Warn("Error loading the token health", "UserID", mattermostUserID, "InstanceID", instanceID, "error", err.Error())
+	}
+
+	health := &types.TokenHealth{
+		Status:    types.TokenHealthy,
+		CheckedAt: time.Now().UnixMilli(),
+	}
+	switch {
+	case refreshErr == nil:
+		health.ExpiresAt = token.Expiry.UnixMilli()
+	case isRefreshTokenRejected(refreshErr):
+		health.Status = types.TokenRejected
+		health.Error = refreshErr.Error()
+		if previous != nil && previous.Status == types.TokenRejected {
+			health.NotifiedAt = previous.NotifiedAt
+		}
+	default:
+		health.Status = types.TokenRefreshFailed
+		health.Error = refreshErr.Error()
+	}
+
+	if health.Status == types.TokenRejected && health.NotifiedAt == 0 {
+		if err = p.sendReconnectMessage(mattermostUserID); err != nil {
+			p.client.Log.Warn("Error asking the user to reconnect to Confluence", "UserID", mattermostUserID, "error", err.Error())
+		} else {
+			health.NotifiedAt = health.CheckedAt
+		}
+	}
+
+	if err = store.StoreTokenHealth(instanceID, mattermostUserID, health); err != nil {
+		p.client.Log.Warn("Error storing the token health", "UserID", mattermostUserID, "InstanceID", instanceID, "error", err.Error())
+	}
+}
+
+// isRefreshTokenRejected returns whether the refresh token is no longer valid, as opposed to a failure that may go away.
+func isRefreshTokenRejected(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+	return retrieveErr.ErrorCode == "invalid_grant" ||
+		(retrieveErr.Response != nil && retrieveErr.Response.StatusCode == http.StatusUnauthorized)
+}
+
+func formatTokenHealth(health *types.TokenHealth) string {
+	if health == nil {
+		return "Not checked yet"
+	}
+
+	checkedAt := time.UnixMilli(health.CheckedAt).UTC().Format("2006-01-02 15:04")
+	switch health.Status {
+	case types.TokenHealthy:
+		return fmt.Sprintf("Refreshed %s", checkedAt)
+	case types.TokenRejected:
+		return fmt.Sprintf(":x: Rejected by Confluence %s, the user needs to reconnect", checkedAt)
+	default:
+		return fmt.Sprintf(":warning: Refresh failed %s", checkedAt)
+	}
+}
+
+func (p *Plugin) sendReconnectMessage(mattermostUserID string) error {
+	channel, appErr := p.API.GetDirectChannel(mattermostUserID, config.BotUserID)
+	if appErr != nil {
+		return appErr
+	}
+
+	if _, appErr = p.API.CreatePost(&model.Post{
+		UserId:    config.BotUserID,
+		ChannelId: channel.Id,
+		Message:   reconnectMessage,
+	}); appErr != nil {
+		return appErr
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+func TestRecordTokenHealth(t *testing.T) {
+	rejected := &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusBadRequest}, ErrorCode: "invalid_grant"}
+
+	for name, tc := range map[string]struct {
+		previous       *types.TokenHealth
+		refreshErr     error
+		expectedStatus string
+		expectDM       bool
+	}{
+		"refreshed": {
+			expectedStatus: types.TokenHealthy,
+		},
+		"Confluence unavailable": {
+			refreshErr:     &oauth2.RetrieveError{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}},
+			expectedStatus: types.TokenRefreshFailed,
+		},
+		"network error": {
+			refreshErr:     errors.New("connection reset"),
+			expectedStatus: types.TokenRefreshFailed,
+		},
+		"refresh token rejected": {
+			refreshErr:     rejected,
+			expectedStatus: types.TokenRejected,
+			expectDM:       true,
+		},
+		"user already asked to reconnect": {
+			previous:       &types.TokenHealth{Status: types.TokenRejected, NotifiedAt: 1000},
+			refreshErr:     rejected,
+			expectedStatus: types.TokenRejected,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			config.BotUserID = "bot-id"
+
+			var previous []byte
+			if tc.previous != nil {
+				previous, _ = json.Marshal(tc.previous)
+			}
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(previous, nil)
+
+			var stored types.TokenHealth
+			mockAPI.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				require.NoError(t, json.Unmarshal(args.Get(1).([]byte), &stored))
+			})
+			mockAPI.On("GetDirectChannel", "user-id", "bot-id").Return(&model.Channel{Id: "dm-id"}, nil)
+			mockAPI.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{}, nil)
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			var token *oauth2.Token
+			if tc.refreshErr == nil {
+				token = &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}
+			}
+			p.recordTokenHealth("https://confluence.example.com", "user-id", token, tc.refreshErr)
+
+			assert.Equal(t, tc.expectedStatus, stored.Status)
+			if tc.expectDM {
+				mockAPI.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+				assert.NotZero(t, stored.NotifiedAt)
+			} else {
+				mockAPI.AssertNotCalled(t, "CreatePost", mock.Anything)
+			}
+			if tc.previous != nil {
+				assert.Equal(t, tc.previous.NotifiedAt, stored.NotifiedAt)
+			}
+		})
+	}
+}
+
+func TestRefreshExpiringToken(t *testing.T) {
+	now := time.Now()
+
+	for name, tc := range map[string]struct {
+		expiry          time.Time
+		rejected        bool
+		changedAfter    int // The number of loads of the connection after which another server stored a new token
+		expectedRefresh bool
+		expectedCalls   int
+	}{
+		"token expiring soon": {
+			expiry:          now.Add(30 * time.Minute),
+			expectedRefresh: true,
+			expectedCalls:   1,
+		},
+		"token already expired": {
+			expiry:          now.Add(-24 * time.Hour),
+			expectedRefresh: true,
+			expectedCalls:   1,
+		},
+		"token valid for a while": {
+			expiry: now.Add(24 * time.Hour),
+		},
+		"token refreshed by another server": {
+			expiry:          now.Add(30 * time.Minute),
+			changedAfter:    1,
+			expectedRefresh: true,
+		},
+		"refresh token rotated by another server during the refresh": {
+			expiry:          now.Add(30 * time.Minute),
+			rejected:        true,
+			changedAfter:    2,
+			expectedRefresh: true,
+			expectedCalls:   2, // The OAuth client retries the rejected request with the other way of authenticating itself.
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			refreshes := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/rest/oauth2/latest/token", r.URL.Path)
+				assert.Equal(t, "refresh", r.FormValue("refresh_token"))
+				refreshes++
+				w.Header().Set("Content-Type", "application/json")
+				if tc.rejected {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"access_token":"new-access","refresh_token":"new-refresh","token_type":"bearer","expires_in":3600}`))
+			}))
+			defer server.Close()
+
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			siteURL := "https://mattermost.example.com"
+			mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			config.SetConfig(&config.Configuration{
+				EncryptionKey:               "0123456789abcdef0123456789abcdef",
+				ConfluenceOAuthClientID:     "client",
+				ConfluenceOAuthClientSecret: "secret",
+			})
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			connection := func(token *oauth2.Token) []byte {
+				encoded, err := p.NewEncodedAuthToken(token)
+				require.NoError(t, err)
+				data, _ := json.Marshal(&types.Connection{
+					ConfluenceUser:   types.ConfluenceUser{AccountID: "ff80808"},
+					OAuth2Token:      encoded,
+					MattermostUserID: "user-id",
+				})
+				return data
+			}
+			data := connection(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: tc.expiry})
+			if tc.changedAfter > 0 {
+				mockAPI.On("KVGet", server.URL+"_user-id").Return(data, nil).Times(tc.changedAfter)
+				data = connection(&oauth2.Token{AccessToken: "other-access", RefreshToken: "other-refresh", Expiry: now.Add(time.Hour)})
+			}
+			mockAPI.On("KVGet", server.URL+"_user-id").Return(data, nil)
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+			mockAPI.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+			mockAPI.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(true, nil)
+			mockAPI.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			refreshed, err := p.refreshExpiringToken(connectionKey{instanceID: server.URL, mattermostUserID: "user-id"}, now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRefresh, refreshed)
+			assert.Equal(t, tc.expectedCalls, refreshes)
+			// The connection is not marked as rejected, which would ask the user to reconnect.
+			mockAPI.AssertNotCalled(t, "CreatePost", mock.Anything)
+		})
+	}
+}
@@ -13,9 +13,12 @@ import (
 	"golang.org/x/oauth2"
 
 	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 
 	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/metrics"
 	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
 	"github.com/mattermost/mattermost-plugin-confluence/server/store"
 	"github.com/mattermost/mattermost-plugin-confluence/server/util"
 	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
@@ -23,6 +26,8 @@ import (
 
 const (
 	AdminMattermostUserID = "admin"
+
+	tokenRefreshLockWait = 10 * time.Second
 )
 
 func httpOAuth2Connect(w http.ResponseWriter, r *http.Request, p *Plugin) {
@@ -212,17 +217,26 @@ func (p *Plugin) getUserConnectURL(instanceID string, mattermostUserID string, i
 	return conf.AuthCodeURL(state, oauth2.AccessTypeOffline), nil
 }
 
-func (p *Plugin) DisconnectUser(instanceURL string, mattermostUserID string) (*types.Connection, error) {
+func (p *Plugin) DisconnectUser(instanceURL string, mattermostUserID string) (*disconnection, error) {
 	user, err := store.LoadUser(mattermostUserID)
 	if err != nil {
 		p.client.Log.Error("Error loading the user", "UserID", user.MattermostUserID, "error", err.Error())
 		return nil, err
 	}
 
-	return p.disconnectUser(instanceURL, user)
+	return p.disconnectUser(instanceURL, user, mattermostUserID)
+}
+
+// disconnection is the outcome of disconnecting a user. The connection is deleted even when its token cannot be
+// revoked on Confluence, RevokeErr is then set so that the failure can be reported.
+type disconnection struct {
+	Connection *types.Connection
+	RevokeErr  error
 }
 
-func (p *Plugin) disconnectUser(instanceID string, user *types.User) (*types.Connection, error) {
+// disconnectUser revokes the token of the user on Confluence and deletes the connection. The actor is the user who
+// asked for the disconnection, it is empty when the plugin disconnects the user on its own.
+func (p *Plugin) disconnectUser(instanceID string, user *types.User, actorID string) (*disconnection, error) {
 	if user.InstanceURL != instanceID {
 		return nil, errors.Wrapf(store.ErrNotFound, "user is not connected to %q", instanceID)
 	}
@@ -233,21 +247,66 @@ func (p *Plugin) disconnectUser(instanceID string, user *types.User) (*types.Con
 		return nil, err
 	}
 
+	var revokeErr error
+	if conn.OAuth2Token != "" {
+		if revokeErr = p.revokeToken(instanceID, conn); revokeErr != nil {
+			p.client.Log.Warn("Error revoking the token on Confluence", "UserID", user.MattermostUserID, "InstanceID", instanceID, "error", revokeErr.Error())
+		}
+	}
+
 	if user.InstanceURL == instanceID {
 		user.InstanceURL = ""
 	}
 
-	if err = store.DeleteConnection(instanceID, user.MattermostUserID); err != nil && errors.Cause(err) != store.ErrNotFound {
+	if err = store.DeleteConnectionFromKVStore(instanceID, user.MattermostUserID, conn); err != nil {
 		p.client.Log.Error("Error deleting the connection", "UserID", user.MattermostUserID, "error", err.Error())
 		return nil, err
 	}
 
+	// The admin connection shares the token of the admin who set up the instance, which is no longer valid.
+	if conn.IsAdmin {
+		if adminConn, loadErr := store.LoadConnection(instanceID, AdminMattermostUserID); loadErr == nil && adminConn.MattermostUserID == user.MattermostUserID {
+			if err = store.DeleteConnectionFromKVStore(instanceID, AdminMattermostUserID, adminConn); err != nil {
+				p.client.Log.Error("Error deleting the admin connection", "UserID", user.MattermostUserID, "error", err.Error())
+				return nil, err
+			}
+		}
+	}
+
 	if err = store.StoreUser(user); err != nil {
 		p.client.Log.Error("Error storing the user", "UserID", user.MattermostUserID, "error", err.Error())
 		return nil, err
 	}
 
-	return conn, nil
+	service.RecordAudit(service.AuditEntry{Action: service.AuditActionUserDisconnected, ActorID: actorID, UserID: user.MattermostUserID})
+
+	return &disconnection{Connection: conn, RevokeErr: revokeErr}, nil
+}
+
+// disconnectDeactivatedUser revokes the token and deletes the connection of a user deactivated in Mattermost.
+// Failures are logged, there is nobody to report them to.
+func (p *Plugin) disconnectDeactivatedUser(mattermostUserID string) {
+	user, err := store.LoadUser(mattermostUserID)
+	if err != nil {
+		if errors.Cause(err) != store.ErrNotFound {
+			p.client.Log.Error("Error loading the deactivated user", "UserID", mattermostUserID, "error", err.Error())
+		}
+		return
+	}
+	if user.InstanceURL == "" {
+		return
+	}
+
+	disconnected, err := p.disconnectUser(user.InstanceURL, user, "")
+	if err != nil {
+		p.client.Log.Error("Error disconnecting the deactivated user", "UserID", mattermostUserID, "error", err.Error())
+		return
+	}
+	if disconnected.RevokeErr != nil {
+		p.client.Log.Warn("Disconnected the deactivated user, but their token could not be revoked on Confluence", "UserID", mattermostUserID, "error", disconnected.RevokeErr.Error())
+		return
+	}
+	p.client.Log.Info("Disconnected the deactivated user from Confluence", "UserID", mattermostUserID)
 }
 
 func (p *Plugin) connectUser(instanceID, mattermostUserID string, connection *types.Connection) error {
@@ -271,9 +330,12 @@ func (p *Plugin) connectUser(instanceID, mattermostUserID string, connection *ty
 		return err
 	}
 
-	if err = store.StoreConnection(instanceID, AdminMattermostUserID, connection); err != nil {
-		p.client.Log.Error("Error storing connection", "InstanceID", instanceID, "UserID", mattermostUserID, "error", err.Error())
-		return err
+	// The Cloud connections are only used on behalf of their own user, they are not shared as the admin connection.
+	if !connection.IsCloud() {
+		if err = store.StoreConnection(instanceID, AdminMattermostUserID, connection); err != nil {
+			p.client.Log.Error("Error storing connection", "InstanceID", instanceID, "UserID", mattermostUserID, "error", err.Error())
+			return err
+		}
 	}
 
 	if err = store.StoreUser(user); err != nil {
@@ -281,6 +343,8 @@ func (p *Plugin) connectUser(instanceID, mattermostUserID string, connection *ty
 		return err
 	}
 
+	service.RecordAudit(service.AuditEntry{Action: service.AuditActionUserConnected, ActorID: mattermostUserID, UserID: mattermostUserID})
+
 	if err = p.flowManager.StartCompletionWizard(mattermostUserID); err != nil {
 		return err
 	}
@@ -303,11 +367,48 @@ func (p *Plugin) refreshAndStoreToken(connection *types.Connection, instanceID s
 		return token, nil
 	}
 
-	src := oconf.TokenSource(context.Background(), token)
+	return p.refreshToken(connection, instanceID, oconf, token)
+}
+
+// refreshToken renews the token if it expired, stores the new token and records the health of the connection.
+// Confluence rotates the refresh tokens, so the refreshes of a connection are serialized across the cluster, and a
+// token already refreshed by another request is used instead of refreshing it again.
+func (p *Plugin) refreshToken(connection *types.Connection, instanceID string, oconf *oauth2.Config, token *oauth2.Token) (*oauth2.Token, error) {
+	mutex, err := cluster.NewMutex(p.API, tokenRefreshLockKey(instanceID, connection.MattermostUserID))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create the token refresh lock")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshLockWait)
+	defer cancel()
+	if err = mutex.LockWithContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "the token is being refreshed by another request")
+	}
+	defer mutex.Unlock()
+
+	if stored, storedToken := p.loadChangedToken(instanceID, connection.MattermostUserID, token); storedToken != nil {
+		connection.OAuth2Token = stored.OAuth2Token
+		if time.Until(storedToken.Expiry) > 1*time.Minute {
+			return storedToken, nil
+		}
+		token = storedToken
+	}
+
+	src := oconf.TokenSource(confluenceContext(), token)
 	newToken, err := src.Token() // this actually goes and renews the tokens
 	if err != nil {
+		if isRefreshTokenRejected(err) {
+			// The refresh token may have been rotated by a refresh that did not wait for the lock.
+			if stored, storedToken := p.loadChangedToken(instanceID, connection.MattermostUserID, token); storedToken != nil {
+				connection.OAuth2Token = stored.OAuth2Token
+				return storedToken, nil
+			}
+		}
+		metrics.TokenRefreshes.WithLabelValues(metrics.ResultFailure).Inc()
+		p.recordTokenHealth(instanceID, connection.MattermostUserID, nil, err)
 		return nil, errors.Wrap(err, "unable to get the new refreshed token")
 	}
+	metrics.TokenRefreshes.WithLabelValues(metrics.ResultSuccess).Inc()
+	p.recordTokenHealth(instanceID, connection.MattermostUserID, newToken, nil)
 	if newToken.AccessToken != token.AccessToken {
 		encryptedToken, err := p.NewEncodedAuthToken(newToken)
 		if err != nil {
@@ -332,8 +433,26 @@ func (p *Plugin) refreshAndStoreToken(connection *types.Connection, instanceID s
 	return token, nil
 }
 
+func tokenRefreshLockKey(instanceID, mattermostUserID string) string {
+	return "token_refresh_" + util.GetKeyHash(instanceID+"/"+mattermostUserID)
+}
+
+// loadChangedToken returns the stored connection and its token when the stored token is no longer the given one.
+func (p *Plugin) loadChangedToken(instanceID, mattermostUserID string, token *oauth2.Token) (*types.Connection, *oauth2.Token) {
+	stored, err := store.LoadConnection(instanceID, mattermostUserID)
+	if err != nil || stored.OAuth2Token == "" {
+		return nil, nil
+	}
+	storedToken, err := p.ParseAuthToken(stored.OAuth2Token)
+	if err != nil || storedToken.AccessToken == token.AccessToken {
+		return nil, nil
+	}
+	return stored, storedToken
+}
+
 type UserConnectionInfo struct {
 	CanRunSubscribeCommand    bool `json:"can_run_subscribe_command"`
+	CanManageSubscriptions    bool `json:"can_manage_subscriptions"`
 	ServerVersionGreaterthan9 bool `json:"server_version_greater_than_9"`
 }
 
@@ -347,10 +466,12 @@ func httpGetUserInfo(w http.ResponseWriter, r *http.Request, p *Plugin) {
 
 	mattermostUserID := r.Header.Get(config.HeaderMattermostUserID)
 	serverVersionGreaterThan9 := config.GetConfig().ServerVersionGreaterthan9
+	canManageSubscriptions := p.canManageSubscriptions(mattermostUserID, r.FormValue("channel_id"))
 
-	if !serverVersionGreaterThan9 {
+	if !serverVersionGreaterThan9 || !canManageSubscriptions {
 		info := &UserConnectionInfo{
-			CanRunSubscribeCommand:    util.IsSystemAdmin(mattermostUserID),
+			CanRunSubscribeCommand:    canManageSubscriptions,
+			CanManageSubscriptions:    canManageSubscriptions,
 			ServerVersionGreaterthan9: serverVersionGreaterThan9,
 		}
 		b, _ := json.Marshal(info)
@@ -372,6 +493,7 @@ func httpGetUserInfo(w http.ResponseWriter, r *http.Request, p *Plugin) {
 		if strings.Contains(err.Error(), "not found") {
 			info := &UserConnectionInfo{
 				CanRunSubscribeCommand:    false,
+				CanManageSubscriptions:    canManageSubscriptions,
 				ServerVersionGreaterthan9: serverVersionGreaterThan9,
 			}
 			b, _ := json.Marshal(info)
@@ -387,6 +509,7 @@ func httpGetUserInfo(w http.ResponseWriter, r *http.Request, p *Plugin) {
 
 	info := &UserConnectionInfo{
 		CanRunSubscribeCommand:    len(connection.ConfluenceAccountID()) != 0,
+		CanManageSubscriptions:    canManageSubscriptions,
 		ServerVersionGreaterthan9: serverVersionGreaterThan9,
 	}
 
@@ -414,18 +537,12 @@ func (p *Plugin) validateUserConfluenceAccess(userID, confluenceURL, subscriptio
 		return http.StatusUnauthorized, errors.New("User needs to connect their Confluence account")
 	}
 
-	client, err := p.GetServerClient(confluenceURL, conn)
+	client, err := p.GetClient(confluenceURL, conn)
 	if err != nil {
 		p.client.Log.Error("Error getting Confluence client. UserID: %s. Error: %s", userID, err.Error())
 		return http.StatusInternalServerError, errors.New("An error occurred while connecting to Confluence. Please try again later")
 	}
 
-	serverClient, ok := client.(*confluenceServerClient)
-	if !ok {
-		p.client.Log.Error("Invalid Confluence server client type while validating user's Confluence access. UserID: %s", userID)
-		return http.StatusInternalServerError, errors.New("an unexpected error occurred. Please try again later")
-	}
-
 	switch subscriptionType {
 	case serializer.SubscriptionTypeSpace:
 		spaceSub, ok := subscription.(serializer.SpaceSubscription)
@@ -433,7 +550,7 @@ func (p *Plugin) validateUserConfluenceAccess(userID, confluenceURL, subscriptio
 			p.client.Log.Error("Failed to parse space subscription. UserID: %s", userID)
 			return http.StatusBadRequest, errors.New("invalid space subscription details provided")
 		}
-		if _, err = serverClient.GetSpaceData(spaceSub.SpaceKey); err != nil {
+		if err = client.CheckSpaceAccess(spaceSub.SpaceKey); err != nil {
 			p.client.Log.Error("User does not have access to the space. UserID: %s, SpaceKey: %s. Error: %s", userID, spaceSub.SpaceKey, err.Error())
 			return http.StatusForbidden, errors.New("User does not have an access to this Confluence space")
 		}
@@ -450,7 +567,7 @@ func (p *Plugin) validateUserConfluenceAccess(userID, confluenceURL, subscriptio
 			return http.StatusInternalServerError, errors.New("an error occurred while processing the page details. Please try again later")
 		}
 
-		if _, err := serverClient.GetPageData(pageID); err != nil {
+		if _, err := client.GetPageData(pageID); err != nil {
 			p.client.Log.Error("User does not have access to the page. UserID: %s, PageID: %d. Error: %s", userID, pageID, err.Error())
 			return http.StatusForbidden, errors.New("User does not have an access to this Confluence page")
 		}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/httpclient"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+const cloudCompleteTemplate = "/api/v1/oauth2/complete.html"
+
+func httpOAuth2ConnectCloud(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	mattermostUserID := r.Header.Get(config.HeaderMattermostUserID)
+
+	pluginConfig := config.GetConfig()
+	if !pluginConfig.IsCloudOAuthConfigured() {
+		p.client.Log.Error("Confluence Cloud OAuth is not configured", "UserID", mattermostUserID)
+		http.Error(w, "Confluence Cloud OAuth is not configured. Please ask the system administrator to set it up in the plugin settings.", http.StatusInternalServerError)
+		return
+	}
+
+	if user, err := store.LoadUser(mattermostUserID); err == nil && user.InstanceURL != "" {
+		p.client.Log.Info("User already has a Confluence account connected", "UserID", mattermostUserID)
+		_, _ = respondErr(w, http.StatusBadRequest, errors.New("User already has a Confluence account linked. Use `/confluence disconnect` to unlink"))
+		return
+	}
+
+	redirectURL, err := p.getCloudUserConnectURL(mattermostUserID)
+	if err != nil {
+		p.client.Log.Error("Error generating the Confluence Cloud connect URL", "UserID", mattermostUserID, "error", err.Error())
+		_, _ = respondErr(w, http.StatusInternalServerError, errors.New("an error occurred while initiating Confluence connection. Please try again later"))
+		return
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+func httpOAuth2CompleteCloud(w http.ResponseWriter, r *http.Request, p *Plugin) {
+	var err error
+	var status int
+
+	// Prettify and present errors on the template page
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		errText := err.Error()
+		if len(errText) > 0 {
+			errText = strings.ToUpper(errText[:1]) + errText[1:]
+		}
+
+		status, err = p.respondTemplate(w, "/other/message.html", nil, status, "text/html", struct {
+			Header  string
+			Message string
+		}{
+			Header:  "Failed to connect to Confluence.",
+			Message: errText,
+		})
+	}()
+
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+	if code == "" || state == "" {
+		err = errors.New("missing authorization code or state")
+		status = http.StatusBadRequest
+		p.client.Log.Error("Confluence Cloud OAuth2 completion failed", "error", err.Error())
+		return
+	}
+
+	siteURL := config.GetConfig().ConfluenceCloudURL
+	if siteURL == "" {
+		err = errors.New("missing Confluence Cloud URL")
+		status = http.StatusInternalServerError
+		p.client.Log.Error("Confluence Cloud OAuth2 completion failed", "error", err.Error())
+		return
+	}
+
+	cuser, mmuser, completeErr := p.CompleteCloudOAuth2(r.Header.Get(config.HeaderMattermostUserID), code, state, siteURL)
+	if completeErr != nil {
+		err = errors.Wrap(completeErr, "an error occurred while completing the Confluence connection")
+		status = http.StatusInternalServerError
+		p.client.Log.Error("Confluence Cloud OAuth2 completion failed", "error", completeErr.Error())
+		return
+	}
+
+	_, _ = p.respondTemplate(w, cloudCompleteTemplate, r, http.StatusOK, "text/html", struct {
+		MattermostDisplayName string
+		ConfluenceDisplayName string
+	}{
+		ConfluenceDisplayName: cuser.DisplayName,
+		MattermostDisplayName: mmuser.GetDisplayName(model.ShowNicknameFullName),
+	})
+}
+
+// CompleteCloudOAuth2 exchanges the authorization code for a token, looks up the cloud ID of the site among the
+// resources the user granted access to, and connects the user.
+func (p *Plugin) CompleteCloudOAuth2(mattermostUserID, code, state, siteURL string) (*types.ConfluenceUser, *model.User, error) {
+	if mattermostUserID == "" || code == "" || state == "" {
+		return nil, nil, errors.New("missing user, code or state")
+	}
+
+	if !strings.HasSuffix(state, "_"+mattermostUserID) {
+		return nil, nil, errors.New("the authorization state was issued to another user")
+	}
+	if err := store.VerifyOAuth2State(state); err != nil {
+		p.client.Log.Error("Error verifying OAuth2 state", "State", state, "error", err.Error())
+		return nil, nil, errors.WithMessage(err, "missing stored state")
+	}
+
+	mmuser, appErr := p.API.GetUser(mattermostUserID)
+	if appErr != nil {
+		return nil, nil, fmt.Errorf("failed to load user %s", mattermostUserID)
+	}
+
+	oconf, err := p.GetCloudOAuth2Config()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(confluenceContext(), 10*time.Second)
+	defer cancel()
+	tok, err := oconf.Exchange(ctx, code)
+	if err != nil {
+		p.client.Log.Error("Error converting authorization code into token", "error", err.Error())
+		return nil, nil, err
+	}
+
+	httpClient := oconf.Client(confluenceContext(), tok)
+	httpClient.Timeout = httpclient.DefaultTimeout
+
+	resources, err := getAccessibleResources(httpClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	resource, err := findAccessibleResource(resources, siteURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	confluenceUser, err := newCloudClient(siteURL, resource.ID, httpClient).GetSelf()
+	if err != nil {
+		p.client.Log.Error("Error getting the Confluence Cloud user", "error", err.Error())
+		return nil, nil, err
+	}
+
+	encryptedToken, err := p.NewEncodedAuthToken(tok)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	connection := &types.Connection{
+		ConfluenceUser:   *confluenceUser,
+		OAuth2Token:      encryptedToken,
+		MattermostUserID: mattermostUserID,
+		CloudID:          resource.ID,
+	}
+	if err = p.connectUser(siteURL, mattermostUserID, connection); err != nil {
+		return nil, nil, err
+	}
+
+	return &connection.ConfluenceUser, mmuser, nil
+}
+
+func (p *Plugin) getCloudUserConnectURL(mattermostUserID string) (string, error) {
+	conf, err := p.GetCloudOAuth2Config()
+	if err != nil {
+		return "", err
+	}
+
+	state := fmt.Sprintf("%v_%v", model.NewId()[0:15], mattermostUserID)
+	if err = store.StoreOAuth2State(state); err != nil {
+		p.client.Log.Error("Error storing the OAuth2 state", "State", state, "error", err.Error())
+		return "", err
+	}
+
+	// Atlassian requires the audience of the API gateway, and only issues refresh tokens after the consent screen.
+	return conf.AuthCodeURL(state, oauth2.SetAuthURLParam("audience", "api.atlassian.com"), oauth2.SetAuthURLParam("prompt", "consent")), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+// getMattermostMention returns the @mention of the Mattermost user behind a Confluence user: the user who connected
+// the Confluence account, or, when matching users by email is enabled, the user with the same email address.
+// It returns an empty string when no active Mattermost user is found.
+func (p *Plugin) getMattermostMention(instanceID, confluenceUserKey, email string) string {
+	if confluenceUserKey != "" {
+		mmUserID, err := store.GetMattermostUserIDFromConfluenceID(instanceID, confluenceUserKey)
+		switch {
+		case err == nil && *mmUserID != "":
+			user, appErr := p.API.GetUser(*mmUserID)
+			if appErr != nil {
+				p.client.Log.Warn("Unable to get the Mattermost user connected to the Confluence user", "UserID", *mmUserID, "error", appErr.Error())
+			} else if m := mention(user); m != "" {
+				return m
+			}
+		case err != nil && !errors.Is(err, store.ErrNotFound):
+			p.client.Log.Warn("Error getting Mattermost User ID from Confluence ID", "InstanceID", instanceID, "error", err.Error())
+		}
+	}
+
+	if email == "" || !config.GetConfig().MatchUsersByEmail {
+		return ""
+	}
+
+	user, appErr := p.API.GetUserByEmail(email)
+	if appErr != nil {
+		return ""
+	}
+	return mention(user)
+}
+
+// getServerEventMention returns the mention of the user who triggered an event sent by the Mattermost app for
+// Confluence Server 8 and below. As for Confluence 9, the user is only matched when it can be read with the admin
+// API token, the email address of the payload is not trusted.
+func (p *Plugin) getServerEventMention(instanceID string, eventUser *serializer.ConfluenceServerUser, pluginConfig *config.Configuration) string {
+	if eventUser == nil || eventUser.Username == "" || pluginConfig.AdminAPIToken == "" {
+		return ""
+	}
+
+	user, err := p.GetUserFromUsernameWithAPIToken(eventUser.Username, pluginConfig)
+	if err != nil {
+		p.client.Log.Warn("Error getting details of the event triggerer user using API token", "error", err.Error())
+		return ""
+	}
+	return p.getMattermostMention(instanceID, user.UserKey, user.Email)
+}
+
+func mention(user *model.User) string {
+	if user.DeleteAt != 0 || user.IsBot {
+		return ""
+	}
+	return "@" + user.Username
+}
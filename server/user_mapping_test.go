@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+)
+
+func TestGetMattermostMention(t *testing.T) {
+	for name, tc := range map[string]struct {
+		userKey           string
+		email             string
+		matchUsersByEmail bool
+		connectedUser     *model.User
+		emailUser         *model.User
+		expected          string
+	}{
+		"connected user": {
+			userKey:       "ff80808",
+			connectedUser: &model.User{Id: "mm-user", Username: "jane"},
+			expected:      "@jane",
+		},
+		"connected user deactivated": {
+			userKey:       "ff80808",
+			connectedUser: &model.User{Id: "mm-user", Username: "jane", DeleteAt: 1},
+		},
+		"user not connected": {
+			userKey: "ff80808",
+			email:   "jane@example.com",
+		},
+		"matched on email": {
+			userKey:           "ff80808",
+			email:             "jane@example.com",
+			matchUsersByEmail: true,
+			emailUser:         &model.User{Id: "mm-user", Username: "jane"},
+			expected:          "@jane",
+		},
+		"no user with the email": {
+			email:             "jane@example.com",
+			matchUsersByEmail: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			config.SetConfig(&config.Configuration{MatchUsersByEmail: tc.matchUsersByEmail})
+
+			var mapping []byte
+			if tc.connectedUser != nil {
+				mapping, _ = json.Marshal(tc.connectedUser.Id)
+				mockAPI.On("GetUser", tc.connectedUser.Id).Return(tc.connectedUser, nil)
+			}
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(mapping, nil)
+			if tc.emailUser != nil {
+				mockAPI.On("GetUserByEmail", tc.email).Return(tc.emailUser, nil)
+			} else {
+				mockAPI.On("GetUserByEmail", mock.Anything).Return(nil, &model.AppError{Message: "not found"})
+			}
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			assert.Equal(t, tc.expected, p.getMattermostMention("https://confluence.example.com", tc.userKey, tc.email))
+		})
+	}
+}
+
+func TestGetServerEventMention(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer admin-token", r.Header.Get("Authorization"))
+		if r.URL.Path != PathUserData || r.URL.Query().Get("username") != "jane" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"userKey":"ff80808","username":"jane","email":"jane@example.com"}`))
+	}))
+	defer server.Close()
+
+	for name, tc := range map[string]struct {
+		adminAPIToken string
+		eventUser     *serializer.ConfluenceServerUser
+		expected      string
+	}{
+		"matched on the email read with the admin API token": {
+			adminAPIToken: "admin-token",
+			eventUser:     &serializer.ConfluenceServerUser{Username: "jane", Email: "spoofed@example.com"},
+			expected:      "@jane",
+		},
+		"payload email not trusted without the admin API token": {
+			eventUser: &serializer.ConfluenceServerUser{Username: "jane", Email: "jane@example.com"},
+		},
+		"user not found": {
+			adminAPIToken: "admin-token",
+			eventUser:     &serializer.ConfluenceServerUser{Username: "john", Email: "jane@example.com"},
+		},
+		"no user": {
+			adminAPIToken: "admin-token",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			pluginConfig := &config.Configuration{ConfluenceURL: server.URL, AdminAPIToken: tc.adminAPIToken, MatchUsersByEmail: true}
+			config.SetConfig(pluginConfig)
+
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+			mockAPI.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+			mockAPI.On("GetUserByEmail", "jane@example.com").Return(&model.User{Id: "mm-user", Username: "jane"}, nil)
+			mockAPI.On("GetUserByEmail", mock.Anything).Return(nil, &model.AppError{Message: "not found"})
+			mockAPI.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			assert.Equal(t, tc.expected, p.getServerEventMention(server.URL, tc.eventUser, pluginConfig))
+		})
+	}
+}
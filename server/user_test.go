@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/service"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+func TestDisconnectUser(t *testing.T) {
+	for name, tc := range map[string]struct {
+		revokeStatus      int
+		expectedRevokeErr bool
+	}{
+		"token revoked": {
+			revokeStatus: http.StatusOK,
+		},
+		"connection deleted when the token cannot be revoked": {
+			revokeStatus:      http.StatusInternalServerError,
+			expectedRevokeErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/rest/oauth2/latest/revoke", r.URL.Path)
+				w.WriteHeader(tc.revokeStatus)
+			}))
+			defer server.Close()
+
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			siteURL := "https://mattermost.example.com"
+			mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			config.SetConfig(&config.Configuration{EncryptionKey: "0123456789abcdef0123456789abcdef"})
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			encoded, err := p.NewEncodedAuthToken(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"})
+			require.NoError(t, err)
+			connection := &types.Connection{
+				ConfluenceUser:   types.ConfluenceUser{AccountID: "ff80808", DisplayName: "Jane Doe"},
+				OAuth2Token:      encoded,
+				MattermostUserID: "user-id",
+			}
+			data, _ := json.Marshal(connection)
+
+			connectionKey := server.URL + "_user-id"
+			mockAPI.On("KVGet", connectionKey).Return(data, nil)
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+			mockAPI.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+			mockAPI.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+			var audit []service.AuditEntry
+			mockAPI.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				var entries []service.AuditEntry
+				if json.Unmarshal(args.Get(2).([]byte), &entries) == nil {
+					audit = entries
+				}
+			}).Return(true, nil)
+			mockAPI.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+			mockAPI.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			user := &types.User{MattermostUserID: "user-id", InstanceURL: server.URL}
+			disconnected, err := p.disconnectUser(server.URL, user, "admin-id")
+			require.NoError(t, err)
+
+			assert.Equal(t, "Jane Doe", disconnected.Connection.DisplayName)
+			assert.Equal(t, tc.expectedRevokeErr, disconnected.RevokeErr != nil)
+			assert.Empty(t, user.InstanceURL)
+			mockAPI.AssertCalled(t, "KVDelete", connectionKey)
+			require.Len(t, audit, 1)
+			assert.Equal(t, service.AuditActionUserDisconnected, audit[0].Action)
+			assert.Equal(t, "admin-id", audit[0].ActorID)
+			assert.Equal(t, "user-id", audit[0].UserID)
+		})
+	}
+}
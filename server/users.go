@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+const (
+	usersOnlySystemAdmin = "`/confluence users` can only be run by a system administrator."
+	noConnectedUsers     = "No user is connected to Confluence."
+	usersDisconnectUsage = "Please specify the user to disconnect, e.g. `/confluence users disconnect @jane`."
+)
+
+// connectedUser is a Mattermost user connected to a Confluence instance.
+type connectedUser struct {
+	MattermostUserID    string
+	Username            string
+	InstanceID          string
+	ConfluenceAccountID string
+	ConfluenceName      string
+	IsAdmin             bool
+	TokenExpiry         time.Time
+	TokenHealth         *types.TokenHealth
+}
+
+func executeUsers(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, usersOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	if len(args) > 0 && args[0] == "disconnect" {
+		postCommandResponse(context, p.executeUsersDisconnect(context.UserId, args[1:]))
+		return &model.CommandResponse{}
+	}
+
+	instanceID, err := parseUsersArgs(args)
+	if err != nil {
+		postCommandResponse(context, err.Error())
+		return &model.CommandResponse{}
+	}
+
+	users, err := p.listConnectedUsers(instanceID)
+	if err != nil {
+		p.client.Log.Error("Error listing the connected users", "error", err.Error())
+		postCommandResponse(context, fmt.Sprintf("Failed to list the connected users. Error: %v", err))
+		return &model.CommandResponse{}
+	}
+
+	postCommandResponse(context, formatConnectedUsers(users, time.Now()))
+	return &model.CommandResponse{}
+}
+
+// parseUsersArgs parses the arguments of `/confluence users [--instance <URL>]`.
+func parseUsersArgs(args []string) (string, error) {
+	instanceID := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--instance":
+			if i+1 >= len(args) {
+				return "", errors.New("Please specify the URL of the Confluence instance for `--instance`.")
+			}
+			i++
+			instanceID = strings.TrimRight(args[i], "/")
+		default:
+			return "", errors.Errorf("Unexpected argument %q.", args[i])
+		}
+	}
+	return instanceID, nil
+}
+
+func (p *Plugin) executeUsersDisconnect(actorID string, args []string) string {
+	if len(args) != 1 {
+		return usersDisconnectUsage
+	}
+
+	username := strings.TrimPrefix(args[0], "@")
+	mmUser, appErr := p.API.GetUserByUsername(username)
+	if appErr != nil {
+		return fmt.Sprintf("User **@%s** not found.", username)
+	}
+
+	user, err := store.LoadUser(mmUser.Id)
+	if err != nil || user.InstanceURL == "" {
+		return fmt.Sprintf("**@%s** is not connected to Confluence.", username)
+	}
+
+	disconnected, err := p.disconnectUser(user.InstanceURL, user, actorID)
+	if err != nil {
+		if errors.Cause(err) == store.ErrNotFound {
+			return fmt.Sprintf("**@%s** is not connected to Confluence.", username)
+		}
+		p.client.Log.Error("Error disconnecting the user", "UserID", mmUser.Id, "error", err.Error())
+		return fmt.Sprintf("Failed to disconnect **@%s**. Error: %v", username, err)
+	}
+
+	message := fmt.Sprintf("**@%s** was disconnected from the Confluence account **%s**.", username, disconnected.Connection.DisplayName)
+	if disconnected.RevokeErr != nil {
+		message += fmt.Sprintf("\nThe token could not be revoked on Confluence, please revoke it from the Confluence administration. Error: %v", disconnected.RevokeErr)
+	}
+	return message
+}
+
+// listConnectedUsers returns the users connected to the instance, or to any instance when instanceID is empty.
+func (p *Plugin) listConnectedUsers(instanceID string) ([]connectedUser, error) {
+	userIDs, err := store.ListUserIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []connectedUser
+	for _, userID := range userIDs {
+		user, err := store.LoadUser(userID)
+		if err != nil {
+			p.client.Log.Warn("Error loading the user", "UserID", userID, "error", err.Error())
+			continue
+		}
+		if user.InstanceURL == "" || (instanceID != "" && user.InstanceURL != instanceID) {
+			continue
+		}
+
+		connection, err := store.LoadConnection(user.InstanceURL, userID)
+		if err != nil {
+			p.client.Log.Warn("Error loading the connection", "UserID", userID, "InstanceURL", user.InstanceURL, "error", err.Error())
+			continue
+		}
+
+		connected := connectedUser{
+			MattermostUserID:    userID,
+			Username:            userID,
+			InstanceID:          user.InstanceURL,
+			ConfluenceAccountID: connection.ConfluenceAccountID(),
+			ConfluenceName:      connection.DisplayName,
+			IsAdmin:             connection.IsAdmin,
+		}
+		if mmUser, appErr := p.API.GetUser(userID); appErr == nil {
+			connected.Username = "@" + mmUser.Username
+		}
+		if connection.OAuth2Token != "" {
+			if token, err := p.ParseAuthToken(connection.OAuth2Token); err == nil {
+				connected.TokenExpiry = token.Expiry
+			}
+		}
+		if health, err := store.LoadTokenHealth(user.InstanceURL, userID); err == nil {
+			connected.TokenHealth = health
+		}
+		users = append(users, connected)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].InstanceID != users[j].InstanceID {
+			return users[i].InstanceID < users[j].InstanceID
+		}
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
+}
+
+func formatConnectedUsers(users []connectedUser, now time.Time) string {
+	if len(users) == 0 {
+		return noConnectedUsers
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| User | Instance | Confluence account | Confluence admin | Token expiry (UTC) | Token health |\n| :--- | :--- | :--- | :--- | :--- | :--- |\n")
+	for _, user := range users {
+		admin := ""
+		if user.IsAdmin {
+			admin = "Yes"
+		}
+
+		expiry := "Unknown"
+		if !user.TokenExpiry.IsZero() {
+			expiry = user.TokenExpiry.UTC().Format("2006-01-02 15:04")
+			if !user.TokenExpiry.After(now) {
+				expiry += " (expired)"
+			}
+		}
+
+		fmt.Fprintf(&sb, "| %s | %s | %s (`%s`) | %s | %s | %s |\n", user.Username, user.InstanceID, user.ConfluenceName, user.ConfluenceAccountID, admin, expiry, formatTokenHealth(user.TokenHealth))
+	}
+	fmt.Fprintf(&sb, "\n%d connected users.", len(users))
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+func TestParseUsersArgs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args             []string
+		expectedInstance string
+		expectError      bool
+	}{
+		"no arguments": {},
+		"instance": {
+			args:             []string{"--instance", "https://confluence.example.com/"},
+			expectedInstance: "https://confluence.example.com",
+		},
+		"missing instance": {
+			args:        []string{"--instance"},
+			expectError: true,
+		},
+		"unexpected argument": {
+			args:        []string{"all"},
+			expectError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			instanceID, err := parseUsersArgs(tc.args)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedInstance, instanceID)
+		})
+	}
+}
+
+func TestFormatConnectedUsers(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, noConnectedUsers, formatConnectedUsers(nil, now))
+
+	out := formatConnectedUsers([]connectedUser{
+		{Username: "@jane", InstanceID: "https://confluence.example.com", ConfluenceAccountID: "ff80808", ConfluenceName: "Jane Doe", IsAdmin: true, TokenExpiry: now.Add(time.Hour)},
+		{Username: "@john", InstanceID: "https://confluence.example.com", ConfluenceAccountID: "ff80809", ConfluenceName: "John Doe", TokenExpiry: now.Add(-time.Hour)},
+	}, now)
+
+	assert.Contains(t, out, "| @jane | https://confluence.example.com | Jane Doe (`ff80808`) | Yes | 2024-05-01 11:00 | Not checked yet |")
+	assert.Contains(t, out, "| @john | https://confluence.example.com | John Doe (`ff80809`) |  | 2024-05-01 09:00 (expired) |")
+	assert.Contains(t, out, "2 connected users.")
+}
+
+func TestRevokeServerToken(t *testing.T) {
+	for name, tc := range map[string]struct {
+		token             *oauth2.Token
+		status            int
+		expectedToken     string
+		expectedTokenHint string
+		expectError       bool
+	}{
+		"refresh token revoked": {
+			token:             &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"},
+			status:            http.StatusOK,
+			expectedToken:     "refresh",
+			expectedTokenHint: "refresh_token",
+		},
+		"access token revoked without refresh token": {
+			token:             &oauth2.Token{AccessToken: "access"},
+			status:            http.StatusOK,
+			expectedToken:     "access",
+			expectedTokenHint: "access_token",
+		},
+		"revocation rejected": {
+			token:             &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"},
+			status:            http.StatusBadRequest,
+			expectedToken:     "refresh",
+			expectedTokenHint: "refresh_token",
+			expectError:       true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/rest/oauth2/latest/revoke", r.URL.Path)
+				assert.Equal(t, tc.expectedToken, r.FormValue("token"))
+				assert.Equal(t, tc.expectedTokenHint, r.FormValue("token_type_hint"))
+				assert.Equal(t, "client", r.FormValue("client_id"))
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			siteURL := "https://mattermost.example.com"
+			mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			config.SetConfig(&config.Configuration{
+				EncryptionKey:               "0123456789abcdef0123456789abcdef",
+				ConfluenceOAuthClientID:     "client",
+				ConfluenceOAuthClientSecret: "secret",
+			})
+
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			encoded, err := p.NewEncodedAuthToken(tc.token)
+			require.NoError(t, err)
+
+			err = p.revokeServerToken(server.URL, &types.Connection{OAuth2Token: encoded})
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
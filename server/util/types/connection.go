@@ -25,6 +25,13 @@ type Connection struct {
 	DefaultProjectKey string `json:"default_project_key,omitempty"`
 	IsAdmin           bool   `json:"is_admin,omitempty"`
 	MattermostUserID  string `json:"mattermost_user_id,omitempty"`
+	// CloudID identifies the Confluence Cloud site of a 3LO connection, it is empty for Data Center connections.
+	CloudID string `json:"cloud_id,omitempty"`
+}
+
+// IsCloud returns whether the connection was made to Confluence Cloud with OAuth 2.0 (3LO).
+func (c *Connection) IsCloud() bool {
+	return c.CloudID != ""
 }
 
 func (c *Connection) ConfluenceAccountID() string {
@@ -0,0 +1,11 @@
+package types
+
+// KeyRotationStatus tracks the progress of re-encrypting the stored tokens with the current encryption key.
+type KeyRotationStatus struct {
+	KeyID       string `json:"key_id"`
+	Total       int    `json:"total"`
+	Reencrypted int    `json:"reencrypted"`
+	Failed      int    `json:"failed"`
+	StartedAt   int64  `json:"started_at"`
+	FinishedAt  int64  `json:"finished_at,omitempty"`
+}
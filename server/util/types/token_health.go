@@ -0,0 +1,17 @@
+package types
+
+// Health of a stored OAuth token
+const (
+	TokenHealthy       = "healthy"
+	TokenRefreshFailed = "refresh_failed" // The refresh failed for a reason that may go away, e.g. Confluence was unavailable
+	TokenRejected      = "rejected"       // Confluence rejected the refresh token, the user needs to reconnect
+)
+
+// TokenHealth records the outcome of the latest refresh of the token of a connection.
+type TokenHealth struct {
+	Status     string `json:"status"`
+	CheckedAt  int64  `json:"checked_at"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+	Error      string `json:"error,omitempty"`
+	NotifiedAt int64  `json:"notified_at,omitempty"` // When the user was asked to reconnect
+}
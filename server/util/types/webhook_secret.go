@@ -0,0 +1,19 @@
+package types
+
+// WebhookSecretState keeps a hash of the webhook secret that was replaced by the current one, so that webhooks
+// still configured with it keep working for a grace period. The uses of the previous secret are stored under their
+// own keys, UsageKeys lists them.
+type WebhookSecretState struct {
+	CurrentHash  string   `json:"current_hash"`
+	PreviousHash string   `json:"previous_hash,omitempty"`
+	RotatedAt    int64    `json:"rotated_at,omitempty"`
+	UsageKeys    []string `json:"usage_keys,omitempty"`
+}
+
+// PreviousSecretUse records the requests made to an endpoint with the previous webhook secret.
+type PreviousSecretUse struct {
+	Endpoint   string `json:"endpoint"`
+	RemoteAddr string `json:"remote_addr"`
+	Count      int    `json:"count"`
+	LastUsedAt int64  `json:"last_used_at"`
+}
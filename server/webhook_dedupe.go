@@ -0,0 +1,39 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+)
+
+// webhookDedupeTTL is how long a webhook delivery is remembered. Confluence retries failed deliveries well within it.
+const webhookDedupeTTL = 24 * time.Hour
+
+// isDuplicateWebhook records the webhook delivery and reports whether it was already handled.
+// If the delivery cannot be recorded, it is handled anyway: posting a notification twice is better than losing it.
+func (p *Plugin) isDuplicateWebhook(dedupeKey string) bool {
+	isNew, err := store.MarkWebhookDelivery(dedupeKey, webhookDedupeTTL)
+	if err != nil {
+		p.client.Log.Warn("Unable to record the webhook delivery", "DedupeKey", dedupeKey, "error", err.Error())
+		return false
+	}
+	if !isNew {
+		p.client.Log.Debug("Ignoring a repeated webhook delivery", "DedupeKey", dedupeKey)
+	}
+	return !isNew
+}
+
+// forgetWebhookDelivery lets a retry of a delivery that could not be handled go through.
+func (p *Plugin) forgetWebhookDelivery(dedupeKey string) {
+	if err := store.ForgetWebhookDelivery(dedupeKey); err != nil {
+		p.client.Log.Warn("Unable to forget the webhook delivery", "DedupeKey", dedupeKey, "error", err.Error())
+	}
+}
+
+// recordWebhookDelivery keeps track of the last webhook request, so that admins can tell whether Confluence still
+// reaches Mattermost.
+func (p *Plugin) recordWebhookDelivery() {
+	if err := store.StoreLastWebhookDelivery(time.Now()); err != nil {
+		p.client.Log.Warn("Unable to record the webhook delivery time", "error", err.Error())
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func TestIsDuplicateWebhook(t *testing.T) {
+	for name, tc := range map[string]struct {
+		stored      bool
+		setErr      *model.AppError
+		isDuplicate bool
+	}{
+		"first delivery": {
+			stored: true,
+		},
+		"repeated delivery": {
+			isDuplicate: true,
+		},
+		"delivery cannot be checked": {
+			setErr: &model.AppError{Message: "KV store unavailable"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+
+			mockAPI.On("KVSetWithOptions", mock.AnythingOfType("string"), []byte("page_updated/12/3/1000"), model.PluginKVSetOptions{
+				Atomic:          true,
+				ExpireInSeconds: int64(webhookDedupeTTL.Seconds()),
+			}).Return(tc.stored, tc.setErr)
+			mockAPI.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+			mockAPI.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			assert.Equal(t, tc.isDuplicate, p.isDuplicateWebhook("page_updated/12/3/1000"))
+			mockAPI.AssertExpectations(t)
+		})
+	}
+}
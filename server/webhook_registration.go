@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	serverWebhookName       = "Mattermost Webhook"
+	webhookOnlySystemAdmin  = "`/confluence webhook` can only be run by a system administrator."
+	webhookRequiresServerV9 = "Webhooks can only be registered automatically on Confluence Data Center 9 or later."
+	webhookNoInstanceText   = "No Confluence Data Center instance is configured. Run `/confluence install server` first."
+
+	// Statuses of the Mattermost webhook on a Confluence Data Center instance
+	webhookStatusRegistered = "registered"
+	webhookStatusStale      = "stale"
+	webhookStatusMissing    = "missing"
+
+	// Actions taken when registering the Mattermost webhook
+	webhookActionCreated = "created"
+	webhookActionUpdated = "updated"
+)
+
+// serverWebhookAPI is the part of the Confluence Data Center REST API used to register the Mattermost webhook.
+type serverWebhookAPI interface {
+	ListWebhooks() ([]ServerWebhook, error)
+	CreateWebhook(webhook *ServerWebhook) (*ServerWebhook, error)
+	UpdateWebhook(webhook *ServerWebhook) (*ServerWebhook, error)
+	TestWebhook(webhookURL string) (*ServerWebhookTestResult, error)
+}
+
+// webhookRegistration describes the Mattermost webhook registered on a Confluence Data Center instance.
+type webhookRegistration struct {
+	status   string
+	webhook  *ServerWebhook
+	problems []string
+}
+
+func expectedServerWebhookURL() string {
+	return util.GetPluginURL() + util.GetConfluenceServerWebhookURLPath()
+}
+
+// findServerWebhook looks for the Mattermost webhook among the webhooks of the instance. A webhook pointing at the
+// plugin with an outdated URL, for instance after the site URL or the webhook secret changed, is reported as stale.
+func findServerWebhook(webhooks []ServerWebhook, expectedURL string) webhookRegistration {
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if webhook.URL != expectedURL {
+			continue
+		}
+
+		registration := webhookRegistration{status: webhookStatusRegistered, webhook: webhook}
+		if !webhook.Active {
+			registration.problems = append(registration.problems, "the webhook is disabled")
+		}
+		if missing := missingWebhookEvents(webhook.Events); len(missing) > 0 {
+			registration.problems = append(registration.problems, "the webhook does not send these events: "+strings.Join(missing, ", "))
+		}
+		if len(registration.problems) > 0 {
+			registration.status = webhookStatusStale
+		}
+		return registration
+	}
+
+	pluginWebhookPath := util.GetPluginURLPath() + confluenceServerWebhook.Path
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if webhook.Name == serverWebhookName || strings.Contains(webhook.URL, pluginWebhookPath) {
+			return webhookRegistration{
+				status:   webhookStatusStale,
+				webhook:  webhook,
+				problems: []string{"the webhook URL is outdated"},
+			}
+		}
+	}
+
+	return webhookRegistration{status: webhookStatusMissing}
+}
+
+func missingWebhookEvents(events []string) []string {
+	registered := map[string]bool{}
+	for _, event := range events {
+		registered[event] = true
+	}
+
+	var missing []string
+	for _, event := range serializer.SupportedEventsV9AndAbove {
+		if !registered[event] {
+			missing = append(missing, event)
+		}
+	}
+	return missing
+}
+
+// registerServerWebhook creates the Mattermost webhook on the instance, or repairs it, and verifies it with a test
+// delivery. It returns whether the webhook was created or updated.
+func registerServerWebhook(api serverWebhookAPI, instanceID string) (string, error) {
+	webhooks, err := api.ListWebhooks()
+	if err != nil {
+		return "", err
+	}
+
+	secret, generated, err := getOrGenerateWebhookSigningSecret(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	expectedURL := expectedServerWebhookURL()
+	webhook := &ServerWebhook{
+		Name:          serverWebhookName,
+		URL:           expectedURL,
+		Active:        true,
+		Events:        append(append([]string{}, serializer.SupportedEventsV9AndAbove...), serializer.CacheInvalidationEvents...),
+		Configuration: map[string]string{"secret": secret},
+	}
+
+	action := webhookActionCreated
+	if registration := findServerWebhook(webhooks, expectedURL); registration.status == webhookStatusMissing {
+		if _, err = api.CreateWebhook(webhook); err != nil {
+			return "", err
+		}
+	} else {
+		// The signing secret cannot be read back from Confluence, the webhook is always updated to make sure it matches.
+		action = webhookActionUpdated
+		webhook.ID = registration.webhook.ID
+		if _, err = api.UpdateWebhook(webhook); err != nil {
+			return "", err
+		}
+	}
+
+	// A generated secret is only saved once Confluence signs the webhooks with it, the test delivery needs it.
+	if generated {
+		if err = store.StoreWebhookSigningSecret(instanceID, secret); err != nil {
+			return "", errors.Wrapf(err, "the webhook was %s but its signing secret could not be saved", action)
+		}
+	}
+
+	result, err := api.TestWebhook(expectedURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "the webhook was %s but the test delivery failed", action)
+	}
+	if result.StatusCode < http.StatusOK || result.StatusCode >= http.StatusMultipleChoices {
+		return "", errors.Errorf("the webhook was %s but Mattermost answered the test delivery with status %d", action, result.StatusCode)
+	}
+
+	return action, nil
+}
+
+// getOrGenerateWebhookSigningSecret returns the signing secret of the instance, or generates a new one without saving
+// it when none is set.
+func getOrGenerateWebhookSigningSecret(instanceID string) (secret string, generated bool, err error) {
+	secret, err = store.LoadWebhookSigningSecret(instanceID)
+	if err != nil || secret != "" {
+		return secret, false, err
+	}
+
+	if secret, err = generateRandomKey(webhookSigningSecretLength); err != nil {
+		return "", false, err
+	}
+	return secret, true, nil
+}
+
+// getAdminServerClient returns a client using the connection of the admin who set up the instance.
+func (p *Plugin) getAdminServerClient(instanceID string) (*confluenceServerClient, error) {
+	connection, err := store.LoadConnection(instanceID, AdminMattermostUserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "no admin is connected to Confluence")
+	}
+
+	client, err := p.GetServerClient(instanceID, connection)
+	if err != nil {
+		return nil, err
+	}
+	return client.(*confluenceServerClient), nil
+}
+
+func (p *Plugin) registerServerWebhookForInstance(instanceID string) (string, error) {
+	if !config.GetConfig().ServerVersionGreaterthan9 {
+		return "", errors.New(webhookRequiresServerV9)
+	}
+
+	client, err := p.getAdminServerClient(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	return registerServerWebhook(client, instanceID)
+}
+
+func executeWebhook(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, webhookOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	instanceID := config.GetConfig().GetConfluenceBaseURL()
+	if instanceID == "" {
+		postCommandResponse(context, webhookNoInstanceText)
+		return &model.CommandResponse{}
+	}
+
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "", "status":
+		if !config.GetConfig().ServerVersionGreaterthan9 {
+			postCommandResponse(context, webhookRequiresServerV9)
+			return &model.CommandResponse{}
+		}
+
+		client, err := p.getAdminServerClient(instanceID)
+		if err != nil {
+			postCommandResponse(context, fmt.Sprintf("Unable to check the webhook: %s.", err.Error()))
+			return &model.CommandResponse{}
+		}
+		webhooks, err := client.ListWebhooks()
+		if err != nil {
+			p.client.Log.Error("Error listing the Confluence webhooks", "InstanceID", instanceID, "error", err.Error())
+			postCommandResponse(context, fmt.Sprintf("Unable to list the webhooks of %s: %s.", instanceID, err.Error()))
+			return &model.CommandResponse{}
+		}
+
+		postCommandResponse(context, formatWebhookRegistration(findServerWebhook(webhooks, expectedServerWebhookURL()), instanceID))
+	case "repair":
+		registered, err := p.registerServerWebhookForInstance(instanceID)
+		if err != nil {
+			p.client.Log.Error("Error registering the Confluence webhook", "InstanceID", instanceID, "error", err.Error())
+			postCommandResponse(context, fmt.Sprintf("Unable to register the webhook on %s: %s.", instanceID, err.Error()))
+			return &model.CommandResponse{}
+		}
+		postCommandResponse(context, fmt.Sprintf("The webhook has been %s on %s, and a test delivery succeeded.", registered, instanceID))
+	default:
+		postCommandResponse(context, fmt.Sprintf("Unexpected argument %q.", action))
+	}
+
+	return &model.CommandResponse{}
+}
+
+func formatWebhookRegistration(registration webhookRegistration, instanceID string) string {
+	switch registration.status {
+	case webhookStatusRegistered:
+		return fmt.Sprintf("The webhook is registered on %s.", instanceID)
+	case webhookStatusStale:
+		return fmt.Sprintf("The webhook on %s needs to be repaired: %s. Run `/confluence webhook repair` to fix it.", instanceID, strings.Join(registration.problems, ", "))
+	default:
+		return fmt.Sprintf("No webhook sends the Confluence events of %s to Mattermost. Run `/confluence webhook repair` to create it.", instanceID)
+	}
+}
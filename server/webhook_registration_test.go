@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/serializer"
+)
+
+const testWebhookURL = "https://mattermost.example.com/plugins/com.mattermost.confluence/api/v1/server/webhook?secret=abcd"
+
+type fakeServerWebhookAPI struct {
+	webhooks   []ServerWebhook
+	created    *ServerWebhook
+	updated    *ServerWebhook
+	testStatus int
+	testErr    error
+	createErr  error
+}
+
+func (f *fakeServerWebhookAPI) ListWebhooks() ([]ServerWebhook, error) {
+	return f.webhooks, nil
+}
+
+func (f *fakeServerWebhookAPI) CreateWebhook(webhook *ServerWebhook) (*ServerWebhook, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	f.created = webhook
+	return webhook, nil
+}
+
+func (f *fakeServerWebhookAPI) UpdateWebhook(webhook *ServerWebhook) (*ServerWebhook, error) {
+	f.updated = webhook
+	return webhook, nil
+}
+
+func (f *fakeServerWebhookAPI) TestWebhook(string) (*ServerWebhookTestResult, error) {
+	if f.testErr != nil {
+		return nil, f.testErr
+	}
+	return &ServerWebhookTestResult{StatusCode: f.testStatus}, nil
+}
+
+func setupWebhookRegistrationTest(t *testing.T) {
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	config.SetConfig(&config.Configuration{Secret: "abcd", ConfluenceURL: "https://confluence.example.com", ServerVersionGreaterthan9: true})
+
+	siteURL := "https://mattermost.example.com"
+	mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+
+	signingSecret, _ := json.Marshal("signing")
+	mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(signingSecret, nil)
+	require.Equal(t, testWebhookURL, expectedServerWebhookURL())
+}
+
+func TestFindServerWebhook(t *testing.T) {
+	setupWebhookRegistrationTest(t)
+
+	for name, tc := range map[string]struct {
+		webhooks         []ServerWebhook
+		expectedStatus   string
+		expectedProblems int
+	}{
+		"registered": {
+			webhooks: []ServerWebhook{
+				{ID: 1, Name: "Other", URL: "https://example.com/hook", Active: true},
+				{ID: 2, Name: serverWebhookName, URL: testWebhookURL, Active: true, Events: serializer.SupportedEventsV9AndAbove},
+			},
+			expectedStatus: webhookStatusRegistered,
+		},
+		"disabled and missing events": {
+			webhooks: []ServerWebhook{
+				{ID: 2, Name: serverWebhookName, URL: testWebhookURL, Events: []string{serializer.PageCreatedEvent}},
+			},
+			expectedStatus:   webhookStatusStale,
+			expectedProblems: 2,
+		},
+		"stale URL": {
+			webhooks: []ServerWebhook{
+				{ID: 3, Name: "Confluence to Mattermost", URL: "https://old.example.com/plugins/com.mattermost.confluence/api/v1/server/webhook?secret=old", Active: true},
+			},
+			expectedStatus:   webhookStatusStale,
+			expectedProblems: 1,
+		},
+		"missing": {
+			webhooks:       []ServerWebhook{{ID: 1, Name: "Other", URL: "https://example.com/hook", Active: true}},
+			expectedStatus: webhookStatusMissing,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			registration := findServerWebhook(tc.webhooks, testWebhookURL)
+			assert.Equal(t, tc.expectedStatus, registration.status)
+			assert.Len(t, registration.problems, tc.expectedProblems)
+		})
+	}
+}
+
+func TestRegisterServerWebhook(t *testing.T) {
+	setupWebhookRegistrationTest(t)
+
+	for name, tc := range map[string]struct {
+		api            *fakeServerWebhookAPI
+		expectedAction string
+		expectCreate   bool
+		expectUpdateID int64
+		expectError    bool
+	}{
+		"creates the missing webhook": {
+			api:            &fakeServerWebhookAPI{testStatus: http.StatusOK},
+			expectedAction: webhookActionCreated,
+			expectCreate:   true,
+		},
+		"repairs the stale webhook": {
+			api: &fakeServerWebhookAPI{
+				webhooks:   []ServerWebhook{{ID: 7, Name: serverWebhookName, URL: "https://old.example.com/hook"}},
+				testStatus: http.StatusOK,
+			},
+			expectedAction: webhookActionUpdated,
+			expectUpdateID: 7,
+		},
+		"test delivery rejected": {
+			api:          &fakeServerWebhookAPI{testStatus: http.StatusForbidden},
+			expectCreate: true,
+			expectError:  true,
+		},
+		"test delivery failed": {
+			api:          &fakeServerWebhookAPI{testErr: errors.New("connection refused")},
+			expectCreate: true,
+			expectError:  true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			action, err := registerServerWebhook(tc.api, "https://confluence.example.com")
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedAction, action)
+			}
+
+			if tc.expectCreate {
+				require.NotNil(t, tc.api.created)
+				assert.Equal(t, testWebhookURL, tc.api.created.URL)
+				assert.Equal(t, "signing", tc.api.created.Configuration["secret"])
+				assert.True(t, tc.api.created.Active)
+			} else {
+				assert.Nil(t, tc.api.created)
+			}
+
+			if tc.expectUpdateID != 0 {
+				require.NotNil(t, tc.api.updated)
+				assert.Equal(t, tc.expectUpdateID, tc.api.updated.ID)
+				assert.Equal(t, testWebhookURL, tc.api.updated.URL)
+			}
+		})
+	}
+}
+
+func TestRegisterServerWebhookSigningSecret(t *testing.T) {
+	for name, tc := range map[string]struct {
+		api         *fakeServerWebhookAPI
+		expectSaved bool
+	}{
+		"saves the generated secret once the webhook is created": {
+			api:         &fakeServerWebhookAPI{testStatus: http.StatusOK},
+			expectSaved: true,
+		},
+		"does not save the generated secret when the webhook cannot be created": {
+			api:         &fakeServerWebhookAPI{createErr: errors.New("forbidden")},
+			expectSaved: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			config.SetConfig(&config.Configuration{Secret: "abcd", ConfluenceURL: "https://confluence.example.com", ServerVersionGreaterthan9: true})
+			siteURL := "https://mattermost.example.com"
+			mockAPI.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+			if tc.expectSaved {
+				mockAPI.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+			}
+
+			_, err := registerServerWebhook(tc.api, "https://confluence.example.com")
+			if tc.expectSaved {
+				require.NoError(t, err)
+				require.NotNil(t, tc.api.created)
+				secret, _ := json.Marshal(tc.api.created.Configuration["secret"])
+				mockAPI.AssertCalled(t, "KVSet", mock.AnythingOfType("string"), secret)
+			} else {
+				assert.Error(t, err)
+				mockAPI.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
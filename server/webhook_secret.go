@@ -0,0 +1,263 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+const webhookSecretOnlySystemAdmin = "`/confluence webhook-secret` can only be run by a system administrator."
+
+// previousSecretUseInterval is the shortest interval between two records of the requests made by a webhook with the
+// previous secret.
+const previousSecretUseInterval = time.Minute
+
+// previousSecretUses throttles the records of the requests made with the previous secret.
+var previousSecretUses = newPreviousSecretUseThrottle()
+
+// recordWebhookSecret keeps a hash of the replaced secret as the previous secret when the webhook secret is regenerated.
+func recordWebhookSecret(secret string, now time.Time) error {
+	if secret == "" {
+		return nil
+	}
+
+	secretHash := util.GetKeyHash(secret)
+	var retiredUsageKeys []string
+	err := store.ModifyWebhookSecretState(func(state *types.WebhookSecretState) error {
+		retiredUsageKeys = nil
+		if state.CurrentHash == secretHash {
+			return nil
+		}
+		if state.CurrentHash != "" {
+			state.PreviousHash = state.CurrentHash
+			state.RotatedAt = now.UnixMilli()
+			retiredUsageKeys = state.UsageKeys
+			state.UsageKeys = nil
+		}
+		state.CurrentHash = secretHash
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return store.DeletePreviousSecretUses(retiredUsageKeys)
+}
+
+// previousSecretAccepted checks if the previous secret is still within its grace period.
+func previousSecretAccepted(state *types.WebhookSecretState, gracePeriod time.Duration, now time.Time) bool {
+	return state.PreviousHash != "" && gracePeriod > 0 && now.Before(time.UnixMilli(state.RotatedAt).Add(gracePeriod))
+}
+
+// verifySecretHash is verifyHTTPSecret for a secret of which only the hash is kept.
+func verifySecretHash(expectedHash, got string) error {
+	for subtle.ConstantTimeCompare([]byte(util.GetKeyHash(got)), []byte(expectedHash)) != 1 {
+		unescaped, _ := url.QueryUnescape(got)
+		if unescaped == got {
+			return errors.New("request URL: secret did not match")
+		}
+		got = unescaped
+	}
+	return nil
+}
+
+// verifyWebhookSecret verifies the secret of a request coming from Confluence. The previous secret is accepted during
+// the grace period following its rotation, and its use is recorded so that admins can find the webhooks to update.
+func (p *Plugin) verifyWebhookSecret(r *http.Request) (int, error) {
+	pluginConfig := config.GetConfig()
+	got := r.FormValue("secret")
+
+	status, err := verifyHTTPSecret(pluginConfig.Secret, got)
+	if err == nil {
+		return status, nil
+	}
+
+	state, sErr := store.LoadWebhookSecretState()
+	if sErr != nil {
+		p.client.Log.Warn("Unable to load the previous webhook secret", "error", sErr.Error())
+		return status, err
+	}
+	if !previousSecretAccepted(state, pluginConfig.GetWebhookSecretGracePeriod(), time.Now()) {
+		return status, err
+	}
+	if pErr := verifySecretHash(state.PreviousHash, got); pErr != nil {
+		return status, err
+	}
+
+	remoteAddr := requestRemoteAddr(r, config.Mattermost.GetConfig().ServiceSettings.TrustedProxyIPHeader)
+	p.client.Log.Warn("Request authenticated with the previous webhook secret, update the webhook to use the current secret", "Endpoint", r.URL.Path, "RemoteAddr", remoteAddr)
+	if uErr := previousSecretUses.record(state.RotatedAt, r.URL.Path, remoteAddr, time.Now()); uErr != nil {
+		p.client.Log.Warn("Unable to record the use of the previous webhook secret", "error", uErr.Error())
+	}
+
+	return 0, nil
+}
+
+// previousSecretUseThrottle records the requests made with the previous secret at most once per
+// previousSecretUseInterval for each endpoint and remote address. The requests made in between are counted in the
+// next record.
+type previousSecretUseThrottle struct {
+	mu        sync.Mutex
+	rotatedAt int64
+	uses      map[string]*throttledSecretUse
+}
+
+type throttledSecretUse struct {
+	pending    int
+	recordedAt time.Time
+	indexed    bool
+}
+
+func newPreviousSecretUseThrottle() *previousSecretUseThrottle {
+	return &previousSecretUseThrottle{uses: map[string]*throttledSecretUse{}}
+}
+
+func (t *previousSecretUseThrottle) record(rotatedAt int64, endpoint, remoteAddr string, now time.Time) error {
+	key := fmt.Sprintf("%d %s %s", rotatedAt, endpoint, remoteAddr)
+
+	t.mu.Lock()
+	if t.rotatedAt != rotatedAt {
+		t.rotatedAt = rotatedAt
+		t.uses = map[string]*throttledSecretUse{}
+	}
+	use, ok := t.uses[key]
+	if !ok {
+		use = &throttledSecretUse{}
+		t.uses[key] = use
+	}
+	use.pending++
+	if ok && now.Sub(use.recordedAt) < previousSecretUseInterval {
+		t.mu.Unlock()
+		return nil
+	}
+	count, indexed := use.pending, use.indexed
+	use.pending = 0
+	use.recordedAt = now
+	use.indexed = true
+	t.mu.Unlock()
+
+	if !indexed {
+		err := store.ModifyWebhookSecretState(func(state *types.WebhookSecretState) error {
+			if state.RotatedAt == rotatedAt && !slices.Contains(state.UsageKeys, key) {
+				state.UsageKeys = append(state.UsageKeys, key)
+			}
+			return nil
+		})
+		if err != nil {
+			t.mu.Lock()
+			use.indexed = false
+			t.mu.Unlock()
+			return err
+		}
+	}
+
+	return store.AddPreviousSecretUse(key, endpoint, remoteAddr, count, now.UnixMilli())
+}
+
+// requestRemoteAddr returns the address the request comes from. Like the Mattermost server, the proxy headers are
+// only honored when they are listed in the TrustedProxyIPHeader setting, as any client can set them.
+func requestRemoteAddr(r *http.Request, trustedProxyHeaders []string) string {
+	for _, header := range trustedProxyHeaders {
+		if forwarded := r.Header.Get(header); forwarded != "" {
+			if addr := strings.TrimSpace(strings.Split(forwarded, ",")[0]); addr != "" {
+				return addr
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func executeWebhookSecret(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, webhookSecretOnlySystemAdmin)
+		return &model.CommandResponse{}
+	}
+
+	if len(args) > 0 {
+		if args[0] != "revoke-previous" {
+			postCommandResponse(context, fmt.Sprintf("Unexpected argument %q.", args[0]))
+			return &model.CommandResponse{}
+		}
+
+		var usageKeys []string
+		err := store.ModifyWebhookSecretState(func(state *types.WebhookSecretState) error {
+			usageKeys = state.UsageKeys
+			state.PreviousHash = ""
+			state.UsageKeys = nil
+			return nil
+		})
+		if err == nil {
+			err = store.DeletePreviousSecretUses(usageKeys)
+		}
+		if err != nil {
+			p.client.Log.Error("Error revoking the previous webhook secret", "error", err.Error())
+			postCommandResponse(context, errorExecutingCommand)
+			return &model.CommandResponse{}
+		}
+
+		postCommandResponse(context, "The previous webhook secret has been revoked, only the current secret is accepted.")
+		return &model.CommandResponse{}
+	}
+
+	state, err := store.LoadWebhookSecretState()
+	if err != nil {
+		p.client.Log.Error("Error loading the webhook secret state", "error", err.Error())
+		postCommandResponse(context, errorExecutingCommand)
+		return &model.CommandResponse{}
+	}
+
+	var uses []*types.PreviousSecretUse
+	for _, key := range state.UsageKeys {
+		use, uErr := store.LoadPreviousSecretUse(key)
+		if uErr != nil {
+			p.client.Log.Warn("Error loading the use of the previous webhook secret", "error", uErr.Error())
+			continue
+		}
+		uses = append(uses, use)
+	}
+
+	postCommandResponse(context, formatWebhookSecretState(state, uses, config.GetConfig().GetWebhookSecretGracePeriod(), time.Now()))
+	return &model.CommandResponse{}
+}
+
+func formatWebhookSecretState(state *types.WebhookSecretState, uses []*types.PreviousSecretUse, gracePeriod time.Duration, now time.Time) string {
+	if !previousSecretAccepted(state, gracePeriod, now) {
+		return "Only the current webhook secret is accepted."
+	}
+
+	graceEnd := time.UnixMilli(state.RotatedAt).Add(gracePeriod)
+	out := fmt.Sprintf("The webhook secret was regenerated on %s. The previous secret is accepted until %s.\n",
+		time.UnixMilli(state.RotatedAt).UTC().Format(time.RFC1123), graceEnd.UTC().Format(time.RFC1123))
+
+	if len(uses) == 0 {
+		return out + "No request has used the previous secret since."
+	}
+
+	sort.Slice(uses, func(i, j int) bool {
+		return uses[i].LastUsedAt > uses[j].LastUsedAt
+	})
+
+	out += "\nThe following webhooks still use the previous secret:\n\n| Endpoint | Remote Address | Requests | Last Request |\n| :--- | :--- | :--- | :--- |\n"
+	for _, use := range uses {
+		out += fmt.Sprintf("| %s | %s | %d | %s |\n", use.Endpoint, use.RemoteAddr, use.Count, time.UnixMilli(use.LastUsedAt).UTC().Format(time.RFC1123))
+	}
+	return out
+}
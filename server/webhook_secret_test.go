@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util/types"
+)
+
+func TestVerifyWebhookSecret(t *testing.T) {
+	now := time.Now()
+	for name, tc := range map[string]struct {
+		secret         string
+		state          *types.WebhookSecretState
+		expectedStatus int
+		expectError    bool
+		expectRecorded bool
+	}{
+		"current secret": {
+			secret:         "current",
+			state:          &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("previous"), RotatedAt: now.UnixMilli()},
+			expectedStatus: 0,
+		},
+		"previous secret within the grace period": {
+			secret:         "previous",
+			state:          &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("previous"), RotatedAt: now.Add(-time.Hour).UnixMilli()},
+			expectedStatus: 0,
+			expectRecorded: true,
+		},
+		"previous secret after the grace period": {
+			secret:         "previous",
+			state:          &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("previous"), RotatedAt: now.Add(-3 * time.Hour).UnixMilli()},
+			expectedStatus: http.StatusForbidden,
+			expectError:    true,
+		},
+		"revoked previous secret": {
+			secret:         "previous",
+			state:          &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current")},
+			expectedStatus: http.StatusForbidden,
+			expectError:    true,
+		},
+		"wrong secret": {
+			secret:         "wrong",
+			state:          &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("previous"), RotatedAt: now.UnixMilli()},
+			expectedStatus: http.StatusForbidden,
+			expectError:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+			config.SetConfig(&config.Configuration{Secret: "current", WebhookSecretGracePeriodHours: 2})
+
+			data, _ := json.Marshal(tc.state)
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(data, nil).Maybe()
+			mockAPI.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+			mockAPI.On("GetConfig").Return(&model.Config{}).Maybe()
+			var recorded *types.PreviousSecretUse
+			mockAPI.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				use := &types.PreviousSecretUse{}
+				if json.Unmarshal(args.Get(2).([]byte), use) == nil && use.Count > 0 {
+					recorded = use
+				}
+			}).Return(true, nil).Maybe()
+			previousSecretUses = newPreviousSecretUseThrottle()
+
+			r := httptest.NewRequest(http.MethodPost, "/server/webhook?secret="+tc.secret, nil)
+			r.RemoteAddr = "10.0.0.1:4321"
+			r.Header.Set("X-Forwarded-For", "203.0.113.7")
+			status, err := p.verifyWebhookSecret(r)
+
+			assert.Equal(t, tc.expectedStatus, status)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if !tc.expectRecorded {
+				assert.Nil(t, recorded)
+				return
+			}
+			if assert.NotNil(t, recorded) {
+				assert.Equal(t, "/server/webhook", recorded.Endpoint)
+				assert.Equal(t, "10.0.0.1", recorded.RemoteAddr)
+				assert.Equal(t, 1, recorded.Count)
+			}
+		})
+	}
+}
+
+func TestRequestRemoteAddr(t *testing.T) {
+	for name, tc := range map[string]struct {
+		forwardedFor        string
+		trustedProxyHeaders []string
+		expected            string
+	}{
+		"remote address": {
+			expected: "10.0.0.1",
+		},
+		"forwarded address ignored without a trusted proxy": {
+			forwardedFor: "203.0.113.7",
+			expected:     "10.0.0.1",
+		},
+		"forwarded address from a trusted proxy": {
+			forwardedFor:        "203.0.113.7, 10.0.0.2",
+			trustedProxyHeaders: []string{"X-Forwarded-For"},
+			expected:            "203.0.113.7",
+		},
+		"other trusted proxy header": {
+			forwardedFor:        "203.0.113.7",
+			trustedProxyHeaders: []string{"X-Real-IP"},
+			expected:            "10.0.0.1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/server/webhook", nil)
+			r.RemoteAddr = "10.0.0.1:4321"
+			if tc.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+
+			assert.Equal(t, tc.expected, requestRemoteAddr(r, tc.trustedProxyHeaders))
+		})
+	}
+}
+
+func TestRecordWebhookSecret(t *testing.T) {
+	now := time.Now()
+	for name, tc := range map[string]struct {
+		state           *types.WebhookSecretState
+		secret          string
+		expected        *types.WebhookSecretState
+		expectedDeleted int
+	}{
+		"first secret": {
+			secret:   "current",
+			expected: &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current")},
+		},
+		"regenerated secret": {
+			state:           &types.WebhookSecretState{CurrentHash: util.GetKeyHash("old"), UsageKeys: []string{"1 /server/webhook 10.0.0.1"}},
+			secret:          "current",
+			expected:        &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("old"), RotatedAt: now.UnixMilli()},
+			expectedDeleted: 1,
+		},
+		"unchanged secret": {
+			state:    &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("old"), RotatedAt: 1},
+			secret:   "current",
+			expected: &types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("old"), RotatedAt: 1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+
+			var data []byte
+			if tc.state != nil {
+				data, _ = json.Marshal(tc.state)
+			}
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(data, nil)
+			recorded := tc.state
+			mockAPI.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				recorded = &types.WebhookSecretState{}
+				_ = json.Unmarshal(args.Get(2).([]byte), recorded)
+			}).Return(true, nil).Maybe()
+			mockAPI.On("KVDelete", mock.AnythingOfType("string")).Return(nil).Maybe()
+
+			assert.NoError(t, recordWebhookSecret(tc.secret, now))
+			assert.Equal(t, tc.expected, recorded)
+			mockAPI.AssertNumberOfCalls(t, "KVDelete", tc.expectedDeleted)
+		})
+	}
+}
+
+func TestPreviousSecretUseThrottle(t *testing.T) {
+	now := time.Now()
+	mockAPI := &plugintest.API{}
+	config.Mattermost = mockAPI
+	state, _ := json.Marshal(&types.WebhookSecretState{CurrentHash: util.GetKeyHash("current"), PreviousHash: util.GetKeyHash("previous"), RotatedAt: 1})
+	mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(state, nil)
+	var counts []int
+	indexed := 0
+	mockAPI.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		var recordedState types.WebhookSecretState
+		if json.Unmarshal(args.Get(2).([]byte), &recordedState) == nil && len(recordedState.UsageKeys) > 0 {
+			indexed++
+			return
+		}
+		var use types.PreviousSecretUse
+		if json.Unmarshal(args.Get(2).([]byte), &use) == nil {
+			counts = append(counts, use.Count)
+		}
+	}).Return(true, nil)
+
+	throttle := newPreviousSecretUseThrottle()
+	for _, at := range []time.Duration{0, time.Second, 30 * time.Second, previousSecretUseInterval, previousSecretUseInterval + time.Second} {
+		assert.NoError(t, throttle.record(1, "/server/webhook", "10.0.0.1", now.Add(at)))
+	}
+
+	// The stored use is not updated by the mock, so each record holds the requests since the previous one.
+	assert.Equal(t, []int{1, 3}, counts)
+	assert.Equal(t, 1, indexed)
+}
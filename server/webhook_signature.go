@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+	"github.com/mattermost/mattermost-plugin-confluence/server/store"
+	"github.com/mattermost/mattermost-plugin-confluence/server/util"
+)
+
+const (
+	headerHubSignature           = "X-Hub-Signature"
+	hubSignaturePrefix           = "sha256="
+	webhookSigningSecretLength   = 32
+	webhookSigningOnlySysAdmin   = "`/confluence webhook-signing-secret` can only be run by a system administrator."
+	webhookSigningNoInstanceText = "No Confluence Data Center instance is configured. Run `/confluence install server` first, or specify the instance URL."
+)
+
+// verifyWebhookSignature checks the HMAC-SHA256 signature of the raw body, as sent by Confluence Data Center in the
+// X-Hub-Signature header.
+func verifyWebhookSignature(secret, signature string, body []byte) (int, error) {
+	if !strings.HasPrefix(signature, hubSignaturePrefix) {
+		return http.StatusForbidden, errors.New("unsupported webhook signature algorithm")
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, hubSignaturePrefix))
+	if err != nil {
+		return http.StatusForbidden, errors.Wrap(err, "malformed webhook signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return http.StatusForbidden, errors.New("webhook signature did not match")
+	}
+
+	return 0, nil
+}
+
+// verifyServerWebhookRequest authenticates a Confluence Data Center webhook. When a signing secret is configured for
+// the instance, only requests signed with it are accepted. The secret in the query string is only checked for the
+// instances without a signing secret.
+func (p *Plugin) verifyServerWebhookRequest(r *http.Request, body []byte, instanceID string) (int, error) {
+	secret, err := store.LoadWebhookSigningSecret(instanceID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "unable to load the webhook signing secret")
+	}
+	if secret == "" {
+		return p.verifyWebhookSecret(r)
+	}
+
+	signature := r.Header.Get(headerHubSignature)
+	if signature == "" {
+		return http.StatusUnauthorized, errors.New("the webhook is not signed")
+	}
+
+	return verifyWebhookSignature(secret, signature, body)
+}
+
+func executeWebhookSigningSecret(p *Plugin, context *model.CommandArgs, args ...string) *model.CommandResponse {
+	if !util.IsSystemAdmin(context.UserId) {
+		postCommandResponse(context, webhookSigningOnlySysAdmin)
+		return &model.CommandResponse{}
+	}
+
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+		args = args[1:]
+	}
+
+	instanceID := config.GetConfig().GetConfluenceBaseURL()
+	if len(args) > 0 {
+		instanceID = strings.TrimRight(args[0], "/")
+	}
+	if instanceID == "" {
+		postCommandResponse(context, webhookSigningNoInstanceText)
+		return &model.CommandResponse{}
+	}
+
+	switch action {
+	case "", "status":
+		secret, err := store.LoadWebhookSigningSecret(instanceID)
+		if err != nil {
+			p.client.Log.Error("Error loading the webhook signing secret", "InstanceID", instanceID, "error", err.Error())
+			postCommandResponse(context, errorExecutingCommand)
+			return &model.CommandResponse{}
+		}
+		if secret == "" {
+			postCommandResponse(context, fmt.Sprintf("Webhook signatures are not verified for %s. Run `/confluence webhook-signing-secret generate` to set a signing secret.", instanceID))
+			return &model.CommandResponse{}
+		}
+		postCommandResponse(context, fmt.Sprintf("Webhooks from %s must be signed with the signing secret. Unsigned webhooks are rejected.", instanceID))
+	case "generate":
+		secret, err := generateRandomKey(webhookSigningSecretLength)
+		if err != nil {
+			p.client.Log.Error("Error generating the webhook signing secret", "error", err.Error())
+			postCommandResponse(context, errorExecutingCommand)
+			return &model.CommandResponse{}
+		}
+		if err = store.StoreWebhookSigningSecret(instanceID, secret); err != nil {
+			p.client.Log.Error("Error storing the webhook signing secret", "InstanceID", instanceID, "error", err.Error())
+			postCommandResponse(context, errorExecutingCommand)
+			return &model.CommandResponse{}
+		}
+		postCommandResponse(context, fmt.Sprintf("The webhook signing secret for %s is `%s`.\nSet it as the **Secret** of the Mattermost webhook in [**Settings > Webhooks**](%s/plugins/servlet/webhooks/). Webhooks that are not signed with this secret are rejected.", instanceID, secret, instanceID))
+	case "clear":
+		if err := store.DeleteWebhookSigningSecret(instanceID); err != nil {
+			p.client.Log.Error("Error deleting the webhook signing secret", "InstanceID", instanceID, "error", err.Error())
+			postCommandResponse(context, errorExecutingCommand)
+			return &model.CommandResponse{}
+		}
+		postCommandResponse(context, fmt.Sprintf("The webhook signing secret for %s has been removed. Webhooks are authenticated with the secret in the webhook URL.", instanceID))
+	default:
+		postCommandResponse(context, fmt.Sprintf("Unexpected argument %q.", action))
+	}
+
+	return &model.CommandResponse{}
+}
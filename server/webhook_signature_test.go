@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-confluence/server/config"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return hubSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyServerWebhookRequest(t *testing.T) {
+	body := []byte(`{"event":"page_created"}`)
+	for name, tc := range map[string]struct {
+		signingSecret  string
+		signature      string
+		querySecret    string
+		expectedStatus int
+		expectError    bool
+	}{
+		"valid signature": {
+			signingSecret: "signing",
+			signature:     sign("signing", body),
+		},
+		"valid signature without the query secret": {
+			signingSecret: "signing",
+			signature:     sign("signing", body),
+			querySecret:   "wrong",
+		},
+		"signature with another secret": {
+			signingSecret:  "signing",
+			signature:      sign("other", body),
+			querySecret:    "current",
+			expectedStatus: http.StatusForbidden,
+			expectError:    true,
+		},
+		"malformed signature": {
+			signingSecret:  "signing",
+			signature:      "sha256=xyz",
+			expectedStatus: http.StatusForbidden,
+			expectError:    true,
+		},
+		"unsupported algorithm": {
+			signingSecret:  "signing",
+			signature:      "sha1=abcd",
+			expectedStatus: http.StatusForbidden,
+			expectError:    true,
+		},
+		"unsigned request with a signing secret": {
+			signingSecret:  "signing",
+			querySecret:    "current",
+			expectedStatus: http.StatusUnauthorized,
+			expectError:    true,
+		},
+		"unsigned request without signing secret uses the query secret": {
+			querySecret: "current",
+		},
+		"signed request without signing secret falls back to the query secret": {
+			signature:   sign("signing", body),
+			querySecret: "current",
+		},
+		"unsigned request with a wrong query secret": {
+			querySecret:    "wrong",
+			expectedStatus: http.StatusForbidden,
+			expectError:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockAPI := &plugintest.API{}
+			config.Mattermost = mockAPI
+			p := &Plugin{}
+			p.SetAPI(mockAPI)
+			p.client = pluginapi.NewClient(mockAPI, nil)
+			config.SetConfig(&config.Configuration{Secret: "current", ConfluenceURL: "https://confluence.example.com"})
+
+			var signingSecret []byte
+			if tc.signingSecret != "" {
+				signingSecret, _ = json.Marshal(tc.signingSecret)
+			}
+			mockAPI.On("KVGet", "https://confluence.example.com_webhook_signing_secret").Return(signingSecret, nil).Maybe()
+			mockAPI.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil).Maybe()
+
+			r := httptest.NewRequest(http.MethodPost, "/server/webhook?secret="+tc.querySecret, strings.NewReader(string(body)))
+			if tc.signature != "" {
+				r.Header.Set(headerHubSignature, tc.signature)
+			}
+
+			status, err := p.verifyServerWebhookRequest(r, body, "https://confluence.example.com")
+			assert.Equal(t, tc.expectedStatus, status)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
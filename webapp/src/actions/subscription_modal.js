@@ -41,13 +41,13 @@ export const editChannelSubscription = (body) => {
     };
 };
 
-export function getSubscriptionAccess() {
+export function getSubscriptionAccess(channelID) {
     return async () => {
         let data = null;
         let error = null;
 
         try {
-            data = await Client.getSubscriptionAccess();
+            data = await Client.getSubscriptionAccess(channelID);
         } catch (e) {
             error = e;
         }
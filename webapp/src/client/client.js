@@ -32,8 +32,8 @@ export default class Client {
         return this.doGet(url);
     };
 
-    getSubscriptionAccess = () => {
-        const url = `${this.pluginApiUrl}/user-connection-info`;
+    getSubscriptionAccess = (channelID) => {
+        const url = `${this.pluginApiUrl}/user-connection-info?channel_id=${channelID}`;
         return this.doGet(url);
     };
 
@@ -18,6 +18,12 @@ const initialState = {
     subscriptionType: Constants.SUBSCRIPTION_TYPE[0],
     events: Constants.CONFLUENCE_EVENTS,
     supportedEvents: Constants.CONFLUENCE_EVENTS,
+    minorEdits: Constants.MINOR_EDIT_OPTIONS[0],
+    restrictedContent: Constants.RESTRICTED_CONTENT_OPTIONS[0],
+    quietHoursStart: '',
+    quietHoursEnd: '',
+    quietHoursTimeZone: '',
+    quietHoursAction: Constants.QUIET_HOURS_ACTIONS[0],
     error: '',
     saving: false,
 };
@@ -69,21 +75,36 @@ export default class SubscriptionModal extends React.PureComponent {
 
     setData = () => {
         const {
-            alias, baseURL, spaceKey, events, pageID,
+            alias, baseURL, spaceKey, events, pageID, skipMinorEdits, restrictedContent, quietHours,
         } = this.props.subscription;
         if (alias) {
-            const availableEvents = this.state.supportedEvents.filter((option) => events.includes(option.value));
+            const subscriptionType = pageID ? Constants.SUBSCRIPTION_TYPE[1] : Constants.SUBSCRIPTION_TYPE[0];
+            const availableEvents = this.getEventOptions(subscriptionType).filter((option) => events.includes(option.value));
             this.setState({
                 alias,
                 baseURL,
                 spaceKey,
                 pageID,
                 events: availableEvents,
-                subscriptionType: pageID ? Constants.SUBSCRIPTION_TYPE[1] : Constants.SUBSCRIPTION_TYPE[0],
+                subscriptionType,
+                minorEdits: Constants.MINOR_EDIT_OPTIONS.find((option) => option.value === Boolean(skipMinorEdits)),
+                restrictedContent: Constants.RESTRICTED_CONTENT_OPTIONS.find((option) => option.value === restrictedContent) || Constants.RESTRICTED_CONTENT_OPTIONS[0],
+                quietHoursStart: quietHours?.start || '',
+                quietHoursEnd: quietHours?.end || '',
+                quietHoursTimeZone: quietHours?.timeZone || '',
+                quietHoursAction: Constants.QUIET_HOURS_ACTIONS.find((option) => option.value === quietHours?.action) || Constants.QUIET_HOURS_ACTIONS[0],
             });
         }
     };
 
+    // Only space subscriptions can subscribe to the events about the space itself.
+    getEventOptions = (subscriptionType) => {
+        if (subscriptionType.value === Constants.SUBSCRIPTION_TYPE[0].value) {
+            return this.state.supportedEvents;
+        }
+        return this.state.supportedEvents.filter((option) => !Constants.SPACE_EVENTS.includes(option.value));
+    };
+
     handleClose = (e) => {
         if (e && e.preventDefault) {
             e.preventDefault();
@@ -128,14 +149,52 @@ export default class SubscriptionModal extends React.PureComponent {
         });
     };
 
+    handleMinorEdits = (minorEdits) => {
+        this.setState({
+            minorEdits,
+        });
+    };
+
+    handleRestrictedContent = (restrictedContent) => {
+        this.setState({
+            restrictedContent,
+        });
+    };
+
+    handleQuietHoursStart = (e) => {
+        this.setState({
+            quietHoursStart: e.target.value,
+        });
+    };
+
+    handleQuietHoursEnd = (e) => {
+        this.setState({
+            quietHoursEnd: e.target.value,
+        });
+    };
+
+    handleQuietHoursTimeZone = (e) => {
+        this.setState({
+            quietHoursTimeZone: e.target.value,
+        });
+    };
+
+    handleQuietHoursAction = (quietHoursAction) => {
+        this.setState({
+            quietHoursAction,
+        });
+    };
+
     handleSubscriptionType = (subscriptionType) => {
         if (subscriptionType === this.state.subscriptionType) {
             return;
         }
+        const eventOptions = this.getEventOptions(subscriptionType);
         this.setState({
             subscriptionType,
             pageID: '',
             spaceKey: '',
+            events: this.state.events.filter((event) => eventOptions.some((option) => option.value === event.value)),
         });
     };
 
@@ -145,6 +204,7 @@ export default class SubscriptionModal extends React.PureComponent {
         }
         const {
             alias, baseURL, spaceKey, events, pageID, subscriptionType,
+            minorEdits, restrictedContent, quietHoursStart, quietHoursEnd, quietHoursTimeZone, quietHoursAction,
         } = this.state;
         const {
             currentChannelID, subscription, saveChannelSubscription, editChannelSubscription,
@@ -157,7 +217,19 @@ export default class SubscriptionModal extends React.PureComponent {
             pageID: pageID ? pageID.trim() : '',
             channelID: currentChannelID,
             events: events ? events.map((event) => event.value) : [],
+            skipMinorEdits: minorEdits.value,
         };
+        if (subscriptionType.value === Constants.SUBSCRIPTION_TYPE[0].value && restrictedContent.value) {
+            channelSubscription.restrictedContent = restrictedContent.value;
+        }
+        if (quietHoursStart && quietHoursEnd) {
+            channelSubscription.quietHours = {
+                start: quietHoursStart,
+                end: quietHoursEnd,
+                timeZone: quietHoursTimeZone.trim() || Intl.DateTimeFormat().resolvedOptions().timeZone,
+                action: quietHoursAction.value,
+            };
+        }
         this.setState({
             saving: true,
             error: '',
@@ -184,7 +256,7 @@ export default class SubscriptionModal extends React.PureComponent {
         const {visibility, subscription} = this.props;
         const editSubscription = Boolean(subscription && subscription.alias);
         const isModalVisible = Boolean(visibility || editSubscription);
-        const {error, saving, subscriptionType, supportedEvents, events} = this.state;
+        const {error, saving, subscriptionType, events} = this.state;
         let typeField = (
             <ConfluenceField
                 formGroupStyle={getStyle.typeValue}
@@ -298,13 +370,99 @@ export default class SubscriptionModal extends React.PureComponent {
                             fieldType={'dropDown'}
                             required={true}
                             theme={this.props.theme}
-                            options={supportedEvents}
+                            options={this.getEventOptions(subscriptionType)}
                             value={events}
                             addValidation={this.validator.addValidation}
                             removeValidation={this.validator.removeValidation}
                             onChange={this.handleEvents}
                             testId='subscription-events-select'
                         />
+                        <ConfluenceField
+                            isSearchable={false}
+                            isMulti={false}
+                            label={'Minor Edits'}
+                            name={'minorEdits'}
+                            fieldType={'dropDown'}
+                            required={false}
+                            theme={this.props.theme}
+                            options={Constants.MINOR_EDIT_OPTIONS}
+                            value={this.state.minorEdits}
+                            addValidation={this.validator.addValidation}
+                            removeValidation={this.validator.removeValidation}
+                            onChange={this.handleMinorEdits}
+                            testId='subscription-minor-edits-select'
+                        />
+                        {subscriptionType.value === Constants.SUBSCRIPTION_TYPE[0].value && (
+                            <ConfluenceField
+                                isSearchable={false}
+                                isMulti={false}
+                                label={'Restricted Pages'}
+                                name={'restrictedContent'}
+                                fieldType={'dropDown'}
+                                required={false}
+                                theme={this.props.theme}
+                                options={Constants.RESTRICTED_CONTENT_OPTIONS}
+                                value={this.state.restrictedContent}
+                                addValidation={this.validator.addValidation}
+                                removeValidation={this.validator.removeValidation}
+                                onChange={this.handleRestrictedContent}
+                                testId='subscription-restricted-content-select'
+                            />
+                        )}
+                        <div style={getStyle.innerFields}>
+                            <ConfluenceField
+                                formGroupStyle={getStyle.subscriptionType}
+                                label={'Quiet Hours Start'}
+                                type={'time'}
+                                fieldType={'input'}
+                                required={false}
+                                value={this.state.quietHoursStart}
+                                addValidation={this.validator.addValidation}
+                                removeValidation={this.validator.removeValidation}
+                                onChange={this.handleQuietHoursStart}
+                                testId='subscription-quiet-hours-start-input'
+                            />
+                            <ConfluenceField
+                                formGroupStyle={getStyle.subscriptionType}
+                                label={'Quiet Hours End'}
+                                type={'time'}
+                                fieldType={'input'}
+                                required={false}
+                                value={this.state.quietHoursEnd}
+                                addValidation={this.validator.addValidation}
+                                removeValidation={this.validator.removeValidation}
+                                onChange={this.handleQuietHoursEnd}
+                                testId='subscription-quiet-hours-end-input'
+                            />
+                            <ConfluenceField
+                                formGroupStyle={getStyle.typeValue}
+                                label={'Time Zone'}
+                                type={'text'}
+                                fieldType={'input'}
+                                required={false}
+                                placeholder={'e.g. Europe/Berlin'}
+                                value={this.state.quietHoursTimeZone}
+                                addValidation={this.validator.addValidation}
+                                removeValidation={this.validator.removeValidation}
+                                onChange={this.handleQuietHoursTimeZone}
+                                testId='subscription-quiet-hours-time-zone-input'
+                            />
+                        </div>
+                        <ConfluenceField
+                            isSearchable={false}
+                            isMulti={false}
+                            label={'During Quiet Hours'}
+                            name={'quietHoursAction'}
+                            fieldType={'dropDown'}
+                            required={false}
+                            theme={this.props.theme}
+                            options={Constants.QUIET_HOURS_ACTIONS}
+                            value={this.state.quietHoursAction}
+                            addValidation={this.validator.addValidation}
+                            removeValidation={this.validator.removeValidation}
+                            onChange={this.handleQuietHoursAction}
+                            testId='subscription-quiet-hours-action-select'
+                        />
                         {createError}
                     </div>
                 </Modal.Body>
@@ -81,6 +81,7 @@ describe('components/ChannelSettingsModal', () => {
                 baseURL: 'https://test.com',
                 spaceKey: 'test',
                 events: Constants.CONFLUENCE_EVENTS.map((event) => event.value),
+                skipMinorEdits: false,
                 channelID: 'abcabcabcabcabc',
                 pageID: '',
                 subscriptionType: 'space_subscription',
@@ -140,6 +141,7 @@ describe('components/ChannelSettingsModal', () => {
                 baseURL: 'https://test.com',
                 spaceKey: 'test',
                 events: Constants.CONFLUENCE_EVENTS.map((event) => event.value),
+                skipMinorEdits: false,
                 channelID: 'abcabcabcabcabc',
                 pageID: '',
                 subscriptionType: 'space_subscription',
@@ -181,7 +183,8 @@ describe('components/ChannelSettingsModal', () => {
                 alias: 'Abc',
                 baseURL: 'https://test.com',
                 spaceKey: '',
-                events: Constants.CONFLUENCE_EVENTS.map((event) => event.value),
+                events: Constants.CONFLUENCE_EVENTS.map((event) => event.value).filter((event) => !Constants.SPACE_EVENTS.includes(event)),
+                skipMinorEdits: false,
                 channelID: 'abcabcabcabcabc',
                 pageID: '1234',
                 subscriptionType: 'page_subscription',
@@ -247,7 +250,8 @@ describe('components/ChannelSettingsModal', () => {
                 alias: 'Xyz',
                 baseURL: 'https://test.com',
                 spaceKey: '',
-                events: Constants.CONFLUENCE_EVENTS.map((event) => event.value),
+                events: Constants.CONFLUENCE_EVENTS.map((event) => event.value).filter((event) => !Constants.SPACE_EVENTS.includes(event)),
+                skipMinorEdits: false,
                 channelID: 'abcabcabcabcabc',
                 pageID: '1234',
                 subscriptionType: 'page_subscription',
@@ -283,6 +287,7 @@ describe('components/ChannelSettingsModal', () => {
                 baseURL: 'https://test.com',
                 spaceKey: 'test',
                 events: Constants.CONFLUENCE_EVENTS.map((event) => event.value),
+                skipMinorEdits: false,
                 channelID: 'abcabcabcabcabc',
                 pageID: '',
                 subscriptionType: 'space_subscription',
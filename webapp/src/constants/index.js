@@ -35,8 +35,27 @@ const CONFLUENCE_EVENTS = [
         value: 'page_removed',
         label: 'Page Remove',
     },
+    {
+        value: 'space_created',
+        label: 'Space Create',
+    },
+    {
+        value: 'space_updated',
+        label: 'Space Update',
+    },
+    {
+        value: 'space_removed',
+        label: 'Space Remove',
+    },
+    {
+        value: 'space_permissions_updated',
+        label: 'Space Permissions Update',
+    },
 ];
 
+// Events about a space itself, which only space subscriptions can subscribe to.
+const SPACE_EVENTS = ['space_created', 'space_updated', 'space_removed', 'space_permissions_updated'];
+
 const SUBSCRIPTION_TYPE = [
     {
         value: 'space_subscription',
@@ -48,12 +67,49 @@ const SUBSCRIPTION_TYPE = [
     },
 ];
 
+const MINOR_EDIT_OPTIONS = [
+    {
+        value: false,
+        label: 'Notify',
+    },
+    {
+        value: true,
+        label: 'Skip',
+    },
+];
+
+const RESTRICTED_CONTENT_OPTIONS = [
+    {
+        value: '',
+        label: 'Post as is',
+    },
+    {
+        value: 'redact',
+        label: 'Post a redacted notice',
+    },
+    {
+        value: 'skip',
+        label: 'Skip',
+    },
+];
+
+const QUIET_HOURS_ACTIONS = [
+    {
+        value: 'drop',
+        label: 'Drop notifications',
+    },
+    {
+        value: 'hold',
+        label: 'Hold until quiet hours end',
+    },
+];
+
 const {id} = manifest;
 const MATTERMOST_CSRF_COOKIE = 'MMCSRF';
 const OPEN_EDIT_SUBSCRIPTION_MODAL_WEBSOCKET_EVENT = `custom_${id}_open_edit_subscription_modal`;
 const SPECIFY_ALIAS = 'Please specify a name for the subscription.';
 
-const COMMAND_ADMIN_ONLY = '`/confluence` commands can only be run by a system administrator.';
+const COMMAND_NO_PERMISSION = 'You do not have permission to manage Confluence subscriptions in this channel.';
 const SYSTEM_ADMIN_ROLE = 'system_admin';
 const DISCONNECTED_USER = 'User not connected. Please use `/confluence connect`.';
 const ERROR_EXECUTING_COMMAND = 'An error occurred while executing the command. Please try again later.';
@@ -61,13 +117,17 @@ const ERROR_EXECUTING_COMMAND = 'An error occurred while executing the command.
 export default {
     ACTION_TYPES,
     CONFLUENCE_EVENTS,
+    SPACE_EVENTS,
     MATTERMOST_CSRF_COOKIE,
     OPEN_EDIT_SUBSCRIPTION_MODAL_WEBSOCKET_EVENT,
     id,
     SPECIFY_ALIAS,
-    COMMAND_ADMIN_ONLY,
+    COMMAND_NO_PERMISSION,
     SYSTEM_ADMIN_ROLE,
     SUBSCRIPTION_TYPE,
+    MINOR_EDIT_OPTIONS,
+    RESTRICTED_CONTENT_OPTIONS,
+    QUIET_HOURS_ACTIONS,
     DISCONNECTED_USER,
     ERROR_EXECUTING_COMMAND,
 };
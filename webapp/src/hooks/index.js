@@ -28,7 +28,7 @@ export default class Hooks {
         const user = getCurrentUser(state);
 
         if (commandTrimmed && commandTrimmed === '/confluence subscribe') {
-            const {data: subscriptionAccessData, error} = await getSubscriptionAccess()(this.store.dispatch);
+            const {data: subscriptionAccessData, error} = await getSubscriptionAccess(contextArgs.channel_id)(this.store.dispatch);
 
             if (error) {
                 this.store.dispatch(sendEphemeralPost(Constants.ERROR_EXECUTING_COMMAND, contextArgs.channel_id, user.id));
@@ -36,7 +36,7 @@ export default class Hooks {
             }
 
             if (!subscriptionAccessData?.can_run_subscribe_command) {
-                const errorMsg = subscriptionAccessData?.server_version_greater_than_9 ? Constants.DISCONNECTED_USER : Constants.COMMAND_ADMIN_ONLY;
+                const errorMsg = subscriptionAccessData?.can_manage_subscriptions ? Constants.DISCONNECTED_USER : Constants.COMMAND_NO_PERMISSION;
                 this.store.dispatch(sendEphemeralPost(errorMsg, contextArgs.channel_id, user.id));
                 return Promise.resolve({});
             }
@@ -44,7 +44,7 @@ export default class Hooks {
             openSubscriptionModal()(this.store.dispatch);
             return Promise.resolve({});
         } else if (commandTrimmed && commandTrimmed.startsWith('/confluence edit')) {
-            const {data: subscriptionAccessData, error} = await getSubscriptionAccess()(this.store.dispatch);
+            const {data: subscriptionAccessData, error} = await getSubscriptionAccess(contextArgs.channel_id)(this.store.dispatch);
 
             if (error) {
                 this.store.dispatch(sendEphemeralPost(Constants.ERROR_EXECUTING_COMMAND, contextArgs.channel_id, user.id));
@@ -52,7 +52,7 @@ export default class Hooks {
             }
 
             if (!subscriptionAccessData?.can_run_subscribe_command) {
-                const errorMsg = subscriptionAccessData?.server_version_greater_than_9 ? Constants.DISCONNECTED_USER : Constants.COMMAND_ADMIN_ONLY;
+                const errorMsg = subscriptionAccessData?.can_manage_subscriptions ? Constants.DISCONNECTED_USER : Constants.COMMAND_NO_PERMISSION;
                 this.store.dispatch(sendEphemeralPost(errorMsg, contextArgs.channel_id, user.id));
                 return Promise.resolve({});
             }